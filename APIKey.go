@@ -0,0 +1,242 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// API 密钥的权限范围
+const (
+	ScopeReadStudents = "read:students" // 查询学生、成绩、排名和导出
+	ScopeWriteScores  = "write:scores"  // 录入、修改和删除成绩
+	ScopeImport       = "import"        // 导入学生和成绩
+)
+
+// apiKeyPrefix API 密钥的前缀，完整的密钥为 sms_<编号>.<随机串>
+const apiKeyPrefix = "sms_"
+
+// apiKeyUsageInterval 最近使用时间的记录间隔，避免每个请求都写一次存储
+const apiKeyUsageInterval = time.Minute
+
+// APIKey 供其他系统调用接口的密钥，只保存随机串的 SHA-256 哈希
+type APIKey struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	SecretHash string     `json:"secret_hash,omitempty"` // 接口返回密钥信息时清空
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // 为空表示永不过期
+	RotatedAt  *time.Time `json:"rotated_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"` // 精确到 apiKeyUsageInterval
+}
+
+// APIKeyStore 定义 API 密钥的存储接口，内置的三种学生存储都实现了该接口
+type APIKeyStore interface {
+	// GetAPIKey 按编号读取密钥，不存在时 exists 为 false
+	GetAPIKey(id string) (key APIKey, exists bool, err error)
+	// SaveAPIKey 保存密钥，已存在则覆盖
+	SaveAPIKey(key APIKey) error
+	// DeleteAPIKey 删除密钥
+	DeleteAPIKey(id string) error
+	// ListAPIKeys 按创建时间升序返回全部密钥
+	ListAPIKeys() ([]APIKey, error)
+}
+
+// HasScope 判断密钥是否拥有权限范围
+func (key *APIKey) HasScope(scope string) bool {
+	for _, s := range key.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// apiKeyNotFound 返回密钥不存在的错误
+func apiKeyNotFound(id string) error {
+	return fmt.Errorf("api key %s %w", id, ErrNotFound)
+}
+
+// normalizeScopes 校验权限范围并去重排序
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, &ValidationError{Field: "scopes", Rule: "required", Message: "must not be empty"}
+	}
+	set := make(map[string]bool)
+	for _, scope := range scopes {
+		switch scope {
+		case ScopeReadStudents, ScopeWriteScores, ScopeImport:
+			set[scope] = true
+		default:
+			return nil, &ValidationError{Field: "scopes", Rule: "oneof", Message: fmt.Sprintf("unknown scope %q, must be one of read:students, write:scores, import", scope)}
+		}
+	}
+	normalized := make([]string, 0, len(set))
+	for scope := range set {
+		normalized = append(normalized, scope)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// hashSecret 计算密钥随机串的哈希，随机串足够长，不需要加盐和慢哈希
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSecret 生成新的随机串，返回完整的密钥和随机串的哈希
+func newSecret(id string) (string, string, error) {
+	secret, err := randomToken(32)
+	if err != nil {
+		return "", "", err
+	}
+	return apiKeyPrefix + id + "." + secret, hashSecret(secret), nil
+}
+
+// CreateAPIKey 创建 API 密钥，返回密钥信息和完整的密钥，完整的密钥只在创建时返回一次
+func (sm *StudentManager) CreateAPIKey(name string, scopes []string, expiresAt *time.Time, createdBy string) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", &ValidationError{Field: "name", Rule: "required", Message: "must not be empty"}
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, "", &ValidationError{Field: "expires_at", Rule: "future", Message: "must be in the future"}
+	}
+	// 编号出现在密钥中，使用十六进制避免与分隔符混淆
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, "", fmt.Errorf("generate api key id: %w", err)
+	}
+	id := hex.EncodeToString(buf)
+	full, hash, err := newSecret(id)
+	if err != nil {
+		return nil, "", err
+	}
+	key := APIKey{ID: id, Name: name, Scopes: scopes, SecretHash: hash, CreatedBy: createdBy, CreatedAt: now, ExpiresAt: expiresAt}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	_, exists, err := sm.apiKeys.GetAPIKey(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if exists {
+		return nil, "", fmt.Errorf("api key %s %w", id, ErrConflict)
+	}
+	if err := sm.apiKeys.SaveAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	key.SecretHash = ""
+	return &key, full, nil
+}
+
+// RotateAPIKey 为密钥生成新的随机串，旧的密钥立即失效，权限范围和有效期不变
+func (sm *StudentManager) RotateAPIKey(id string) (*APIKey, string, error) {
+	full, hash, err := newSecret(id)
+	if err != nil {
+		return nil, "", err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	key, exists, err := sm.apiKeys.GetAPIKey(id)
+	if err != nil {
+		return nil, "", err
+	}
+	if !exists {
+		return nil, "", apiKeyNotFound(id)
+	}
+	now := time.Now().UTC()
+	key.SecretHash = hash
+	key.RotatedAt = &now
+	if err := sm.apiKeys.SaveAPIKey(key); err != nil {
+		return nil, "", err
+	}
+	key.SecretHash = ""
+	return &key, full, nil
+}
+
+// DeleteAPIKey 删除密钥，删除后立即失效
+func (sm *StudentManager) DeleteAPIKey(id string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	_, exists, err := sm.apiKeys.GetAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apiKeyNotFound(id)
+	}
+	return sm.apiKeys.DeleteAPIKey(id)
+}
+
+// QueryAPIKey 查询密钥，不包含哈希
+func (sm *StudentManager) QueryAPIKey(id string) (*APIKey, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	key, exists, err := sm.apiKeys.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apiKeyNotFound(id)
+	}
+	key.SecretHash = ""
+	return &key, nil
+}
+
+// ListAPIKeys 按创建时间升序返回全部密钥，不包含哈希
+func (sm *StudentManager) ListAPIKeys() ([]APIKey, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	keys, err := sm.apiKeys.ListAPIKeys()
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		keys[i].SecretHash = ""
+	}
+	return keys, nil
+}
+
+// AuthenticateAPIKey 校验完整的密钥，密钥不存在、不匹配或已过期时返回 ErrUnauthorized，并记录最近使用时间
+func (sm *StudentManager) AuthenticateAPIKey(full string) (*APIKey, error) {
+	invalid := fmt.Errorf("invalid api key: %w", ErrUnauthorized)
+	id, secret, ok := strings.Cut(strings.TrimPrefix(full, apiKeyPrefix), ".")
+	if !ok || !strings.HasPrefix(full, apiKeyPrefix) {
+		return nil, invalid
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	key, exists, err := sm.apiKeys.GetAPIKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists || subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, invalid
+	}
+	now := time.Now().UTC()
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(now) {
+		return nil, fmt.Errorf("api key %s has expired: %w", id, ErrUnauthorized)
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyUsageInterval {
+		key.LastUsedAt = &now
+		if err := sm.apiKeys.SaveAPIKey(key); err != nil {
+			return nil, err
+		}
+	}
+	key.SecretHash = ""
+	return &key, nil
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAPIKeys 测试 API 密钥的创建、校验、轮换和删除
+func TestAPIKeys(t *testing.T) {
+	sm := NewStudentManager()
+	var validationErr *ValidationError
+	past := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name      string
+		scopes    []string
+		expiresAt *time.Time
+		field     string
+	}{
+		{"", []string{ScopeImport}, nil, "name"},
+		{"lms", nil, nil, "scopes"},
+		{"lms", []string{"write:students"}, nil, "scopes"},
+		{"lms", []string{ScopeImport}, &past, "expires_at"},
+	}
+	for _, tt := range tests {
+		if _, _, err := sm.CreateAPIKey(tt.name, tt.scopes, tt.expiresAt, "admin"); !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Errorf("%+v: expected validation error on %s, got %v", tt, tt.field, err)
+		}
+	}
+
+	key, secret, err := sm.CreateAPIKey("lms", []string{ScopeWriteScores, ScopeReadStudents, ScopeWriteScores}, nil, "admin")
+	if err != nil || key.SecretHash != "" || len(key.Scopes) != 2 || key.Scopes[0] != ScopeReadStudents || !strings.HasPrefix(secret, apiKeyPrefix+key.ID+".") {
+		t.Fatalf("Expected key with sorted scopes, got %+v %q %v", key, secret, err)
+	}
+	authenticated, err := sm.AuthenticateAPIKey(secret)
+	if err != nil || authenticated.ID != key.ID || authenticated.LastUsedAt == nil {
+		t.Fatalf("Expected key to authenticate and record its use, got %+v %v", authenticated, err)
+	}
+	for _, invalid := range []string{"", "sms_", key.ID, secret + "x", strings.TrimPrefix(secret, apiKeyPrefix)} {
+		if _, err := sm.AuthenticateAPIKey(invalid); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%q: expected unauthorized, got %v", invalid, err)
+		}
+	}
+
+	rotated, newSecret, err := sm.RotateAPIKey(key.ID)
+	if err != nil || rotated.RotatedAt == nil || newSecret == secret {
+		t.Fatalf("Expected rotated key, got %+v %v", rotated, err)
+	}
+	if _, err := sm.AuthenticateAPIKey(secret); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected old key to be rejected after rotation, got %v", err)
+	}
+	if _, err := sm.AuthenticateAPIKey(newSecret); err != nil {
+		t.Errorf("Expected rotated key to authenticate, got %v", err)
+	}
+
+	expiresAt := time.Now().Add(50 * time.Millisecond)
+	_, expiring, _ := sm.CreateAPIKey("timetable", []string{ScopeImport}, &expiresAt, "admin")
+	time.Sleep(100 * time.Millisecond)
+	if _, err := sm.AuthenticateAPIKey(expiring); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected expired key to be rejected, got %v", err)
+	}
+
+	if err := sm.DeleteAPIKey(key.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := sm.AuthenticateAPIKey(newSecret); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected deleted key to be rejected, got %v", err)
+	}
+	if err := sm.DeleteAPIKey(key.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found, got %v", err)
+	}
+}
+
+// TestAPIKeyScopes 测试 API 密钥只能访问权限范围内的接口
+func TestAPIKeyScopes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sm := NewStudentManager()
+	auth := NewAuthenticator(sm, []byte("test secret"))
+	_, reader, _ := sm.CreateAPIKey("reader", []string{ScopeReadStudents}, nil, "admin")
+	_, writer, _ := sm.CreateAPIKey("lms", []string{ScopeWriteScores}, nil, "admin")
+
+	r := gin.New()
+	r.Use(auth.Middleware(), Authorize(sm))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/courses", ok)
+	r.GET("/students/:id", ok)
+	r.POST("/students/:id/scores", ok)
+	r.POST("/import", ok)
+	r.GET("/users", ok)
+
+	tests := []struct {
+		key    string
+		method string
+		path   string
+		status int
+	}{
+		{reader, http.MethodGet, "/courses", http.StatusOK},
+		{reader, http.MethodGet, "/students/1", http.StatusOK},
+		{reader, http.MethodPost, "/students/1/scores", http.StatusForbidden},
+		{writer, http.MethodPost, "/students/1/scores", http.StatusOK},
+		{writer, http.MethodGet, "/students/1", http.StatusForbidden},
+		{writer, http.MethodPost, "/import", http.StatusForbidden},
+		{writer, http.MethodGet, "/users", http.StatusForbidden},
+		{"sms_unknown.key", http.MethodGet, "/courses", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set(apiKeyHeader, tt.key)
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s %s: expected status %d, got %d (%s)", tt.method, tt.path, tt.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+// TestAPIKeyPersistence 测试文件存储和 SQLite 存储在重新打开后保留 API 密钥
+func TestAPIKeyPersistence(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]func() (StudentStore, error){
+		"file":   func() (StudentStore, error) { return NewFileStore(filepath.Join(dir, "students.json")) },
+		"sqlite": func() (StudentStore, error) { return NewSQLiteStore(filepath.Join(dir, "students.db")) },
+	}
+	for kind, open := range stores {
+		store, err := open()
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", kind, err)
+		}
+		sm := NewStudentManagerWithStore(store)
+		expiresAt := time.Now().Add(time.Hour).UTC()
+		key, secret, err := sm.CreateAPIKey("lms", []string{ScopeImport, ScopeWriteScores}, &expiresAt, "admin")
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", kind, err)
+		}
+		sm.AuthenticateAPIKey(secret)
+		sm.Close()
+
+		store, err = open()
+		if err != nil {
+			t.Fatalf("%s: expected no error on reopen, got %v", kind, err)
+		}
+		sm = NewStudentManagerWithStore(store)
+		reopened, err := sm.AuthenticateAPIKey(secret)
+		if err != nil || reopened.ID != key.ID || len(reopened.Scopes) != 2 || reopened.CreatedBy != "admin" ||
+			reopened.ExpiresAt == nil || !reopened.ExpiresAt.Equal(expiresAt) || reopened.LastUsedAt == nil {
+			t.Errorf("%s: expected the key after reopen, got %+v %v", kind, reopened, err)
+		}
+		sm.Close()
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 用户角色
+const (
+	RoleAdmin   = "admin"   // 管理员，可以访问全部接口
+	RoleTeacher = "teacher" // 教师，只能为自己任课的课程和担任班主任的班级录入、修改成绩
+	RoleStudent = "student" // 学生，只能查询自己的信息和成绩
+)
+
+// ErrForbidden 表示当前用户的角色无权执行该操作，可通过 errors.Is 判断
+var ErrForbidden = errors.New("forbidden")
+
+// validateRole 校验角色，学生用户必须关联学号，其他角色不能关联学号
+func validateRole(role string, studentID int) error {
+	switch role {
+	case RoleAdmin, RoleTeacher:
+		if studentID != 0 {
+			return &ValidationError{Field: "student_id", Rule: "role", Message: fmt.Sprintf("must be empty for role %s", role)}
+		}
+	case RoleStudent:
+		if studentID <= 0 {
+			return &ValidationError{Field: "student_id", Rule: "required", Message: "is required for role student"}
+		}
+	default:
+		return &ValidationError{Field: "role", Rule: "oneof", Message: "must be one of admin, teacher, student"}
+	}
+	return nil
+}
+
+// accessRule 判断非管理员用户能否访问接口，返回 nil 表示允许
+type accessRule func(c *gin.Context, sm *StudentManager, user *User) error
+
+// accessPolicy 非管理员可以访问的接口，键为 "方法 路由"，值为各角色的访问规则
+// 管理员可以访问全部接口，其他角色只能访问这里列出的接口
+var accessPolicy = map[string]map[string]accessRule{
+	"GET /auth/me":                             {RoleTeacher: allowAccess, RoleStudent: allowAccess},
+	"POST /auth/revoke":                        {RoleTeacher: allowAccess, RoleStudent: allowAccess},
+	"PUT /users/:username/password":            {RoleTeacher: ownUser, RoleStudent: ownUser},
+	"POST /students/:id/scores":                {RoleTeacher: teachesScore},
+	"PUT /students/:id/scores":                 {RoleTeacher: teachesScore},
+	"GET /students/:id":                        {RoleStudent: ownStudent},
+	"GET /students/:id/scores/:course":         {RoleStudent: ownStudent},
+	"GET /students/:id/scores/:course/:term":   {RoleStudent: ownStudent},
+	"GET /students/:id/scores/:course/history": {RoleStudent: ownStudent},
+	"GET /students/:id/transcript":             {RoleStudent: ownStudent},
+	"GET /students/:id/gpa":                    {RoleStudent: ownStudent},
+}
+
+// scopeAnyKey 任何有效的 API 密钥都可以访问
+const scopeAnyKey = "*"
+
+// scopePolicy API 密钥可以访问的接口及所需的权限范围，键为 "方法 路由"，未列出的接口不能用 API 密钥访问
+var scopePolicy = map[string]string{
+	"GET /grading":                              scopeAnyKey,
+	"GET /courses":                              scopeAnyKey,
+	"GET /courses/:course":                      scopeAnyKey,
+	"GET /classes":                              scopeAnyKey,
+	"GET /classes/:class":                       scopeAnyKey,
+	"GET /students":                             ScopeReadStudents,
+	"GET /students/:id":                         ScopeReadStudents,
+	"GET /students/:id/gpa":                     ScopeReadStudents,
+	"GET /students/:id/scores/:course":          ScopeReadStudents,
+	"GET /students/:id/scores/:course/:term":    ScopeReadStudents,
+	"GET /students/:id/scores/:course/history":  ScopeReadStudents,
+	"GET /students/:id/transcript":              ScopeReadStudents,
+	"GET /classes/:class/roster":                ScopeReadStudents,
+	"GET /classes/:class/rankings":              ScopeReadStudents,
+	"GET /export":                               ScopeReadStudents,
+	"POST /students/:id/scores":                 ScopeWriteScores,
+	"PUT /students/:id/scores":                  ScopeWriteScores,
+	"DELETE /students/:id/scores/:course":       ScopeWriteScores,
+	"DELETE /students/:id/scores/:course/:term": ScopeWriteScores,
+	"POST /students/:id/scores/:course/revert":  ScopeWriteScores,
+	"POST /import":                              ScopeImport,
+	"GET /import/jobs/:id":                      ScopeImport,
+	"POST /import/jobs/:id/cancel":              ScopeImport,
+}
+
+// Authorize 返回按 accessPolicy 检查当前用户角色、按 scopePolicy 检查 API 密钥权限范围的中间件，需注册在验证中间件之后
+func Authorize(sm *StudentManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := CurrentAPIKey(c); key != nil {
+			scope, ok := scopePolicy[c.Request.Method+" "+c.FullPath()]
+			if !ok {
+				abortForbidden(c, fmt.Errorf("api keys may not %s %s: %w", c.Request.Method, c.Request.URL.Path, ErrForbidden))
+				return
+			}
+			if scope != scopeAnyKey && !key.HasScope(scope) {
+				abortForbidden(c, fmt.Errorf("api key %s lacks scope %s: %w", key.ID, scope, ErrForbidden))
+				return
+			}
+			c.Next()
+			return
+		}
+		user := CurrentUser(c)
+		if user == nil {
+			abortUnauthorized(c, "missing authenticated user")
+			return
+		}
+		if user.Role == RoleAdmin {
+			c.Next()
+			return
+		}
+		rule := accessPolicy[c.Request.Method+" "+c.FullPath()][user.Role]
+		if rule == nil {
+			abortForbidden(c, fmt.Errorf("role %s may not %s %s: %w", user.Role, c.Request.Method, c.Request.URL.Path, ErrForbidden))
+			return
+		}
+		if err := rule(c, sm, user); err != nil {
+			abortForbidden(c, err)
+			return
+		}
+		c.Next()
+	}
+}
+
+// importJobOwner 返回当前请求只能访问其导入任务的操作者，管理员可以访问全部任务时为空
+func importJobOwner(c *gin.Context) string {
+	if user := CurrentUser(c); user != nil && CurrentAPIKey(c) == nil && user.Role == RoleAdmin {
+		return ""
+	}
+	return requestActor(c).Name
+}
+
+// abortForbidden 以错误对应的状态码结束请求，无权访问时为 403
+func abortForbidden(c *gin.Context, err error) {
+	c.AbortWithStatusJSON(statusForError(err), gin.H{"error": err.Error()})
+}
+
+// allowAccess 允许访问
+func allowAccess(*gin.Context, *StudentManager, *User) error {
+	return nil
+}
+
+// ownUser 只允许操作自己的账号
+func ownUser(c *gin.Context, _ *StudentManager, user *User) error {
+	if c.Param("username") != user.Username {
+		return fmt.Errorf("user %s may only manage their own account: %w", user.Username, ErrForbidden)
+	}
+	return nil
+}
+
+// ownStudent 只允许访问自己的学生信息
+func ownStudent(c *gin.Context, _ *StudentManager, user *User) error {
+	if c.Param("id") != strconv.Itoa(user.StudentID) {
+		return fmt.Errorf("user %s may only access student %d: %w", user.Username, user.StudentID, ErrForbidden)
+	}
+	return nil
+}
+
+// teachesScore 只允许为自己任课的课程和担任班主任的班级的学生录入或修改成绩
+// 课程读取自请求体，读取后还原请求体供处理函数使用；学号或请求体无效时交由处理函数返回 400
+func teachesScore(c *gin.Context, sm *StudentManager, user *User) error {
+	studentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	var request scoreRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		return nil
+	}
+	return sm.AuthorizeScore(user.Username, studentID, request.CourseName)
+}
+
+// AuthorizeScore 检查教师 teacher 能否为学生录入或修改该课程的成绩
+// 课程必须已注册且任课教师为 teacher，学生所在班级必须已创建且班主任为 teacher
+func (sm *StudentManager) AuthorizeScore(teacher string, studentID int, courseName string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	catalog, err := sm.catalog()
+	if err != nil {
+		return err
+	}
+	course, ok := catalog.find(courseName)
+	if !ok || course.Teacher != teacher {
+		return fmt.Errorf("user %s does not teach course %s: %w", teacher, courseName, ErrForbidden)
+	}
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("user %s does not manage student %d: %w", teacher, studentID, ErrForbidden)
+	}
+	class, err := sm.findClass(record.GetClass())
+	if errors.Is(err, ErrNotFound) || (err == nil && class.HomeroomTeacher != teacher) {
+		return fmt.Errorf("user %s does not manage the class of student %d: %w", teacher, studentID, ErrForbidden)
+	}
+	return err
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestUserRoles 测试角色的校验和最后一个管理员的保护
+func TestUserRoles(t *testing.T) {
+	sm := NewStudentManager()
+	var validationErr *ValidationError
+	tests := []struct {
+		user  User
+		field string
+	}{
+		{User{Username: "wei"}, "role"},
+		{User{Username: "wei", Role: "root"}, "role"},
+		{User{Username: "wei", Role: RoleStudent}, "student_id"},
+		{User{Username: "wei", Role: RoleTeacher, StudentID: 1}, "student_id"},
+	}
+	for _, tt := range tests {
+		if _, err := sm.AddUser(tt.user, "password1"); !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Errorf("%+v: expected validation error on %s, got %v", tt.user, tt.field, err)
+		}
+	}
+
+	sm.AddUser(User{Username: "admin", Role: RoleAdmin}, "password1")
+	if _, err := sm.ModifyUser("admin", User{Role: RoleTeacher}); !errors.As(err, &validationErr) || validationErr.Rule != "last_admin" {
+		t.Errorf("Expected the last admin to keep the role, got %v", err)
+	}
+	if err := sm.DeleteUser("admin"); !errors.As(err, &validationErr) || validationErr.Rule != "last_admin" {
+		t.Errorf("Expected the last admin not to be deleted, got %v", err)
+	}
+	sm.AddUser(User{Username: "wei", Role: RoleTeacher}, "password1")
+	user, err := sm.ModifyUser("wei", User{Role: RoleStudent, StudentID: 1})
+	if err != nil || user.Role != RoleStudent || user.StudentID != 1 || user.PasswordHash != "" {
+		t.Errorf("Expected wei to become student 1, got %+v %v", user, err)
+	}
+}
+
+// TestAuthorize 测试各角色能访问的接口
+func TestAuthorize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sm := NewStudentManager()
+	sm.AddCourse(Course{Code: "MATH101", Name: "Math", Credits: 3, Teacher: "zhang"})
+	sm.AddCourse(Course{Code: "ENG101", Name: "English", Credits: 2, Teacher: "li"})
+	sm.AddClass(Class{ID: "CS1", Name: "计算机1班", HomeroomTeacher: "zhang"})
+	sm.AddClass(Class{ID: "CS2", Name: "计算机2班", HomeroomTeacher: "li"})
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Class: "CS1"}})
+	sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 2, Class: "CS2"}})
+	for _, user := range []User{
+		{Username: "admin", Role: RoleAdmin},
+		{Username: "zhang", Role: RoleTeacher},
+		{Username: "wei", Role: RoleStudent, StudentID: 1},
+	} {
+		if _, err := sm.AddUser(user, "password1"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	auth := NewAuthenticator(sm, []byte("test secret"))
+
+	r := gin.New()
+	r.Use(auth.Middleware(), Authorize(sm))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	// 处理函数需要能读到授权检查之后的请求体
+	score := func(c *gin.Context) {
+		var request scoreRequest
+		if err := c.ShouldBindJSON(&request); err != nil || request.CourseName == "" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	r.GET("/students", ok)
+	r.GET("/students/:id", ok)
+	r.DELETE("/students/:id", ok)
+	r.GET("/students/:id/scores/:course", ok)
+	r.POST("/students/:id/scores", score)
+	r.PUT("/students/:id/scores", score)
+	r.PUT("/users/:username/password", ok)
+
+	tests := []struct {
+		user   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"admin", http.MethodDelete, "/students/1", "", http.StatusOK},
+		{"admin", http.MethodPost, "/students/2/scores", `{"course_name":"English"}`, http.StatusOK},
+		{"zhang", http.MethodPost, "/students/1/scores", `{"course_name":"math"}`, http.StatusOK},
+		{"zhang", http.MethodPut, "/students/1/scores", `{"course_name":"MATH101"}`, http.StatusOK},
+		{"zhang", http.MethodPost, "/students/1/scores", `{"course_name":"English"}`, http.StatusForbidden},
+		{"zhang", http.MethodPost, "/students/2/scores", `{"course_name":"Math"}`, http.StatusForbidden},
+		{"zhang", http.MethodPost, "/students/9/scores", `{"course_name":"Math"}`, http.StatusForbidden},
+		{"zhang", http.MethodPost, "/students/1/scores", `not json`, http.StatusBadRequest},
+		{"zhang", http.MethodGet, "/students/1", "", http.StatusForbidden},
+		{"zhang", http.MethodPut, "/users/zhang/password", "", http.StatusOK},
+		{"zhang", http.MethodPut, "/users/admin/password", "", http.StatusForbidden},
+		{"wei", http.MethodGet, "/students/1", "", http.StatusOK},
+		{"wei", http.MethodGet, "/students/1/scores/Math", "", http.StatusOK},
+		{"wei", http.MethodGet, "/students/2", "", http.StatusForbidden},
+		{"wei", http.MethodGet, "/students", "", http.StatusForbidden},
+		{"wei", http.MethodPost, "/students/1/scores", `{"course_name":"Math"}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		tokens, err := auth.Login(tt.user, "password1")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s %s %s: expected status %d, got %d (%s)", tt.user, tt.method, tt.path, tt.status, w.Code, w.Body.String())
+		}
+	}
+
+	// 角色修改后立即生效
+	tokens, _ := auth.Login("wei", "password1")
+	sm.ModifyUser("wei", User{Role: RoleStudent, StudentID: 2})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/students/1", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected changed role to apply to issued tokens, got %d", w.Code)
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 审计日志中的操作
+const (
+	AuditAddStudent           = "add_student"
+	AuditUpsertStudent        = "upsert_student"
+	AuditDeleteStudent        = "delete_student"
+	AuditModifyStudent        = "modify_student"
+	AuditAddScore             = "add_score"
+	AuditModifyScore          = "modify_score"
+	AuditDeleteScore          = "delete_score"
+	AuditRecordMakeup         = "record_makeup"
+	AuditAddScoreComponent    = "add_score_component"
+	AuditModifyScoreComponent = "modify_score_component"
+	AuditRecomputeScores      = "recompute_scores" // 修改课程权重后重新计算成绩
+	AuditMoveStudents         = "move_students"
+	AuditMergeClasses         = "merge_classes"
+	AuditSplitClass           = "split_class"
+	AuditImport               = "import"
+	AuditRevertScore          = "revert_score"
+)
+
+// auditSystemActor 不是通过接口发起的修改（例如测试和命令行）记录的操作者
+const auditSystemActor = "system"
+
+// requestIDHeader 请求编号的请求头和响应头
+const requestIDHeader = "X-Request-ID"
+
+// requestIDKey gin 上下文中保存请求编号的键
+const requestIDKey = "request_id"
+
+// validRequestID 调用方提供的请求编号只接受这些字符，否则重新生成
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// AuditEntry 审计日志的一条记录，保存一次写入前后的完整学生信息
+type AuditEntry struct {
+	Seq       int64           `json:"seq"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"` // 用户名，API 密钥为 apikey:<编号>
+	RequestID string          `json:"request_id,omitempty"`
+	Action    string          `json:"action"`
+	Reason    string          `json:"reason,omitempty"` // 修改成绩和调班时填写的原因
+	StudentID int             `json:"student_id"`
+	Before    json.RawMessage `json:"before,omitempty"` // 新增学生时为空
+	After     json.RawMessage `json:"after,omitempty"`  // 删除学生时为空
+}
+
+// AuditQuery 审计日志的查询条件，为空的条件不限制
+type AuditQuery struct {
+	Actor     string
+	Action    string
+	StudentID int
+	RequestID string
+	Since     time.Time // 包含
+	Until     time.Time // 不包含
+	Offset    int
+	Limit     int // 0 在 StudentManager.QueryAudit 中表示默认值，在 AuditStore 中表示全部
+}
+
+// AuditStore 定义审计日志的存储接口，只能追加不能修改，内置的三种学生存储都实现了该接口
+type AuditStore interface {
+	// AppendAudit 追加一条记录，由存储分配递增的序号
+	AppendAudit(entry AuditEntry) error
+	// QueryAudit 按序号降序（最新的在前）返回满足条件的记录及其总数，Limit 为 0 时返回全部
+	QueryAudit(query AuditQuery) ([]AuditEntry, int, error)
+}
+
+// matches 判断记录是否满足查询条件
+func (q AuditQuery) matches(entry AuditEntry) bool {
+	return (q.Actor == "" || entry.Actor == q.Actor) &&
+		(q.Action == "" || entry.Action == q.Action) &&
+		(q.StudentID == 0 || entry.StudentID == q.StudentID) &&
+		(q.RequestID == "" || entry.RequestID == q.RequestID) &&
+		(q.Since.IsZero() || !entry.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || entry.Time.Before(q.Until))
+}
+
+// Actor 发起修改的操作者和请求编号
+type Actor struct {
+	Name      string
+	RequestID string
+}
+
+// auditStore 在学生存储之上记录每次写入的审计日志
+// 写入日志失败时恢复学生数据，保证每次修改都有记录
+type auditStore struct {
+	StudentStore
+	log    AuditStore
+	actor  Actor
+	action string // 当前的操作和原因，由持有锁的方法通过 operation 设置
+	reason string
+}
+
+// Save 保存学生并记录写入前后的学生信息
+func (as *auditStore) Save(student StudentInterface) error {
+	studentID := student.GetID()
+	before, existed, err := as.StudentStore.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if err := as.StudentStore.Save(student); err != nil {
+		return err
+	}
+	if !existed {
+		before = nil
+	}
+	if err := as.record(studentID, before, student); err != nil {
+		if existed {
+			return errors.Join(err, as.StudentStore.Save(before))
+		}
+		return errors.Join(err, as.StudentStore.Delete(studentID))
+	}
+	return nil
+}
+
+// Delete 删除学生并记录删除前的学生信息
+func (as *auditStore) Delete(studentID int) error {
+	before, existed, err := as.StudentStore.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if err := as.StudentStore.Delete(studentID); err != nil {
+		return err
+	}
+	if !existed {
+		return nil
+	}
+	if err := as.record(studentID, before, nil); err != nil {
+		return errors.Join(err, as.StudentStore.Save(before))
+	}
+	return nil
+}
+
+// SaveAll 一次写入多个学生的修改并为每个学生记录一条审计记录，被包装的存储不支持批量写入时逐个写入，
+// 中途失败时恢复已写入的学生；批量写入后写入日志失败时恢复整批学生数据
+func (as *auditStore) SaveAll(changes map[int]StudentInterface) error {
+	saver, ok := as.StudentStore.(StudentBatchSaver)
+	if !ok {
+		return saveEach(as, changes)
+	}
+
+	before := make(map[int]StudentInterface, len(changes))
+	for id := range changes {
+		student, existed, err := as.StudentStore.Get(id)
+		if err != nil {
+			return err
+		}
+		if existed {
+			before[id] = student
+		} else {
+			before[id] = nil
+		}
+	}
+	if err := saver.SaveAll(changes); err != nil {
+		return err
+	}
+	for _, id := range sortedStudentIDs(changes) {
+		if before[id] == nil && changes[id] == nil {
+			continue
+		}
+		if err := as.record(id, before[id], changes[id]); err != nil {
+			return errors.Join(err, saver.SaveAll(before))
+		}
+	}
+	return nil
+}
+
+// ListAfter 按学号分页读取被包装的存储
+func (as *auditStore) ListAfter(afterID, limit int) ([]StudentInterface, error) {
+	return listStudentsAfter(as.StudentStore, afterID, limit)
+}
+
+// record 追加一条审计记录
+func (as *auditStore) record(studentID int, before, after StudentInterface) error {
+	entry := AuditEntry{
+		Time:      time.Now().UTC(),
+		Actor:     as.actor.Name,
+		RequestID: as.actor.RequestID,
+		Action:    as.action,
+		Reason:    as.reason,
+		StudentID: studentID,
+	}
+	if entry.Actor == "" {
+		entry.Actor = auditSystemActor
+	}
+	var err error
+	if before != nil {
+		if entry.Before, err = json.Marshal(before); err != nil {
+			return fmt.Errorf("encode audit entry: %w", err)
+		}
+	}
+	if after != nil {
+		if entry.After, err = json.Marshal(after); err != nil {
+			return fmt.Errorf("encode audit entry: %w", err)
+		}
+	}
+	return as.log.AppendAudit(entry)
+}
+
+// operation 设置之后的写入在审计日志中记录的操作和原因，返回的函数恢复之前的操作，调用方需持有锁
+func (sm *StudentManager) operation(action, reason string) func() {
+	if sm.auditor == nil {
+		return func() {}
+	}
+	previousAction, previousReason := sm.auditor.action, sm.auditor.reason
+	sm.auditor.action, sm.auditor.reason = action, reason
+	return func() { sm.auditor.action, sm.auditor.reason = previousAction, previousReason }
+}
+
+// As 返回以 actor 身份修改数据的 StudentManager，与 sm 共享存储和锁，修改记录在审计日志中
+func (sm *StudentManager) As(actor Actor) *StudentManager {
+	view := *sm
+	auditor := *sm.auditor
+	auditor.actor = actor
+	auditor.action = ""
+	auditor.reason = ""
+	view.auditor = &auditor
+	view.store = &auditor
+	return &view
+}
+
+// QueryAudit 按条件查询审计日志，最新的记录在前，同时返回满足条件的总数
+func (sm *StudentManager) QueryAudit(query AuditQuery) ([]AuditEntry, int, error) {
+	if query.Offset < 0 {
+		return nil, 0, &ValidationError{Field: "offset", Message: "must not be negative"}
+	}
+	if query.Limit < 0 || query.Limit > maxPageLimit {
+		return nil, 0, &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxPageLimit)}
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageLimit
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.auditor.log.QueryAudit(query)
+}
+
+// RequestID 返回为每个请求分配请求编号的中间件，调用方在 X-Request-ID 中提供的合法编号原样使用
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID.MatchString(id) {
+			var err error
+			if id, err = randomToken(12); err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// requestActor 返回发起请求的操作者，API 密钥记为 apikey:<编号>
+func requestActor(c *gin.Context) Actor {
+	actor := Actor{RequestID: c.GetString(requestIDKey)}
+	if key := CurrentAPIKey(c); key != nil {
+		actor.Name = "apikey:" + key.ID
+	} else if user := CurrentUser(c); user != nil {
+		actor.Name = user.Username
+	}
+	return actor
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingAuditStore 追加记录总是失败的审计日志
+type failingAuditStore struct{}
+
+func (failingAuditStore) AppendAudit(AuditEntry) error { return errors.New("disk full") }
+
+func (failingAuditStore) QueryAudit(AuditQuery) ([]AuditEntry, int, error) { return nil, 0, nil }
+
+// auditScore 返回审计记录中学生某门课程的成绩
+func auditScore(t *testing.T, data json.RawMessage, course string) float64 {
+	t.Helper()
+	var student Student
+	if err := json.Unmarshal(data, &student); err != nil {
+		t.Fatalf("Expected student JSON, got %s: %v", data, err)
+	}
+	return student.Scores[course]
+}
+
+// TestAuditLog 测试各种修改都记录操作者、请求编号和修改前后的学生信息
+func TestAuditLog(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	wei := sm.As(Actor{Name: "wei", RequestID: "req-1"})
+	if err := wei.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 1}}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	wei.AddScore(1, "Math", "", 80)
+	sm.As(Actor{Name: "li", RequestID: "req-2"}).ModifyScore(1, "Math", "", 90, "复核")
+	sm.ModifyStudent(1, map[string]interface{}{"name": "hao2"})
+	sm.ImportCSV(context.Background(), strings.NewReader("id,name,Math\n2,zhang,70"), ImportOptions{Atomic: true, Actor: Actor{Name: "apikey:abc"}})
+	wei.DeleteStudent(2)
+
+	entries, total, err := sm.QueryAudit(AuditQuery{})
+	if err != nil || total != 6 {
+		t.Fatalf("Expected 6 entries, got %d %v", total, err)
+	}
+	actions := []string{AuditDeleteStudent, AuditImport, AuditModifyStudent, AuditModifyScore, AuditAddScore, AuditAddStudent}
+	for i, entry := range entries {
+		if entry.Action != actions[i] || entry.Seq != int64(6-i) {
+			t.Errorf("Expected entry %d to be %s, got %+v", 6-i, actions[i], entry)
+		}
+	}
+	if entries[0].After != nil || entries[0].Before == nil || entries[5].Before != nil || entries[5].After == nil {
+		t.Errorf("Expected no after for delete and no before for add, got %+v %+v", entries[0], entries[5])
+	}
+	if entries[1].Actor != "apikey:abc" || entries[2].Actor != auditSystemActor {
+		t.Errorf("Expected import by apikey:abc and modification by system, got %q %q", entries[1].Actor, entries[2].Actor)
+	}
+
+	modified, _, _ := sm.QueryAudit(AuditQuery{Actor: "li", RequestID: "req-2"})
+	if len(modified) != 1 || modified[0].Reason != "复核" || auditScore(t, modified[0].Before, "Math") != 80 || auditScore(t, modified[0].After, "Math") != 90 {
+		t.Errorf("Expected score change from 80 to 90 with its reason, got %+v", modified)
+	}
+	byStudent, total, _ := sm.QueryAudit(AuditQuery{StudentID: 2, Limit: 1})
+	if total != 2 || len(byStudent) != 1 || byStudent[0].Action != AuditDeleteStudent {
+		t.Errorf("Expected 2 entries of student 2 and the latest first, got %d %+v", total, byStudent)
+	}
+	since, _, _ := sm.QueryAudit(AuditQuery{Since: entries[1].Time})
+	if len(since) < 2 {
+		t.Errorf("Expected entries since the import, got %+v", since)
+	}
+	var validationErr *ValidationError
+	if _, _, err := sm.QueryAudit(AuditQuery{Limit: maxPageLimit + 1}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected limit to be validated, got %v", err)
+	}
+}
+
+// TestAuditRollback 测试审计日志写入失败时不保留修改
+func TestAuditRollback(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1}})
+	sm.AddScore(1, "Math", "", 80)
+	sm.auditor.log = failingAuditStore{}
+
+	if err := sm.ModifyScore(1, "Math", "", 90, "复核"); err == nil {
+		t.Fatal("Expected audit failure to be returned")
+	}
+	if score, _ := sm.QueryScore(1, "Math", ""); score != 80 {
+		t.Errorf("Expected score to stay 80, got %v", score)
+	}
+	if err := sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 2}}); err == nil {
+		t.Fatal("Expected audit failure to be returned")
+	}
+	if _, err := sm.QueryStudent(2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected student 2 not to be added, got %v", err)
+	}
+	if err := sm.DeleteStudent(1); err == nil {
+		t.Fatal("Expected audit failure to be returned")
+	}
+	if _, err := sm.QueryStudent(1); err != nil {
+		t.Errorf("Expected student 1 to be kept, got %v", err)
+	}
+}
+
+// TestRequestID 测试请求编号中间件
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	r.GET("/", func(c *gin.Context) {
+		c.String(http.StatusOK, requestActor(c).RequestID)
+	})
+	for header, keep := range map[string]bool{"lms-42": true, "": false, "bad id\n": false} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestIDHeader, header)
+		r.ServeHTTP(w, req)
+		id := w.Header().Get(requestIDHeader)
+		if id == "" || id != w.Body.String() || (id == header) != keep {
+			t.Errorf("%q: expected request id to be kept %v, got %q", header, keep, id)
+		}
+	}
+}
+
+// TestAuditPersistence 测试文件存储和 SQLite 存储在重新打开后保留审计日志，SQLite 拒绝修改审计日志
+func TestAuditPersistence(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]func() (StudentStore, error){
+		"file":   func() (StudentStore, error) { return NewFileStore(filepath.Join(dir, "students.json")) },
+		"sqlite": func() (StudentStore, error) { return NewSQLiteStore(filepath.Join(dir, "students.db")) },
+	}
+	for kind, open := range stores {
+		store, err := open()
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", kind, err)
+		}
+		sm := NewStudentManagerWithStore(store).As(Actor{Name: "wei", RequestID: "req-1"})
+		registerCourses(t, sm, "Math")
+		sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 1}})
+		sm.AddScore(1, "Math", "", 80)
+		sm.Close()
+
+		store, err = open()
+		if err != nil {
+			t.Fatalf("%s: expected no error on reopen, got %v", kind, err)
+		}
+		sm = NewStudentManagerWithStore(store)
+		entries, total, err := sm.QueryAudit(AuditQuery{Action: AuditAddScore})
+		if err != nil || total != 1 || entries[0].Actor != "wei" || entries[0].RequestID != "req-1" ||
+			entries[0].StudentID != 1 || entries[0].Time.IsZero() || auditScore(t, entries[0].After, "Math") != 80 {
+			t.Errorf("%s: expected the score entry after reopen, got %+v %v", kind, entries, err)
+		}
+		if ss, ok := store.(*SQLiteStore); ok {
+			if _, err := ss.db.Exec(`DELETE FROM audit_log`); err == nil {
+				t.Errorf("%s: expected audit log to be append-only", kind)
+			}
+			if _, err := ss.db.Exec(`UPDATE audit_log SET actor = 'li'`); err == nil {
+				t.Errorf("%s: expected audit log to be append-only", kind)
+			}
+		}
+		sm.Close()
+	}
+}
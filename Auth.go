@@ -0,0 +1,322 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// 令牌类型，访问令牌用于调用接口，刷新令牌只能用于换取新的令牌
+const (
+	tokenAccess  = "access"
+	tokenRefresh = "refresh"
+)
+
+// 默认的令牌有效期
+const (
+	DefaultAccessTTL  = 15 * time.Minute
+	DefaultRefreshTTL = 7 * 24 * time.Hour
+)
+
+// gin 上下文中保存当前用户和当前 API 密钥的键
+const (
+	authUserKey   = "auth_user"
+	authAPIKeyKey = "auth_api_key"
+)
+
+// apiKeyHeader 提供 API 密钥的请求头
+const apiKeyHeader = "X-API-Key"
+
+// TokenClaims 令牌中的声明，sub 为用户名，jti 用于吊销
+type TokenClaims struct {
+	Type string `json:"typ"`
+	// PasswordChangedAt 签发时用户最近一次设置密码的时间（Unix 纳秒），与用户当前的值不同时令牌失效
+	PasswordChangedAt int64 `json:"pwd"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair 登录和刷新时签发的访问令牌和刷新令牌
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // 访问令牌的有效秒数
+}
+
+// Authenticator 使用 HS256 签发和验证 JWT，用户和吊销记录保存在 StudentManager 的存储中
+type Authenticator struct {
+	sm         *StudentManager
+	secret     []byte
+	AccessTTL  time.Duration
+	RefreshTTL time.Duration
+}
+
+// NewAuthenticator 创建使用 secret 签名的 Authenticator，令牌有效期为默认值
+func NewAuthenticator(sm *StudentManager, secret []byte) *Authenticator {
+	return &Authenticator{
+		sm:         sm,
+		secret:     secret,
+		AccessTTL:  DefaultAccessTTL,
+		RefreshTTL: DefaultRefreshTTL,
+	}
+}
+
+// invalidToken 返回令牌无效的错误
+func invalidToken(reason string) error {
+	return fmt.Errorf("invalid token: %s: %w", reason, ErrUnauthorized)
+}
+
+// Login 校验用户名和密码并签发令牌
+func (a *Authenticator) Login(username, password string) (*TokenPair, error) {
+	user, err := a.sm.Authenticate(username, password)
+	if err != nil {
+		return nil, err
+	}
+	return a.issue(user)
+}
+
+// Refresh 用刷新令牌换取新的令牌，旧的刷新令牌随即吊销，不能重复使用
+// 同一个刷新令牌并发刷新时只有一个请求能吊销它并得到新的令牌
+func (a *Authenticator) Refresh(refreshToken string) (*TokenPair, error) {
+	claims, user, err := a.verify(refreshToken, tokenRefresh)
+	if err != nil {
+		return nil, err
+	}
+	err = a.sm.RevokeTokenOnce(claims.ID, claims.ExpiresAt.Time)
+	if errors.Is(err, ErrConflict) {
+		return nil, invalidToken("token has been revoked")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a.issue(user)
+}
+
+// Revoke 吊销访问令牌或刷新令牌，已过期的令牌无需吊销
+func (a *Authenticator) Revoke(token string) error {
+	claims, err := a.parse(token)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil
+	}
+	if err != nil {
+		return invalidToken(err.Error())
+	}
+	return a.sm.RevokeToken(claims.ID, claims.ExpiresAt.Time)
+}
+
+// Verify 验证令牌的签名、有效期和类型，并检查令牌未被吊销、用户仍然存在且之后没有修改过密码
+func (a *Authenticator) Verify(token, kind string) (*TokenClaims, error) {
+	claims, _, err := a.verify(token, kind)
+	return claims, err
+}
+
+// verify 验证令牌并返回令牌所属的用户
+func (a *Authenticator) verify(token, kind string) (*TokenClaims, *User, error) {
+	claims, err := a.parse(token)
+	if err != nil {
+		return nil, nil, invalidToken(err.Error())
+	}
+	if claims.Type != kind {
+		return nil, nil, invalidToken(fmt.Sprintf("expected an %s token", kind))
+	}
+	revoked, err := a.sm.TokenRevoked(claims.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if revoked {
+		return nil, nil, invalidToken("token has been revoked")
+	}
+	user, err := a.sm.QueryUser(claims.Subject)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil, invalidToken("user no longer exists")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// 令牌的签发时间只精确到秒，按签发时记下的设置密码的时间精确比较
+	if claims.PasswordChangedAt != user.PasswordChangedAt.UnixNano() {
+		return nil, nil, invalidToken("password has been changed")
+	}
+	return claims, user, nil
+}
+
+// parse 解析令牌并验证签名和有效期
+func (a *Authenticator) parse(token string) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired(), jwt.WithIssuedAt())
+	if err != nil {
+		return nil, err
+	}
+	if claims.ID == "" || claims.Subject == "" || claims.IssuedAt == nil {
+		return nil, errors.New("token is missing required claims")
+	}
+	return claims, nil
+}
+
+// issue 为用户签发一对新的访问令牌和刷新令牌
+func (a *Authenticator) issue(user *User) (*TokenPair, error) {
+	now := time.Now()
+	access, err := a.sign(user, tokenAccess, now, a.AccessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := a.sign(user, tokenRefresh, now, a.RefreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(a.AccessTTL / time.Second),
+	}, nil
+}
+
+// sign 签发一个令牌，每个令牌有唯一的 jti
+func (a *Authenticator) sign(user *User, kind string, now time.Time, ttl time.Duration) (string, error) {
+	id, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+	claims := TokenClaims{
+		Type:              kind,
+		PasswordChangedAt: user.PasswordChangedAt.UnixNano(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        id,
+			Subject:   user.Username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+	return token, nil
+}
+
+// Middleware 返回验证 Authorization: Bearer <访问令牌> 的中间件，验证通过后在上下文中记录当前用户
+// 用户的角色在每次请求时读取，修改角色后立即生效；提供了 X-API-Key 请求头时改为验证 API 密钥
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if header := c.GetHeader(apiKeyHeader); header != "" {
+			key, err := a.sm.AuthenticateAPIKey(header)
+			if errors.Is(err, ErrUnauthorized) {
+				abortUnauthorized(c, err.Error())
+				return
+			}
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			c.Set(authAPIKeyKey, key)
+			c.Next()
+			return
+		}
+		token, ok := bearerToken(c)
+		if !ok {
+			abortUnauthorized(c, "missing bearer token")
+			return
+		}
+		_, user, err := a.verify(token, tokenAccess)
+		if errors.Is(err, ErrUnauthorized) {
+			abortUnauthorized(c, err.Error())
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Set(authUserKey, user)
+		c.Next()
+	}
+}
+
+// bearerToken 读取请求头中的 Bearer 令牌
+func bearerToken(c *gin.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// abortUnauthorized 以 401 结束请求
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="StudentScoreManager"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": message})
+}
+
+// CurrentUser 返回通过验证的当前用户，未经过验证中间件时返回 nil
+func CurrentUser(c *gin.Context) *User {
+	user, _ := c.Get(authUserKey)
+	current, _ := user.(*User)
+	return current
+}
+
+// CurrentAPIKey 返回通过验证的当前 API 密钥，请求不是用 API 密钥验证时返回 nil
+func CurrentAPIKey(c *gin.Context) *APIKey {
+	key, _ := c.Get(authAPIKeyKey)
+	current, _ := key.(*APIKey)
+	return current
+}
+
+// randomToken 返回 n 个随机字节的 base64url 编码
+func randomToken(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// bootstrapUser 还没有任何用户时创建初始的管理员，返回是否创建了用户
+// password 为空时生成随机密码，先写入只有当前用户可读写的 passwordFile 再创建用户，密码不会出现在日志中
+func bootstrapUser(sm *StudentManager, username, password, passwordFile string) (bool, error) {
+	users, err := sm.ListUsers()
+	if err != nil || len(users) > 0 {
+		return false, err
+	}
+	if password == "" {
+		if password, err = randomToken(12); err != nil {
+			return false, err
+		}
+		if err := writePasswordFile(passwordFile, password); err != nil {
+			return false, err
+		}
+	}
+	if _, err := sm.AddUser(User{Username: username, Role: RoleAdmin}, password); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// writePasswordFile 把密码写入权限为 0600 的文件，文件已存在时覆盖并收紧权限
+func writePasswordFile(path, password string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("write password file %s: %w", path, err)
+	}
+	if err := file.Chmod(0o600); err != nil {
+		file.Close()
+		return fmt.Errorf("write password file %s: %w", path, err)
+	}
+	if _, err := file.WriteString(password + "\n"); err != nil {
+		file.Close()
+		return fmt.Errorf("write password file %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("write password file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAuthTestAuthenticator 创建有用户 admin 的 Authenticator
+func newAuthTestAuthenticator(t *testing.T, store StudentStore) *Authenticator {
+	t.Helper()
+	sm := NewStudentManagerWithStore(store)
+	if _, err := sm.AddUser(User{Username: "admin", Role: RoleAdmin}, "secret123"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return NewAuthenticator(sm, []byte("test secret"))
+}
+
+// TestAddUser 测试用户的校验
+func TestAddUser(t *testing.T) {
+	sm := NewStudentManager()
+	user, err := sm.AddUser(User{Username: " wei ", Role: RoleTeacher}, "password1")
+	if err != nil || user.Username != "wei" || user.PasswordHash != "" {
+		t.Fatalf("Expected user wei without password hash, got %+v %v", user, err)
+	}
+	if _, err := sm.AddUser(User{Username: "wei", Role: RoleTeacher}, "password2"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected conflict, got %v", err)
+	}
+	var validationErr *ValidationError
+	if _, err := sm.AddUser(User{Username: "hao", Role: RoleTeacher}, "short"); !errors.As(err, &validationErr) || validationErr.Field != "password" {
+		t.Errorf("Expected short password to be rejected, got %v", err)
+	}
+	if _, err := sm.AddUser(User{Username: "a/b", Role: RoleTeacher}, "password1"); !errors.As(err, &validationErr) || validationErr.Field != "username" {
+		t.Errorf("Expected invalid username to be rejected, got %v", err)
+	}
+	if _, err := sm.Authenticate("wei", "password2"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected wrong password to be unauthorized, got %v", err)
+	}
+	if _, err := sm.Authenticate("nobody", "password1"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected unknown user to be unauthorized, got %v", err)
+	}
+}
+
+// TestLoginRefreshRevoke 测试登录、刷新和吊销令牌
+func TestLoginRefreshRevoke(t *testing.T) {
+	auth := newAuthTestAuthenticator(t, NewMemoryStore())
+
+	tokens, err := auth.Login("admin", "secret123")
+	if err != nil || tokens.TokenType != "Bearer" || tokens.ExpiresIn != int(DefaultAccessTTL/time.Second) {
+		t.Fatalf("Expected bearer tokens, got %+v %v", tokens, err)
+	}
+	claims, err := auth.Verify(tokens.AccessToken, tokenAccess)
+	if err != nil || claims.Subject != "admin" {
+		t.Fatalf("Expected access token of admin, got %+v %v", claims, err)
+	}
+	if _, err := auth.Verify(tokens.RefreshToken, tokenAccess); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected refresh token not to be accepted as an access token, got %v", err)
+	}
+	other := NewAuthenticator(auth.sm, []byte("other secret"))
+	if _, err := other.Verify(tokens.AccessToken, tokenAccess); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected token signed with another secret to be rejected, got %v", err)
+	}
+
+	// 刷新令牌只能使用一次
+	refreshed, err := auth.Refresh(tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := auth.Refresh(tokens.RefreshToken); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected used refresh token to be rejected, got %v", err)
+	}
+
+	// 同一个刷新令牌并发刷新时只有一个请求成功
+	again, _ := auth.Login("admin", "secret123")
+	var wg sync.WaitGroup
+	var succeeded atomic.Int32
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := auth.Refresh(again.RefreshToken); err == nil {
+				succeeded.Add(1)
+			} else if !errors.Is(err, ErrUnauthorized) {
+				t.Errorf("Expected concurrent refresh to be unauthorized, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded.Load() != 1 {
+		t.Errorf("Expected exactly one concurrent refresh to succeed, got %d", succeeded.Load())
+	}
+
+	if err := auth.Revoke(refreshed.AccessToken); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := auth.Verify(refreshed.AccessToken, tokenAccess); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected revoked token to be rejected, got %v", err)
+	}
+	if err := auth.Revoke("not a token"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected malformed token to be rejected, got %v", err)
+	}
+
+	// 令牌过期后不再有效
+	auth.AccessTTL = -time.Minute
+	expired, _ := auth.Login("admin", "secret123")
+	if _, err := auth.Verify(expired.AccessToken, tokenAccess); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+}
+
+// TestPasswordChangeInvalidatesTokens 测试修改密码和删除用户后已签发的令牌失效
+func TestPasswordChangeInvalidatesTokens(t *testing.T) {
+	auth := newAuthTestAuthenticator(t, NewMemoryStore())
+	tokens, _ := auth.Login("admin", "secret123")
+
+	// 与签发令牌在同一秒内修改密码，之前签发的令牌同样失效
+	if err := auth.sm.SetPassword("admin", "secret456"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := auth.Verify(tokens.AccessToken, tokenAccess); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected token issued before the password change to be rejected, got %v", err)
+	}
+	if _, err := auth.Login("admin", "secret123"); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected old password to be rejected, got %v", err)
+	}
+	tokens, err := auth.Login("admin", "secret456")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := auth.Verify(tokens.AccessToken, tokenAccess); err != nil {
+		t.Errorf("Expected new token to be valid, got %v", err)
+	}
+
+	// 不能删除最后一个管理员
+	auth.sm.AddUser(User{Username: "root", Role: RoleAdmin}, "secret789")
+	if err := auth.sm.DeleteUser("admin"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := auth.Verify(tokens.AccessToken, tokenAccess); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Expected token of deleted user to be rejected, got %v", err)
+	}
+}
+
+// TestAuthMiddleware 测试验证中间件
+func TestAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := newAuthTestAuthenticator(t, NewMemoryStore())
+	r := gin.New()
+	r.Use(auth.Middleware())
+	r.GET("/students", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"user": CurrentUser(c)})
+	})
+	tokens, _ := auth.Login("admin", "secret123")
+
+	tests := []struct {
+		header string
+		status int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Basic YWRtaW46c2VjcmV0MTIz", http.StatusUnauthorized},
+		{"Bearer " + tokens.RefreshToken, http.StatusUnauthorized},
+		{"Bearer " + tokens.AccessToken, http.StatusOK},
+		{"bearer " + tokens.AccessToken, http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/students", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%q: expected status %d, got %d (%s)", tt.header, tt.status, w.Code, w.Body.String())
+		}
+		if w.Code == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("%q: expected WWW-Authenticate header", tt.header)
+		}
+	}
+}
+
+// TestUserPersistence 测试文件存储和 SQLite 存储在重新打开后保留用户和吊销记录
+func TestUserPersistence(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]func() (StudentStore, error){
+		"file":   func() (StudentStore, error) { return NewFileStore(filepath.Join(dir, "students.json")) },
+		"sqlite": func() (StudentStore, error) { return NewSQLiteStore(filepath.Join(dir, "students.db")) },
+	}
+	for kind, open := range stores {
+		store, err := open()
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", kind, err)
+		}
+		auth := newAuthTestAuthenticator(t, store)
+		tokens, _ := auth.Login("admin", "secret123")
+		if err := auth.Revoke(tokens.RefreshToken); err != nil {
+			t.Fatalf("%s: expected no error, got %v", kind, err)
+		}
+		auth.sm.Close()
+
+		store, err = open()
+		if err != nil {
+			t.Fatalf("%s: expected no error on reopen, got %v", kind, err)
+		}
+		auth = NewAuthenticator(NewStudentManagerWithStore(store), []byte("test secret"))
+		if _, err := auth.Verify(tokens.AccessToken, tokenAccess); err != nil {
+			t.Errorf("%s: expected access token to stay valid after reopen, got %v", kind, err)
+		}
+		if _, err := auth.Refresh(tokens.RefreshToken); !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("%s: expected revoked refresh token after reopen, got %v", kind, err)
+		}
+		if user, err := auth.sm.QueryUser("admin"); err != nil || user.Role != RoleAdmin {
+			t.Errorf("%s: expected admin role after reopen, got %+v %v", kind, user, err)
+		}
+		if created, err := bootstrapUser(auth.sm, "root", "", filepath.Join(dir, "admin_password.txt")); err != nil || created {
+			t.Errorf("%s: expected no initial user when users exist, got %v %v", kind, created, err)
+		}
+		auth.sm.Close()
+	}
+}
+
+// TestBootstrapUser 测试生成的初始密码写入权限为 0600 的文件，指定密码时不写文件
+func TestBootstrapUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "admin_password.txt")
+	auth := NewAuthenticator(NewStudentManager(), []byte("test secret"))
+	if created, err := bootstrapUser(auth.sm, "admin", "", path); err != nil || !created {
+		t.Fatalf("Expected the initial user to be created, got %v %v", created, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("Expected a password file with mode 0600, got %v %v", info, err)
+	}
+	password, _ := os.ReadFile(path)
+	if _, err := auth.Login("admin", strings.TrimSpace(string(password))); err != nil {
+		t.Errorf("Expected to log in with the generated password, got %v", err)
+	}
+
+	other := filepath.Join(t.TempDir(), "admin_password.txt")
+	if created, err := bootstrapUser(NewStudentManager(), "admin", "secret123", other); err != nil || !created {
+		t.Fatalf("Expected the initial user to be created, got %v %v", created, err)
+	}
+	if _, err := os.Stat(other); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no password file for a given password, got %v", err)
+	}
+}
@@ -0,0 +1,460 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 调班记录的操作类型
+const (
+	ClassMoveTransfer = "move"   // 调班
+	ClassMoveMerge    = "merge"  // 合并班级
+	ClassMoveSplit    = "split"  // 拆分班级
+	ClassMoveModify   = "modify" // 修改学生信息时修改班级
+)
+
+// Class 班级信息，班级编号唯一标识一个班级，学生的 class 字段保存班级编号
+type Class struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	GradeYear       int    `json:"grade_year,omitempty"` // 年级（入学年份），例如 2025
+	Major           string `json:"major,omitempty"`
+	HomeroomTeacher string `json:"homeroom_teacher,omitempty"` // 班主任
+	Capacity        int    `json:"capacity,omitempty"`         // 最多容纳的学生人数，0 表示不限
+}
+
+// Validate 校验班级字段
+func (c *Class) Validate() error {
+	c.ID = strings.TrimSpace(c.ID)
+	c.Name = strings.TrimSpace(c.Name)
+	if c.ID == "" {
+		return &ValidationError{Field: "id", Rule: "required", Message: "must not be empty"}
+	}
+	if strings.ContainsAny(c.ID, "/?#") {
+		return &ValidationError{Field: "id", Rule: "format", Message: "must not contain /, ? or #"}
+	}
+	if c.Name == "" {
+		return &ValidationError{Field: "name", Rule: "required", Message: "must not be empty"}
+	}
+	if c.GradeYear != 0 && (c.GradeYear < 1900 || c.GradeYear > 9999) {
+		return &ValidationError{Field: "grade_year", Rule: "range", Message: "must be a four-digit year"}
+	}
+	if c.Capacity < 0 {
+		return &ValidationError{Field: "capacity", Rule: "min", Message: "must not be negative"}
+	}
+	return nil
+}
+
+// ClassMove 一条调班记录，调班、合并和拆分班级时每个学生记录一条
+type ClassMove struct {
+	StudentID int       `json:"student_id"`
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Operation string    `json:"operation"`
+	Reason    string    `json:"reason,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// ClassStore 定义班级和调班记录的存储接口，内置的三种学生存储都实现了该接口
+type ClassStore interface {
+	// SaveClass 保存班级，已存在则覆盖
+	SaveClass(class Class) error
+	// DeleteClass 删除班级，调班记录保留
+	DeleteClass(id string) error
+	// ListClasses 按班级编号升序返回全部班级
+	ListClasses() ([]Class, error)
+	// AppendClassMoves 追加调班记录，已有的记录不能修改
+	AppendClassMoves(moves []ClassMove) error
+	// ListClassMoves 按记录的先后顺序返回全部调班记录
+	ListClassMoves() ([]ClassMove, error)
+}
+
+// classNotFound 返回班级不存在的错误
+func classNotFound(id string) error {
+	return fmt.Errorf("class %s %w", id, ErrNotFound)
+}
+
+// classCatalog 已创建的全部班级，用于把班级编号或名称换算为班级编号
+type classCatalog []Class
+
+// find 按班级编号或名称查找班级，不区分大小写和首尾空白
+func (catalog classCatalog) find(name string) (Class, bool) {
+	name = strings.TrimSpace(name)
+	for _, class := range catalog {
+		if strings.EqualFold(class.ID, name) {
+			return class, true
+		}
+	}
+	for _, class := range catalog {
+		if strings.EqualFold(class.Name, name) {
+			return class, true
+		}
+	}
+	return Class{}, false
+}
+
+// classCatalog 读取已创建的全部班级，调用方需持有锁
+func (sm *StudentManager) classCatalog() (classCatalog, error) {
+	classes, err := sm.classes.ListClasses()
+	if err != nil {
+		return nil, err
+	}
+	return classCatalog(classes), nil
+}
+
+// resolveClass 返回学生应记录的班级，班级不存在时返回 ValidationError；班级为空表示未分班。调用方需持有锁
+func (sm *StudentManager) resolveClass(name string) (Class, error) {
+	catalog, err := sm.classCatalog()
+	if err != nil {
+		return Class{}, err
+	}
+	if name == "" {
+		return Class{ID: name}, nil
+	}
+	class, ok := catalog.find(name)
+	if !ok {
+		return Class{}, &ValidationError{Field: "class", Rule: "registered", Message: fmt.Sprintf("class %q does not exist", name)}
+	}
+	return class, nil
+}
+
+// lookupClass 把班级编号或名称换算为班级编号，不存在的班级原样返回，调用方需持有锁
+func (sm *StudentManager) lookupClass(name string) (string, error) {
+	catalog, err := sm.classCatalog()
+	if err != nil {
+		return "", err
+	}
+	if class, ok := catalog.find(name); ok {
+		return class.ID, nil
+	}
+	return name, nil
+}
+
+// findClass 按班级编号查找已创建的班级，调用方需持有锁
+func (sm *StudentManager) findClass(id string) (Class, error) {
+	catalog, err := sm.classCatalog()
+	if err != nil {
+		return Class{}, err
+	}
+	class, ok := catalog.find(id)
+	if !ok || !strings.EqualFold(class.ID, strings.TrimSpace(id)) {
+		return Class{}, classNotFound(id)
+	}
+	return class, nil
+}
+
+// enrolled 按学号升序返回班级中的学生，调用方需持有锁
+func (sm *StudentManager) enrolled(classID string) ([]StudentInterface, error) {
+	students, err := sm.store.List()
+	if err != nil {
+		return nil, err
+	}
+	roster := []StudentInterface{}
+	for _, student := range students {
+		if student.GetClass() == classID {
+			roster = append(roster, student)
+		}
+	}
+	return roster, nil
+}
+
+// checkCapacity 检查班级还能再容纳 incoming 名学生，调用方需持有锁
+func (sm *StudentManager) checkCapacity(class Class, incoming int) error {
+	if class.Capacity == 0 || incoming == 0 {
+		return nil
+	}
+	roster, err := sm.enrolled(class.ID)
+	if err != nil {
+		return err
+	}
+	if len(roster)+incoming > class.Capacity {
+		return &ValidationError{Field: "class", Rule: "capacity", Message: fmt.Sprintf("class %s has %d of %d places taken, cannot take %d more", class.ID, len(roster), class.Capacity, incoming)}
+	}
+	return nil
+}
+
+// checkClassUnique 检查班级编号和名称没有被其他班级使用，调用方需持有锁
+func (sm *StudentManager) checkClassUnique(class Class, except string) error {
+	catalog, err := sm.classCatalog()
+	if err != nil {
+		return err
+	}
+	for _, other := range catalog {
+		if other.ID == except {
+			continue
+		}
+		if strings.EqualFold(other.ID, class.ID) {
+			return fmt.Errorf("class %s %w", class.ID, ErrConflict)
+		}
+		if strings.EqualFold(other.Name, class.Name) || strings.EqualFold(other.ID, class.Name) || strings.EqualFold(other.Name, class.ID) {
+			return fmt.Errorf("class name %s %w as class %s", class.Name, ErrConflict, other.ID)
+		}
+	}
+	return nil
+}
+
+// AddClass 创建班级，班级编号或名称与已有班级重复（不区分大小写）时返回 ErrConflict
+func (sm *StudentManager) AddClass(class Class) (*Class, error) {
+	if err := class.Validate(); err != nil {
+		return nil, err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if err := sm.checkClassUnique(class, ""); err != nil {
+		return nil, err
+	}
+	if err := sm.classes.SaveClass(class); err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+// QueryClass 按班级编号或名称查询班级
+func (sm *StudentManager) QueryClass(name string) (*Class, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	catalog, err := sm.classCatalog()
+	if err != nil {
+		return nil, err
+	}
+	class, ok := catalog.find(name)
+	if !ok {
+		return nil, classNotFound(name)
+	}
+	return &class, nil
+}
+
+// ListClasses 按班级编号升序返回全部班级
+func (sm *StudentManager) ListClasses() ([]Class, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.classes.ListClasses()
+}
+
+// ModifyClass 修改班级信息，班级编号不能修改，容量不能小于班级现有人数
+func (sm *StudentManager) ModifyClass(id string, class Class) (*Class, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	existing, err := sm.findClass(id)
+	if err != nil {
+		return nil, err
+	}
+	if class.ID != "" && class.ID != existing.ID {
+		return nil, &ValidationError{Field: "id", Rule: "immutable", Message: "class id cannot be changed"}
+	}
+	class.ID = existing.ID
+	if err := class.Validate(); err != nil {
+		return nil, err
+	}
+	if err := sm.checkClassUnique(class, existing.ID); err != nil {
+		return nil, err
+	}
+	if class.Capacity > 0 {
+		roster, err := sm.enrolled(class.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(roster) > class.Capacity {
+			return nil, &ValidationError{Field: "capacity", Rule: "enrolled", Message: fmt.Sprintf("class %s already has %d students", class.ID, len(roster))}
+		}
+	}
+	if err := sm.classes.SaveClass(class); err != nil {
+		return nil, err
+	}
+	return &class, nil
+}
+
+// DeleteClass 删除班级，班级中仍有学生时返回 ErrInUse
+func (sm *StudentManager) DeleteClass(id string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	class, err := sm.findClass(id)
+	if err != nil {
+		return err
+	}
+	roster, err := sm.enrolled(class.ID)
+	if err != nil {
+		return err
+	}
+	if len(roster) > 0 {
+		return fmt.Errorf("class %s is %w by %d students", class.ID, ErrInUse, len(roster))
+	}
+	return sm.classes.DeleteClass(class.ID)
+}
+
+// ClassRoster 按学号升序返回班级的花名册
+func (sm *StudentManager) ClassRoster(id string) ([]StudentInterface, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	class, err := sm.findClass(id)
+	if err != nil {
+		return nil, err
+	}
+	return sm.enrolled(class.ID)
+}
+
+// ClassMoves 按时间顺序返回调入或调出班级的记录，已合并删除的班级仍可按班级编号查询
+func (sm *StudentManager) ClassMoves(id string) ([]ClassMove, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	id, err := sm.lookupClass(id)
+	if err != nil {
+		return nil, err
+	}
+	moves, err := sm.classes.ListClassMoves()
+	if err != nil {
+		return nil, err
+	}
+	matched := []ClassMove{}
+	for _, move := range moves {
+		if move.From == id || move.To == id {
+			matched = append(matched, move)
+		}
+	}
+	return matched, nil
+}
+
+// MoveStudents 把学生从 from 班调到 to 班，学生必须都在 from 班，to 班的容量不足时不调动任何学生
+func (sm *StudentManager) MoveStudents(from, to string, studentIDs []int, reason string) ([]ClassMove, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditMoveStudents, reason)()
+	source, err := sm.findClass(from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := sm.findClass(to)
+	if err != nil {
+		return nil, err
+	}
+	if source.ID == target.ID {
+		return nil, &ValidationError{Field: "to", Rule: "different", Message: "must be a different class"}
+	}
+	return sm.transfer(source, target, studentIDs, ClassMoveTransfer, reason)
+}
+
+// MergeClasses 把 from 班的全部学生并入 into 班并删除 from 班
+func (sm *StudentManager) MergeClasses(from, into, reason string) ([]ClassMove, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditMergeClasses, reason)()
+	source, err := sm.findClass(from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := sm.findClass(into)
+	if err != nil {
+		return nil, err
+	}
+	if source.ID == target.ID {
+		return nil, &ValidationError{Field: "into", Rule: "different", Message: "must be a different class"}
+	}
+	roster, err := sm.enrolled(source.ID)
+	if err != nil {
+		return nil, err
+	}
+	studentIDs := make([]int, 0, len(roster))
+	for _, student := range roster {
+		studentIDs = append(studentIDs, student.GetID())
+	}
+	moves, err := sm.transfer(source, target, studentIDs, ClassMoveMerge, reason)
+	if err != nil {
+		return nil, err
+	}
+	if err := sm.classes.DeleteClass(source.ID); err != nil {
+		return nil, err
+	}
+	return moves, nil
+}
+
+// SplitClass 创建新班级并把 from 班中指定的学生调入新班级
+func (sm *StudentManager) SplitClass(from string, class Class, studentIDs []int, reason string) (*Class, []ClassMove, error) {
+	if err := class.Validate(); err != nil {
+		return nil, nil, err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditSplitClass, reason)()
+	source, err := sm.findClass(from)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(studentIDs) == 0 {
+		return nil, nil, &ValidationError{Field: "student_ids", Rule: "required", Message: "must not be empty"}
+	}
+	if err := sm.checkClassUnique(class, ""); err != nil {
+		return nil, nil, err
+	}
+	if class.Capacity > 0 && len(studentIDs) > class.Capacity {
+		return nil, nil, &ValidationError{Field: "class", Rule: "capacity", Message: fmt.Sprintf("class %s can take %d students, cannot take %d", class.ID, class.Capacity, len(studentIDs))}
+	}
+	if err := sm.classes.SaveClass(class); err != nil {
+		return nil, nil, err
+	}
+	moves, err := sm.transfer(source, class, studentIDs, ClassMoveSplit, reason)
+	if err != nil {
+		if rollbackErr := sm.classes.DeleteClass(class.ID); rollbackErr != nil {
+			return nil, nil, errors.Join(err, rollbackErr)
+		}
+		return nil, nil, err
+	}
+	return &class, moves, nil
+}
+
+// transfer 把 source 班中的学生调到 target 班并追加调班记录，调用方需持有锁
+// 全部学生先暂存再一起写入，任何一个学生不满足条件时不调动任何学生
+func (sm *StudentManager) transfer(source, target Class, studentIDs []int, operation, reason string) ([]ClassMove, error) {
+	if err := sm.checkCapacity(target, len(studentIDs)); err != nil {
+		return nil, err
+	}
+	staging := newStagingStore(sm.store, nil)
+	now := time.Now().UTC()
+	moves := make([]ClassMove, 0, len(studentIDs))
+	for _, studentID := range studentIDs {
+		record, exists, err := staging.Get(studentID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, studentNotFound(studentID)
+		}
+		student := record.GetBase()
+		if student.Class != source.ID {
+			return nil, &ValidationError{Field: "student_ids", Rule: "enrolled", Message: fmt.Sprintf("student %d is not in class %s", studentID, source.ID)}
+		}
+		student.Class = target.ID
+		if err := staging.Save(record); err != nil {
+			return nil, err
+		}
+		moves = append(moves, ClassMove{StudentID: studentID, From: source.ID, To: target.ID, Operation: operation, Reason: reason, Time: now})
+	}
+	if err := staging.commit(); err != nil {
+		return nil, err
+	}
+	if err := sm.classes.AppendClassMoves(moves); err != nil {
+		return nil, err
+	}
+	return moves, nil
+}
+
+// classMoveRequest 调班的请求体
+type classMoveRequest struct {
+	StudentIDs []int  `json:"student_ids" binding:"required"`
+	To         string `json:"to" binding:"required"`
+	Reason     string `json:"reason"`
+}
+
+// classMergeRequest 合并班级的请求体
+type classMergeRequest struct {
+	Into   string `json:"into" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+// classSplitRequest 拆分班级的请求体，class 为新班级的信息
+type classSplitRequest struct {
+	Class      Class  `json:"class"`
+	StudentIDs []int  `json:"student_ids" binding:"required"`
+	Reason     string `json:"reason"`
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newClassTestManager 创建有两个班级的 StudentManager，学生 1、2 在 CS1 班
+func newClassTestManager(t *testing.T, store StudentStore) *StudentManager {
+	t.Helper()
+	sm := NewStudentManagerWithStore(store)
+	for _, class := range []Class{
+		{ID: "CS1", Name: "计算机1班", GradeYear: 2025, Major: "计算机科学", HomeroomTeacher: "zhang", Capacity: 3},
+		{ID: "CS2", Name: "计算机2班", GradeYear: 2025, Capacity: 2},
+	} {
+		if _, err := sm.AddClass(class); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	for id, name := range map[int]string{1: "wei", 2: "hao"} {
+		if err := sm.AddStudent(&Undergraduate{Student{Name: name, StudentID: id, Class: "cs1"}}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	return sm
+}
+
+// registerClasses 创建测试中使用的班级，班级编号和名称相同
+func registerClasses(t *testing.T, sm *StudentManager, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		if _, err := sm.AddClass(Class{ID: id, Name: id}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+// TestAddClass 测试班级的校验和学生班级必须已存在
+func TestAddClass(t *testing.T) {
+	sm := newClassTestManager(t, NewMemoryStore())
+
+	tests := []struct {
+		class Class
+		field string
+	}{
+		{Class{Name: "物理1班"}, "id"},
+		{Class{ID: "PH/1", Name: "物理1班"}, "id"},
+		{Class{ID: "PH1"}, "name"},
+		{Class{ID: "PH1", Name: "物理1班", GradeYear: 25}, "grade_year"},
+		{Class{ID: "PH1", Name: "物理1班", Capacity: -1}, "capacity"},
+	}
+	for _, tt := range tests {
+		var validationErr *ValidationError
+		if _, err := sm.AddClass(tt.class); !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Errorf("%+v: expected validation error on %s, got %v", tt.class, tt.field, err)
+		}
+	}
+	if _, err := sm.AddClass(Class{ID: "CS3", Name: "计算机1班"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected class name conflict, got %v", err)
+	}
+
+	// 学生的班级按班级编号保存
+	student, _ := sm.QueryStudent(1)
+	if student.Class != "CS1" {
+		t.Errorf("Expected class CS1, got %q", student.Class)
+	}
+	var validationErr *ValidationError
+	if err := sm.ModifyStudent(1, map[string]interface{}{"class": "CS9"}); !errors.As(err, &validationErr) || validationErr.Rule != "registered" {
+		t.Errorf("Expected unknown class to be rejected, got %v", err)
+	}
+	sm.AddStudent(&Undergraduate{Student{Name: "li", StudentID: 3, Class: "CS2"}})
+	sm.AddStudent(&Undergraduate{Student{Name: "chen", StudentID: 4, Class: "CS2"}})
+	if err := sm.ModifyStudent(1, map[string]interface{}{"class": "计算机2班"}); !errors.As(err, &validationErr) || validationErr.Rule != "capacity" {
+		t.Errorf("Expected full class to be rejected, got %v", err)
+	}
+	if err := sm.ModifyStudent(3, map[string]interface{}{"class": "计算机1班"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	moves, _ := sm.ClassMoves("CS2")
+	if len(moves) != 1 || moves[0].StudentID != 3 || moves[0].To != "CS1" || moves[0].Operation != ClassMoveModify {
+		t.Errorf("Expected class change to be recorded, got %+v", moves)
+	}
+}
+
+// TestUnknownClass 测试没有创建任何班级时同样拒绝不存在的班级，班级为空表示未分班
+func TestUnknownClass(t *testing.T) {
+	sm := NewStudentManager()
+	var validationErr *ValidationError
+	if err := sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Class: "28"}}); !errors.As(err, &validationErr) || validationErr.Rule != "registered" {
+		t.Errorf("Expected unknown class to be rejected, got %v", err)
+	}
+	if err := sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1}}); err != nil {
+		t.Fatalf("Expected student without class to be added, got %v", err)
+	}
+}
+
+// TestMoveMergeSplitClasses 测试调班、合并和拆分班级
+func TestMoveMergeSplitClasses(t *testing.T) {
+	sm := newClassTestManager(t, NewMemoryStore())
+
+	var validationErr *ValidationError
+	if _, err := sm.MoveStudents("CS2", "CS1", []int{1}, ""); !errors.As(err, &validationErr) || validationErr.Rule != "enrolled" {
+		t.Errorf("Expected student outside the source class to be rejected, got %v", err)
+	}
+	moves, err := sm.MoveStudents("CS1", "CS2", []int{1}, "transfer request")
+	if err != nil || len(moves) != 1 || moves[0].Reason != "transfer request" {
+		t.Fatalf("Expected one move, got %+v %v", moves, err)
+	}
+	roster, _ := sm.ClassRoster("CS2")
+	if len(roster) != 1 || roster[0].GetID() != 1 {
+		t.Errorf("Expected student 1 in CS2, got %v", roster)
+	}
+
+	// CS2 容量为 2，不能再并入 CS1 的学生
+	sm.AddStudent(&Undergraduate{Student{Name: "li", StudentID: 3, Class: "CS1"}})
+	if _, err := sm.MergeClasses("CS1", "CS2", ""); !errors.As(err, &validationErr) || validationErr.Rule != "capacity" {
+		t.Errorf("Expected merge over capacity to be rejected, got %v", err)
+	}
+	if roster, _ := sm.ClassRoster("CS1"); len(roster) != 2 {
+		t.Errorf("Expected rejected merge to move nobody, got %v", roster)
+	}
+	if err := sm.DeleteClass("CS2"); !errors.Is(err, ErrInUse) {
+		t.Errorf("Expected class with students to be in use, got %v", err)
+	}
+	if _, err := sm.MergeClasses("CS2", "CS1", "merge"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := sm.QueryClass("CS2"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected merged class to be deleted, got %v", err)
+	}
+	if moves, _ := sm.ClassMoves("CS2"); len(moves) != 2 || moves[1].Operation != ClassMoveMerge {
+		t.Errorf("Expected history of the merged class to be kept, got %+v", moves)
+	}
+
+	class, moves, err := sm.SplitClass("CS1", Class{ID: "CS3", Name: "计算机3班"}, []int{2, 3}, "split")
+	if err != nil || class.ID != "CS3" || len(moves) != 2 {
+		t.Fatalf("Expected 2 students split into CS3, got %+v %+v %v", class, moves, err)
+	}
+	if student, _ := sm.QueryStudent(3); student.Class != "CS3" {
+		t.Errorf("Expected student 3 in CS3, got %q", student.Class)
+	}
+	if _, _, err := sm.SplitClass("CS1", Class{ID: "CS4", Name: "计算机4班"}, []int{2}, ""); !errors.As(err, &validationErr) {
+		t.Errorf("Expected split of a student outside the class to be rejected, got %v", err)
+	}
+	if _, err := sm.QueryClass("CS4"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected rejected split not to create CS4, got %v", err)
+	}
+}
+
+// TestClassPersistence 测试文件存储和 SQLite 存储在重新打开后保留班级和调班记录
+func TestClassPersistence(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]func() (StudentStore, error){
+		"file":   func() (StudentStore, error) { return NewFileStore(filepath.Join(dir, "students.json")) },
+		"sqlite": func() (StudentStore, error) { return NewSQLiteStore(filepath.Join(dir, "students.db")) },
+	}
+	for kind, open := range stores {
+		store, err := open()
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", kind, err)
+		}
+		sm := newClassTestManager(t, store)
+		if _, err := sm.MoveStudents("CS1", "CS2", []int{2}, "reason"); err != nil {
+			t.Fatalf("%s: expected no error, got %v", kind, err)
+		}
+		sm.Close()
+
+		store, err = open()
+		if err != nil {
+			t.Fatalf("%s: expected no error on reopen, got %v", kind, err)
+		}
+		sm = NewStudentManagerWithStore(store)
+		classes, err := sm.ListClasses()
+		if err != nil || len(classes) != 2 || classes[0].HomeroomTeacher != "zhang" || classes[1].Capacity != 2 {
+			t.Errorf("%s: expected 2 classes after reopen, got %+v %v", kind, classes, err)
+		}
+		moves, err := sm.ClassMoves("CS1")
+		if err != nil || len(moves) != 1 || moves[0].StudentID != 2 || moves[0].Reason != "reason" || moves[0].Time.IsZero() {
+			t.Errorf("%s: expected the move after reopen, got %+v %v", kind, moves, err)
+		}
+		sm.Close()
+	}
+}
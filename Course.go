@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// 课程类型
+const (
+	CourseRequired = "required" // 必修
+	CourseElective = "elective" // 选修
+)
+
+// Course 课程信息，课程代码唯一标识一门课程
+type Course struct {
+	Code     string  `json:"code"`
+	Name     string  `json:"name"`
+	Credits  float64 `json:"credits"`
+	Semester string  `json:"semester,omitempty"` // 开课学期，例如 2025-2026-1
+	Teacher  string  `json:"teacher,omitempty"`
+	Type     string  `json:"type"`
+	// Weights 成绩各组成部分（如 homework、lab、midterm、final）的权重百分比，合计为 100
+	Weights map[string]float64 `json:"weights,omitempty"`
+}
+
+// Validate 校验课程字段，类型为空时按必修处理
+func (c *Course) Validate() error {
+	c.Code = strings.TrimSpace(c.Code)
+	c.Name = strings.TrimSpace(c.Name)
+	if c.Code == "" {
+		return &ValidationError{Field: "code", Rule: "required", Message: "must not be empty"}
+	}
+	if strings.ContainsAny(c.Code, "/?#") {
+		return &ValidationError{Field: "code", Rule: "format", Message: "must not contain /, ? or #"}
+	}
+	if c.Name == "" {
+		return &ValidationError{Field: "name", Rule: "required", Message: "must not be empty"}
+	}
+	if c.Credits < 0 || math.IsNaN(c.Credits) || math.IsInf(c.Credits, 0) {
+		return &ValidationError{Field: "credits", Rule: "min", Message: "must be a non-negative number"}
+	}
+	if c.Type == "" {
+		c.Type = CourseRequired
+	}
+	if c.Type != CourseRequired && c.Type != CourseElective {
+		return &ValidationError{Field: "type", Message: fmt.Sprintf("must be %s or %s", CourseRequired, CourseElective)}
+	}
+	if len(c.Weights) > 0 {
+		total := 0.0
+		for component, weight := range c.Weights {
+			if strings.TrimSpace(component) == "" {
+				return &ValidationError{Field: "weights", Rule: "required", Message: "component names must not be empty"}
+			}
+			if weight <= 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+				return &ValidationError{Field: "weights." + component, Rule: "min", Message: "must be a positive number"}
+			}
+			total += weight
+		}
+		if math.Abs(total-100) > 1e-6 {
+			return &ValidationError{Field: "weights", Rule: "sum", Message: fmt.Sprintf("must add up to 100, got %v", total)}
+		}
+	}
+	return nil
+}
+
+// CourseStore 定义课程的存储接口，内置的三种学生存储都实现了该接口
+type CourseStore interface {
+	// SaveCourse 保存课程，已存在则覆盖
+	SaveCourse(course Course) error
+	// DeleteCourse 删除课程
+	DeleteCourse(code string) error
+	// ListCourses 按课程代码升序返回全部课程
+	ListCourses() ([]Course, error)
+}
+
+// courseNotFound 返回课程不存在的错误
+func courseNotFound(code string) error {
+	return fmt.Errorf("course %s %w", code, ErrNotFound)
+}
+
+// courseCatalog 已注册的全部课程，用于把课程代码或名称换算为课程代码
+type courseCatalog []Course
+
+// find 按课程代码或名称查找课程，不区分大小写和首尾空白
+func (catalog courseCatalog) find(courseName string) (Course, bool) {
+	courseName = strings.TrimSpace(courseName)
+	for _, course := range catalog {
+		if strings.EqualFold(course.Code, courseName) {
+			return course, true
+		}
+	}
+	for _, course := range catalog {
+		if strings.EqualFold(course.Name, courseName) {
+			return course, true
+		}
+	}
+	return Course{}, false
+}
+
+// resolve 返回成绩应记录在的课程代码，课程未注册时返回 ValidationError
+func (catalog courseCatalog) resolve(courseName string) (string, error) {
+	course, ok := catalog.find(courseName)
+	if !ok {
+		return "", &ValidationError{Field: "course_name", Rule: "registered", Message: fmt.Sprintf("course %q is not registered", courseName)}
+	}
+	return course.Code, nil
+}
+
+// lookup 返回查询和删除成绩时使用的课程代码，未注册的课程原样返回，以便访问注册课程之前录入的成绩
+func (catalog courseCatalog) lookup(courseName string) string {
+	if course, ok := catalog.find(courseName); ok {
+		return course.Code
+	}
+	return courseName
+}
+
+// catalog 读取已注册的全部课程，调用方需持有锁
+func (sm *StudentManager) catalog() (courseCatalog, error) {
+	courses, err := sm.courses.ListCourses()
+	if err != nil {
+		return nil, err
+	}
+	return courseCatalog(courses), nil
+}
+
+// resolveCourse 把课程代码或名称换算为课程代码，课程未注册时返回 ValidationError，调用方需持有锁
+func (sm *StudentManager) resolveCourse(courseName string) (string, error) {
+	catalog, err := sm.catalog()
+	if err != nil {
+		return "", err
+	}
+	return catalog.resolve(courseName)
+}
+
+// lookupCourse 把课程代码或名称换算为课程代码，未注册的课程原样返回，调用方需持有锁
+func (sm *StudentManager) lookupCourse(courseName string) (string, error) {
+	catalog, err := sm.catalog()
+	if err != nil {
+		return "", err
+	}
+	return catalog.lookup(courseName), nil
+}
+
+// checkCourseUnique 检查课程代码和名称没有被其他课程使用，调用方需持有锁
+func (sm *StudentManager) checkCourseUnique(course Course, except string) error {
+	catalog, err := sm.catalog()
+	if err != nil {
+		return err
+	}
+	for _, other := range catalog {
+		if other.Code == except {
+			continue
+		}
+		if strings.EqualFold(other.Code, course.Code) {
+			return fmt.Errorf("course %s %w", course.Code, ErrConflict)
+		}
+		if strings.EqualFold(other.Name, course.Name) || strings.EqualFold(other.Code, course.Name) || strings.EqualFold(other.Name, course.Code) {
+			return fmt.Errorf("course name %s %w as course %s", course.Name, ErrConflict, other.Code)
+		}
+	}
+	return nil
+}
+
+// AddCourse 注册课程，课程代码或名称与已有课程重复（不区分大小写）时返回 ErrConflict
+func (sm *StudentManager) AddCourse(course Course) (*Course, error) {
+	if err := course.Validate(); err != nil {
+		return nil, err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if err := sm.checkCourseUnique(course, ""); err != nil {
+		return nil, err
+	}
+	if err := sm.courses.SaveCourse(course); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// QueryCourse 按课程代码或名称查询课程
+func (sm *StudentManager) QueryCourse(courseName string) (*Course, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	catalog, err := sm.catalog()
+	if err != nil {
+		return nil, err
+	}
+	course, ok := catalog.find(courseName)
+	if !ok {
+		return nil, courseNotFound(courseName)
+	}
+	return &course, nil
+}
+
+// ListCourses 按课程代码升序返回全部课程
+func (sm *StudentManager) ListCourses() ([]Course, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.courses.ListCourses()
+}
+
+// ModifyCourse 修改课程信息，课程代码不能修改，已有成绩仍按课程代码关联
+// 修改成绩组成部分的权重会重新计算已录入组成部分的总评成绩，reason 为必填的修改原因，记录在审计日志中。
+// 先在暂存存储中重新计算，全部成功后才保存课程并写入学生，写入失败时恢复原来的课程和成绩
+func (sm *StudentManager) ModifyCourse(code string, course Course, reason string) (*Course, error) {
+	reason = strings.TrimSpace(reason)
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditRecomputeScores, reason)()
+	catalog, err := sm.catalog()
+	if err != nil {
+		return nil, err
+	}
+	existing, ok := catalog.find(code)
+	if !ok || !strings.EqualFold(existing.Code, strings.TrimSpace(code)) {
+		return nil, courseNotFound(code)
+	}
+	if course.Code != "" && course.Code != existing.Code {
+		return nil, &ValidationError{Field: "code", Rule: "immutable", Message: "course code cannot be changed"}
+	}
+	course.Code = existing.Code
+	if err := course.Validate(); err != nil {
+		return nil, err
+	}
+	if err := sm.checkCourseUnique(course, existing.Code); err != nil {
+		return nil, err
+	}
+
+	staging := newStagingStore(sm.store, nil)
+	if !reflect.DeepEqual(existing.Weights, course.Weights) {
+		if _, err := requireReason(reason); err != nil {
+			return nil, err
+		}
+		staged := *sm
+		staged.store = staging
+		if err := staged.recomputeComponentScores(course); err != nil {
+			return nil, err
+		}
+	}
+	if err := sm.courses.SaveCourse(course); err != nil {
+		return nil, err
+	}
+	if err := staging.commit(); err != nil {
+		if restoreErr := sm.courses.SaveCourse(existing); restoreErr != nil {
+			return nil, errors.Join(err, restoreErr)
+		}
+		return nil, err
+	}
+	return &course, nil
+}
+
+// DeleteCourse 删除课程，仍有学生有该课程的成绩时返回 ErrInUse
+func (sm *StudentManager) DeleteCourse(code string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	catalog, err := sm.catalog()
+	if err != nil {
+		return err
+	}
+	course, ok := catalog.find(code)
+	if !ok || !strings.EqualFold(course.Code, strings.TrimSpace(code)) {
+		return courseNotFound(code)
+	}
+	students, err := sm.store.List()
+	if err != nil {
+		return err
+	}
+	holders := 0
+	for _, student := range students {
+		if _, ok := student.GetScores()[course.Code]; ok {
+			holders++
+		}
+	}
+	if holders > 0 {
+		return fmt.Errorf("course %s is %w by scores of %d students", course.Code, ErrInUse, holders)
+	}
+	return sm.courses.DeleteCourse(course.Code)
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// 直方图分段数
+const (
+	defaultHistogramBuckets = 10  // 未指定分段时把记分制范围等分的段数
+	maxHistogramBuckets     = 100 // 按宽度分段时允许的最大段数
+)
+
+// CourseStatsOptions 课程成绩统计的条件
+type CourseStatsOptions struct {
+	Class       string    // 班级，精确匹配
+	Type        string    // 学生类型，精确匹配
+	Buckets     []float64 // 直方图的分段边界，升序，优先于 BucketWidth
+	BucketWidth float64   // 直方图每段的宽度，0 表示把记分制范围等分为 10 段
+}
+
+// HistogramBucket 直方图的一段，包含下界不包含上界，最后一段包含上界
+type HistogramBucket struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Count int     `json:"count"`
+}
+
+// CourseStats 课程成绩统计结果
+type CourseStats struct {
+	Course    string            `json:"course"`
+	Scale     string            `json:"scale"`
+	Count     int               `json:"count"`
+	Mean      float64           `json:"mean"`
+	Median    float64           `json:"median"`
+	StdDev    float64           `json:"stddev"` // 总体标准差
+	Min       float64           `json:"min"`
+	Max       float64           `json:"max"`
+	PassRate  float64           `json:"pass_rate"` // 及格人数占比（百分比）
+	Histogram []HistogramBucket `json:"histogram"`
+}
+
+// histogramEdges 返回直方图的分段边界
+func (opts CourseStatsOptions) histogramEdges(scale GradingScale) ([]float64, error) {
+	if len(opts.Buckets) > 0 {
+		if len(opts.Buckets) < 2 {
+			return nil, &ValidationError{Field: "buckets", Message: "must contain at least two boundaries"}
+		}
+		for i := 1; i < len(opts.Buckets); i++ {
+			if !(opts.Buckets[i] > opts.Buckets[i-1]) {
+				return nil, &ValidationError{Field: "buckets", Message: "must be in strictly ascending order"}
+			}
+		}
+		return opts.Buckets, nil
+	}
+
+	width := opts.BucketWidth
+	if width < 0 || math.IsNaN(width) || math.IsInf(width, 0) {
+		return nil, &ValidationError{Field: "bucket_width", Message: "must be a positive number"}
+	}
+	if scale.Max <= scale.Min {
+		return []float64{scale.Min, scale.Max}, nil
+	}
+	if width == 0 {
+		width = (scale.Max - scale.Min) / defaultHistogramBuckets
+	}
+	if (scale.Max-scale.Min)/width > maxHistogramBuckets {
+		return nil, &ValidationError{Field: "bucket_width", Message: fmt.Sprintf("must produce at most %d buckets", maxHistogramBuckets)}
+	}
+	edges := []float64{scale.Min}
+	for i := 1; scale.Min+float64(i)*width < scale.Max-1e-9; i++ {
+		edges = append(edges, round2(scale.Min+float64(i)*width))
+	}
+	return append(edges, scale.Max), nil
+}
+
+// CourseStats 统计课程成绩的人数、平均分、中位数、标准差、最高最低分、及格率和分数段分布
+// 没有任何学生有该课程成绩时返回 ErrNotFound；筛选后没有成绩时各项统计为 0
+func (sm *StudentManager) CourseStats(courseName string, opts CourseStatsOptions) (*CourseStats, error) {
+	sm.mu.Lock()
+	courseName, err := sm.lookupCourse(courseName)
+	if err != nil {
+		sm.mu.Unlock()
+		return nil, err
+	}
+	scale, err := sm.grading.ScaleFor(courseName)
+	if err != nil {
+		sm.mu.Unlock()
+		return nil, err
+	}
+	students, err := sm.store.List()
+	sm.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	edges, err := opts.histogramEdges(scale)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := StudentQuery{Class: opts.Class, Type: opts.Type}
+	found := false
+	var scores []float64
+	for _, record := range students {
+		student := record.GetBase()
+		score, ok := student.Scores[courseName]
+		if !ok {
+			continue
+		}
+		found = true
+		if filter.matches(student) {
+			scores = append(scores, score)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("course %s %w", courseName, ErrNotFound)
+	}
+
+	stats := &CourseStats{Course: courseName, Scale: scale.Name, Count: len(scores), Histogram: make([]HistogramBucket, len(edges)-1)}
+	for i := range stats.Histogram {
+		stats.Histogram[i] = HistogramBucket{Min: edges[i], Max: edges[i+1]}
+	}
+	if len(scores) == 0 {
+		return stats, nil
+	}
+
+	sort.Float64s(scores)
+	var sum float64
+	passed := 0
+	for _, score := range scores {
+		sum += score
+		if scale.Passed(score) {
+			passed++
+		}
+		// 落在最后一段上界的成绩计入最后一段，超出边界的成绩不计入直方图
+		index := sort.Search(len(edges), func(i int) bool { return edges[i] > score }) - 1
+		if index == len(edges)-1 && score == edges[len(edges)-1] {
+			index--
+		}
+		if index >= 0 && index < len(stats.Histogram) {
+			stats.Histogram[index].Count++
+		}
+	}
+	mean := sum / float64(len(scores))
+	var variance float64
+	for _, score := range scores {
+		variance += (score - mean) * (score - mean)
+	}
+	variance /= float64(len(scores))
+
+	middle := len(scores) / 2
+	median := scores[middle]
+	if len(scores)%2 == 0 {
+		median = (scores[middle-1] + scores[middle]) / 2
+	}
+
+	stats.Mean = round2(mean)
+	stats.Median = round2(median)
+	stats.StdDev = round2(math.Sqrt(variance))
+	stats.Min = scores[0]
+	stats.Max = scores[len(scores)-1]
+	stats.PassRate = round2(float64(passed) / float64(len(scores)) * 100)
+	return stats, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// newCourseStatsTestManager 创建两个班级、两种学生类型都有 Math 成绩的 StudentManager
+func newCourseStatsTestManager(t *testing.T) *StudentManager {
+	t.Helper()
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28", "29")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Class: "28", Scores: map[string]float64{"Math": 95}}})
+	sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 2, Class: "28", Scores: map[string]float64{"Math": 85}}})
+	sm.AddStudent(&Undergraduate{Student{Name: "li", StudentID: 3, Class: "28", Scores: map[string]float64{"Math": 55}}})
+	sm.AddStudent(&Graduate{Student: Student{Name: "zhang", StudentID: 4, Class: "28", Scores: map[string]float64{"Math": 100}}})
+	sm.AddStudent(&Undergraduate{Student{Name: "wang", StudentID: 5, Class: "29", Scores: map[string]float64{"Math": 65}}})
+	sm.AddStudent(&Undergraduate{Student{Name: "chen", StudentID: 6, Class: "29", Scores: map[string]float64{"English": 80}}})
+	return sm
+}
+
+// TestCourseStats 测试课程成绩的汇总统计和默认分段的直方图
+func TestCourseStats(t *testing.T) {
+	sm := newCourseStatsTestManager(t)
+
+	stats, err := sm.CourseStats("Math", CourseStatsOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 成绩为 55、65、85、95、100
+	if stats.Count != 5 || stats.Mean != 80 || stats.Median != 85 || stats.Min != 55 || stats.Max != 100 {
+		t.Errorf("Unexpected summary %+v", stats)
+	}
+	if stats.StdDev != 17.32 {
+		t.Errorf("Expected stddev 17.32, got %v", stats.StdDev)
+	}
+	if stats.PassRate != 80 {
+		t.Errorf("Expected pass rate 80, got %v", stats.PassRate)
+	}
+	if len(stats.Histogram) != 10 {
+		t.Fatalf("Expected 10 buckets, got %+v", stats.Histogram)
+	}
+	// 100 分计入最后一段 [90, 100]
+	expected := []int{0, 0, 0, 0, 0, 1, 1, 0, 1, 2}
+	for i, bucket := range stats.Histogram {
+		if bucket.Count != expected[i] {
+			t.Errorf("bucket [%v, %v): expected %d, got %d", bucket.Min, bucket.Max, expected[i], bucket.Count)
+		}
+	}
+}
+
+// TestCourseStatsFilters 测试按班级、学生类型筛选以及自定义分段
+func TestCourseStatsFilters(t *testing.T) {
+	sm := newCourseStatsTestManager(t)
+
+	stats, err := sm.CourseStats("Math", CourseStatsOptions{Class: "28", Type: "undergraduate", Buckets: []float64{0, 60, 90, 100}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 28 班本科生：55、85、95
+	if stats.Count != 3 || stats.Mean != 78.33 || stats.Median != 85 || stats.PassRate != 66.67 {
+		t.Errorf("Unexpected summary %+v", stats)
+	}
+	for i, count := range []int{1, 1, 1} {
+		if stats.Histogram[i].Count != count {
+			t.Errorf("bucket %d: expected %d, got %+v", i, count, stats.Histogram[i])
+		}
+	}
+
+	stats, err = sm.CourseStats("Math", CourseStatsOptions{BucketWidth: 25})
+	if err != nil || len(stats.Histogram) != 4 {
+		t.Errorf("Expected 4 buckets of width 25, got %+v %v", stats, err)
+	}
+
+	stats, err = sm.CourseStats("Math", CourseStatsOptions{Class: "30"})
+	if err != nil || stats.Count != 0 || stats.Mean != 0 {
+		t.Errorf("Expected empty stats for class without scores, got %+v %v", stats, err)
+	}
+}
+
+// TestCourseStatsErrors 测试不存在的课程和不合法的分段
+func TestCourseStatsErrors(t *testing.T) {
+	sm := newCourseStatsTestManager(t)
+
+	if _, err := sm.CourseStats("Chemistry", CourseStatsOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	var validationErr *ValidationError
+	for _, opts := range []CourseStatsOptions{
+		{Buckets: []float64{60}},
+		{Buckets: []float64{60, 60, 100}},
+		{BucketWidth: 0.1},
+	} {
+		if _, err := sm.CourseStats("Math", opts); !errors.As(err, &validationErr) {
+			t.Errorf("%+v: expected validation error, got %v", opts, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newCourseTestManager 创建注册了高等数学和英语两门课程的 StudentManager
+func newCourseTestManager(t *testing.T, store StudentStore) *StudentManager {
+	t.Helper()
+	sm := NewStudentManagerWithStore(store)
+	registerClasses(t, sm, "28")
+	for _, course := range []Course{
+		{Code: "MATH101", Name: "高等数学", Credits: 4, Semester: "2025-2026-1", Teacher: "zhang"},
+		{Code: "ENG101", Name: "English", Credits: 2, Type: CourseElective},
+	} {
+		if _, err := sm.AddCourse(course); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Class: "28"}})
+	return sm
+}
+
+// registerCourses 注册测试中使用的课程，课程代码和名称相同，学分为默认学分
+func registerCourses(t *testing.T, sm *StudentManager, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		if _, err := sm.AddCourse(Course{Code: name, Name: name, Credits: defaultCourseCredits}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+// TestAddCourse 测试课程的校验和重复检查
+func TestAddCourse(t *testing.T) {
+	sm := newCourseTestManager(t, NewMemoryStore())
+
+	course, err := sm.QueryCourse("math101")
+	if err != nil || course.Code != "MATH101" || course.Type != CourseRequired {
+		t.Errorf("Expected MATH101 to default to required, got %+v %v", course, err)
+	}
+
+	tests := []struct {
+		course Course
+		field  string
+	}{
+		{Course{Name: "Physics"}, "code"},
+		{Course{Code: "PHY/1", Name: "Physics"}, "code"},
+		{Course{Code: "PHY101"}, "name"},
+		{Course{Code: "PHY101", Name: "Physics", Credits: -1}, "credits"},
+		{Course{Code: "PHY101", Name: "Physics", Type: "optional"}, "type"},
+	}
+	for _, tt := range tests {
+		var validationErr *ValidationError
+		if _, err := sm.AddCourse(tt.course); !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Errorf("%+v: expected validation error on %s, got %v", tt.course, tt.field, err)
+		}
+	}
+
+	// 代码和名称都不区分大小写
+	for _, course := range []Course{{Code: "math101", Name: "Calculus"}, {Code: "ENG102", Name: "english"}} {
+		if _, err := sm.AddCourse(course); !errors.Is(err, ErrConflict) {
+			t.Errorf("%+v: expected conflict, got %v", course, err)
+		}
+	}
+}
+
+// TestAddScoreRegisteredCourse 测试注册课程后成绩按课程代码保存，未注册的课程被拒绝
+func TestAddScoreRegisteredCourse(t *testing.T) {
+	sm := newCourseTestManager(t, NewMemoryStore())
+
+	if err := sm.AddScore(1, "MATH101", "", 90); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 课程代码和名称都对应同一门课程，已有成绩不能再次录入
+	for _, name := range []string{"math101", "高等数学"} {
+		if err := sm.AddScore(1, name, "", 80); !errors.Is(err, ErrConflict) {
+			t.Errorf("%s: expected conflict, got %v", name, err)
+		}
+	}
+	student, _ := sm.QueryStudent(1)
+	if len(student.Scores) != 1 || student.Scores["MATH101"] != 90 {
+		t.Errorf("Expected a single MATH101 score, got %v", student.Scores)
+	}
+	if score, err := sm.QueryScore(1, "高等数学", ""); err != nil || score != 90 {
+		t.Errorf("Expected to query the score by course name, got %v %v", score, err)
+	}
+
+	var validationErr *ValidationError
+	if err := sm.AddScore(1, "Math", "", 80); !errors.As(err, &validationErr) || validationErr.Rule != "registered" {
+		t.Errorf("Expected unregistered course to be rejected, got %v", err)
+	}
+	err := sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 2, Scores: map[string]float64{"Physics": 80}}})
+	if !errors.As(err, &validationErr) || validationErr.Field != "scores.Physics" {
+		t.Errorf("Expected scores.Physics to be rejected, got %v", err)
+	}
+
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader("id,name,english,Math\n3,li,85,\n4,chen,,70\n"), ImportOptions{})
+	if err != nil || len(report.Accepted) != 1 || len(report.Rejected) != 1 {
+		t.Fatalf("Expected 1 accepted and 1 rejected row, got %+v %v", report, err)
+	}
+	if score, err := sm.QueryScore(3, "ENG101", ""); err != nil || score != 85 {
+		t.Errorf("Expected imported English score under ENG101, got %v %v", score, err)
+	}
+
+	// 已注册课程的学分取自课程信息
+	result, err := sm.ComputeGPA(1, "")
+	if err != nil || result.TotalCredits != 4 {
+		t.Errorf("Expected 4 credits for MATH101, got %+v %v", result, err)
+	}
+}
+
+// TestModifyDeleteCourse 测试修改和删除课程
+func TestModifyDeleteCourse(t *testing.T) {
+	sm := newCourseTestManager(t, NewMemoryStore())
+	sm.AddScore(1, "MATH101", "", 90)
+
+	course, err := sm.ModifyCourse("math101", Course{Name: "Calculus", Credits: 5}, "")
+	if err != nil || course.Code != "MATH101" || course.Name != "Calculus" {
+		t.Fatalf("Expected course renamed to Calculus, got %+v %v", course, err)
+	}
+	var validationErr *ValidationError
+	if _, err := sm.ModifyCourse("MATH101", Course{Code: "MATH102", Name: "Calculus"}, ""); !errors.As(err, &validationErr) || validationErr.Field != "code" {
+		t.Errorf("Expected course code to be immutable, got %v", err)
+	}
+	if _, err := sm.ModifyCourse("MATH101", Course{Name: "English"}, ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected name conflict with ENG101, got %v", err)
+	}
+	if _, err := sm.ModifyCourse("Calculus", Course{Name: "Calculus"}, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected course names not to be accepted as codes, got %v", err)
+	}
+
+	if err := sm.DeleteCourse("MATH101"); !errors.Is(err, ErrInUse) {
+		t.Errorf("Expected course with scores to be in use, got %v", err)
+	}
+	if err := sm.DeleteCourse("ENG101"); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if _, err := sm.QueryCourse("ENG101"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ENG101 to be deleted, got %v", err)
+	}
+}
+
+// TestCoursePersistence 测试文件存储和 SQLite 存储在重新打开后保留课程
+func TestCoursePersistence(t *testing.T) {
+	dir := t.TempDir()
+	stores := map[string]func() (StudentStore, error){
+		"file":   func() (StudentStore, error) { return NewFileStore(filepath.Join(dir, "students.json")) },
+		"sqlite": func() (StudentStore, error) { return NewSQLiteStore(filepath.Join(dir, "students.db")) },
+	}
+	for kind, open := range stores {
+		store, err := open()
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", kind, err)
+		}
+		sm := newCourseTestManager(t, store)
+		sm.AddScore(1, "高等数学", "", 90)
+		sm.Close()
+
+		store, err = open()
+		if err != nil {
+			t.Fatalf("%s: expected no error on reopen, got %v", kind, err)
+		}
+		sm = NewStudentManagerWithStore(store)
+		courses, err := sm.ListCourses()
+		if err != nil || len(courses) != 2 || courses[0].Code != "ENG101" || courses[1].Teacher != "zhang" {
+			t.Errorf("%s: expected 2 courses after reopen, got %+v %v", kind, courses, err)
+		}
+		if score, err := sm.QueryScore(1, "math101", ""); err != nil || score != 90 {
+			t.Errorf("%s: expected MATH101 score after reopen, got %v %v", kind, score, err)
+		}
+		sm.Close()
+	}
+}
+
+// TestFileStoreLegacyFormat 测试读取只有学生数组的旧版数据文件
+func TestFileStoreLegacyFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.json")
+	legacy := `[{"type":"undergraduate","name":"wei","id":1,"gender":"male","class":"28","scores":{"Math":95}}]`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerClasses(t, sm, "28")
+	var validationErr *ValidationError
+	if err := sm.AddScore(1, "History", "", 80); !errors.As(err, &validationErr) || validationErr.Rule != "registered" {
+		t.Errorf("Expected unregistered course to be rejected without any registered course, got %v", err)
+	}
+	registerCourses(t, sm, "History")
+	if err := sm.AddScore(1, "History", "", 80); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.HasPrefix(string(data), "{") || !strings.Contains(string(data), `"History": 80`) {
+		t.Errorf("Expected data file to be rewritten in the current format, got %s", data)
+	}
+}
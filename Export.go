@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 导出格式
+const (
+	ExportCSV   = "csv"   // 与 /import 的表头格式一致，可以直接导入；每个学期的每门课程成绩一行
+	ExportJSONL = "jsonl" // 每行一个学生的 JSON
+	ExportXLSX  = "xlsx"  // Excel 工作簿，列与 CSV 相同
+)
+
+// exportContentTypes 导出格式对应的 Content-Type
+var exportContentTypes = map[string]string{
+	ExportCSV:   "text/csv; charset=utf-8",
+	ExportJSONL: "application/x-ndjson",
+	ExportXLSX:  "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// exportPageSize 每次从存储读取的学生数，每写完一页刷新一次输出
+const exportPageSize = 100
+
+// exportSheet XLSX 导出的工作表名
+const exportSheet = "students"
+
+// ExportContentType 返回导出格式的 Content-Type，不支持的格式返回 ValidationError
+func ExportContentType(format string) (string, error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return "", &ValidationError{Field: "format", Message: fmt.Sprintf("must be one of %s, %s or %s", ExportCSV, ExportJSONL, ExportXLSX)}
+	}
+	return contentType, nil
+}
+
+// exportTable 表格导出的列：基本字段、所有学生类型的特有字段、学期、补考成绩和出现过的课程
+// 有组成部分成绩的课程在课程列后紧跟各组成部分的列，列名为“课程.组成部分”，例如 MATH.homework
+type exportTable struct {
+	profile []string
+	courses []exportColumn
+}
+
+// exportColumn 成绩列，component 为空时是课程的总评成绩列
+type exportColumn struct {
+	course    string
+	component string
+}
+
+// name 返回成绩列的表头
+func (column exportColumn) name() string {
+	if column.component == "" {
+		return column.course
+	}
+	return column.course + "." + column.component
+}
+
+// newExportTable 逐页读取要导出的学生确定列，课程和组成部分按名称排序
+func (sm *StudentManager) newExportTable(query StudentQuery) (exportTable, error) {
+	seen := make(map[exportColumn]bool)
+	var courses []exportColumn
+	add := func(column exportColumn) {
+		if !seen[column] {
+			seen[column] = true
+			courses = append(courses, column)
+		}
+	}
+	err := sm.eachStudentPage(query, func(students []StudentInterface) error {
+		for _, student := range students {
+			for _, record := range student.GetBase().termScores() {
+				add(exportColumn{course: record.Course})
+				for component := range record.Components {
+					add(exportColumn{course: record.Course, component: component})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return exportTable{}, err
+	}
+	sort.Slice(courses, func(i, j int) bool {
+		if courses[i].course != courses[j].course {
+			return courses[i].course < courses[j].course
+		}
+		return courses[i].component < courses[j].component
+	})
+	return exportTable{profile: profileFieldNames(), courses: courses}, nil
+}
+
+// header 返回表头，与 /import 识别的列名一致
+func (t exportTable) header() []string {
+	header := []string{"type", "id", "name", "gender", "class"}
+	header = append(header, t.profile...)
+	header = append(header, "term", "makeup")
+	for _, column := range t.courses {
+		header = append(header, column.name())
+	}
+	return header
+}
+
+// rows 返回学生对应的各行，每个学期的每门课程成绩一行，行中只有该课程的成绩列和补考列有值；
+// 没有成绩的学生输出一行。没有的字段和成绩为空字符串，成绩为 float64
+func (t exportTable) rows(student StudentInterface) ([][]interface{}, error) {
+	base := student.GetBase()
+	profile := []interface{}{student.GetType(), base.StudentID, base.Name, base.Gender, base.Class}
+
+	// 类型特有的字段从 JSON 中读取，其他类型的字段留空
+	var fields map[string]interface{}
+	if len(t.profile) > 0 {
+		data, err := json.Marshal(student)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+	}
+	for _, field := range t.profile {
+		if value, ok := fields[field]; ok && value != nil {
+			profile = append(profile, fmt.Sprint(value))
+		} else {
+			profile = append(profile, "")
+		}
+	}
+
+	records := base.termScores()
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Course != records[j].Course {
+			return records[i].Course < records[j].Course
+		}
+		return records[i].Term < records[j].Term
+	})
+	if len(records) == 0 {
+		records = []TermScore{{}}
+	}
+	rows := make([][]interface{}, 0, len(records))
+	for _, record := range records {
+		row := append(append([]interface{}{}, profile...), record.Term, "")
+		if record.Makeup != nil {
+			row[len(row)-1] = *record.Makeup
+		}
+		found := 0
+		if record.Course == "" {
+			found = 1 + len(record.Components)
+		}
+		for _, column := range t.courses {
+			switch score, ok := record.Components[column.component]; {
+			case record.Course != column.course:
+				row = append(row, "")
+			case column.component == "":
+				row = append(row, record.Score)
+				found++
+			case ok:
+				row = append(row, score)
+				found++
+			default:
+				row = append(row, "")
+			}
+		}
+		// 确定列之后才录入的课程或组成部分没有对应的列，不能静默丢弃成绩
+		if found != 1+len(record.Components) {
+			return nil, fmt.Errorf("scores of course %s were added during the export, export again", record.Course)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// flushWriter 在 w 支持时把已写入的数据发送给客户端
+func flushWriter(w io.Writer) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// ExportStudents 按筛选条件把学生及成绩以指定格式写入 w
+// 学生按学号逐页读取，不会一次载入全部学生；JSONL 和 CSV 边读取边写入，每写完一页刷新一次，
+// XLSX 在 excelize 中生成完整的工作簿后才写入 w。表格格式需要先读取一遍学生确定课程列。
+// 各页在不同时刻读取，导出期间被修改的学生可能是修改前或修改后的数据；返回错误时 w 中可能已有部分数据
+func (sm *StudentManager) ExportStudents(w io.Writer, format string, query StudentQuery) error {
+	if _, err := ExportContentType(format); err != nil {
+		return err
+	}
+
+	switch format {
+	case ExportJSONL:
+		encoder := json.NewEncoder(w)
+		return sm.eachStudentPage(query, func(students []StudentInterface) error {
+			for _, student := range students {
+				if err := encoder.Encode(student); err != nil {
+					return err
+				}
+			}
+			flushWriter(w)
+			return nil
+		})
+	case ExportCSV:
+		return sm.exportCSV(w, query)
+	default:
+		return sm.exportXLSX(w, query)
+	}
+}
+
+// exportCSV 以 /import 可识别的表头格式写入 CSV
+func (sm *StudentManager) exportCSV(w io.Writer, query StudentQuery) error {
+	table, err := sm.newExportTable(query)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.Write(table.header()); err != nil {
+		return err
+	}
+	record := make([]string, len(table.header()))
+	err = sm.eachStudentPage(query, func(students []StudentInterface) error {
+		for _, student := range students {
+			rows, err := table.rows(student)
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				for j, value := range row {
+					switch value := value.(type) {
+					case float64:
+						record[j] = strconv.FormatFloat(value, 'f', -1, 64)
+					default:
+						record[j] = fmt.Sprint(value)
+					}
+				}
+				if err := writer.Write(record); err != nil {
+					return err
+				}
+			}
+		}
+		writer.Flush()
+		flushWriter(w)
+		return writer.Error()
+	})
+	if err != nil {
+		return err
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportXLSX 写入只有一个工作表的 Excel 工作簿，列与 CSV 相同
+func (sm *StudentManager) exportXLSX(w io.Writer, query StudentQuery) error {
+	table, err := sm.newExportTable(query)
+	if err != nil {
+		return err
+	}
+	file := excelize.NewFile()
+	defer file.Close()
+	if err := file.SetSheetName(file.GetSheetName(0), exportSheet); err != nil {
+		return err
+	}
+	stream, err := file.NewStreamWriter(exportSheet)
+	if err != nil {
+		return err
+	}
+
+	header := table.header()
+	cells := make([]interface{}, len(header))
+	for i, name := range header {
+		cells[i] = name
+	}
+	if err := stream.SetRow("A1", cells); err != nil {
+		return err
+	}
+	line := 2
+	err = sm.eachStudentPage(query, func(students []StudentInterface) error {
+		for _, student := range students {
+			rows, err := table.rows(student)
+			if err != nil {
+				return err
+			}
+			for _, row := range rows {
+				cell, err := excelize.CoordinatesToCellName(1, line)
+				if err != nil {
+					return err
+				}
+				if err := stream.SetRow(cell, row); err != nil {
+					return err
+				}
+				line++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := stream.Flush(); err != nil {
+		return err
+	}
+	return file.Write(w)
+}
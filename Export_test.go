@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newExportTestManager 创建包含本科生、研究生和多种记分制成绩的 StudentManager
+func newExportTestManager(t *testing.T) *StudentManager {
+	t.Helper()
+	policy := NewGradingPolicy()
+	policy.Courses["English"] = CourseGrading{Scale: ScaleLetter}
+	sm := NewStudentManager()
+	sm.SetGradingPolicy(policy)
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28", "29")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28", Scores: map[string]float64{"Math": 95.5, "English": 3.7}}})
+	sm.AddStudent(&Graduate{
+		Student:      Student{Name: "hao, jr", StudentID: 2, Gender: "female", Class: "28", Scores: map[string]float64{"Math": 88}},
+		Advisor:      "zhang",
+		ResearchArea: "ml",
+		DegreeType:   DegreePhD,
+	})
+	sm.AddStudent(&Undergraduate{Student{Name: "li", StudentID: 3, Gender: "male", Class: "29"}})
+	return sm
+}
+
+// TestExportCSVRoundTrip 测试导出的 CSV 可以原样导入
+func TestExportCSVRoundTrip(t *testing.T) {
+	sm := newExportTestManager(t)
+	var buf bytes.Buffer
+	if err := sm.ExportStudents(&buf, ExportCSV, StudentQuery{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	header := strings.SplitN(buf.String(), "\n", 2)[0]
+	if !strings.HasPrefix(header, "type,id,name,gender,class,advisor") || !strings.HasSuffix(header, ",term,makeup,English,Math") {
+		t.Errorf("Unexpected header %q", header)
+	}
+
+	imported := NewStudentManager()
+	imported.SetGradingPolicy(sm.GradingPolicy())
+	registerCourses(t, imported, "Math", "English")
+	registerClasses(t, imported, "28", "29")
+	// 每门课程成绩一行，学生 1 有两行
+	report, err := imported.ImportCSV(context.Background(), &buf, ImportOptions{})
+	if err != nil || len(report.Accepted) != 4 {
+		t.Fatalf("Expected 4 imported rows, got %+v %v", report, err)
+	}
+	for _, id := range []int{1, 2, 3} {
+		original, _ := sm.QueryStudentDetail(id)
+		copied, err := imported.QueryStudentDetail(id)
+		if err != nil {
+			t.Fatalf("Expected student %d to be imported, got %v", id, err)
+		}
+		// 导入时没有成绩的学生 Scores 为空映射
+		if len(original.GetScores()) == 0 && len(copied.GetScores()) == 0 {
+			copied.SetScores(original.GetScores())
+		}
+		if !reflect.DeepEqual(original, copied) {
+			t.Errorf("Expected round trip of %+v, got %+v", original, copied)
+		}
+	}
+}
+
+// TestExportFormats 测试 JSON Lines 和 XLSX 导出以及筛选条件
+func TestExportFormats(t *testing.T) {
+	sm := newExportTestManager(t)
+
+	var buf bytes.Buffer
+	if err := sm.ExportStudents(&buf, ExportJSONL, StudentQuery{Class: "28"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines for class 28, got %q", buf.String())
+	}
+	student, err := decodeStudent([]byte(lines[1]))
+	if graduate, ok := student.(*Graduate); err != nil || !ok || graduate.Advisor != "zhang" {
+		t.Errorf("Expected graduate with advisor, got %+v %v", student, err)
+	}
+
+	buf.Reset()
+	if err := sm.ExportStudents(&buf, ExportXLSX, StudentQuery{Type: "undergraduate"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("Expected a valid workbook, got %v", err)
+	}
+	defer file.Close()
+	rows, err := file.GetRows(exportSheet)
+	if err != nil || len(rows) != 4 {
+		t.Fatalf("Expected header and a row for each score, got %v %v", rows, err)
+	}
+	if rows[2][1] != "1" || rows[2][len(rows[2])-1] != "95.5" {
+		t.Errorf("Unexpected Math row %v", rows[2])
+	}
+
+	var validationErr *ValidationError
+	if err := sm.ExportStudents(&buf, "pdf", StudentQuery{}); !errors.As(err, &validationErr) {
+		t.Errorf("Expected validation error for unknown format, got %v", err)
+	}
+}
+
+// TestExportTermsRoundTrip 测试同一课程多个学期的成绩和补考成绩在导出后可以原样导入
+func TestExportTermsRoundTrip(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	sm.AddScore(1, "Math", "2024-2025-1", 45)
+	sm.RecordMakeup(1, "Math", "2024-2025-1", 62, "")
+	sm.AddScore(1, "Math", "2025-2026-1", 88)
+	sm.AddScore(1, "English", "2025-2026-1", 90)
+	original, _ := sm.QueryStudentDetail(1)
+
+	for _, format := range []string{ExportCSV, ExportXLSX} {
+		var buf bytes.Buffer
+		if err := sm.ExportStudents(&buf, format, StudentQuery{}); err != nil {
+			t.Fatalf("%s: expected no error, got %v", format, err)
+		}
+		imported := NewStudentManager()
+		registerCourses(t, imported, "Math", "English")
+		registerClasses(t, imported, "28")
+		report, err := imported.Import(context.Background(), &buf, ImportOptions{Format: format, Atomic: true})
+		if err != nil || len(report.Accepted) != 3 || report.RolledBack {
+			t.Fatalf("%s: expected a row for each term score, got %+v %v", format, report, err)
+		}
+		copied, _ := imported.QueryStudentDetail(1)
+		if !reflect.DeepEqual(original, copied) {
+			t.Errorf("%s: expected round trip of %+v, got %+v", format, original, copied)
+		}
+	}
+
+	// 补考列只能对应一门课程的成绩
+	report, _ := sm.ImportCSV(context.Background(), strings.NewReader("id,name,makeup,Math,English\n2,hao,70,50,60"), ImportOptions{})
+	if len(report.Rejected) != 1 || !strings.Contains(report.Rejected[0].Reason, "makeup") {
+		t.Errorf("Expected makeup with two course scores to be rejected, got %+v", report)
+	}
+}
+
+// TestExportPages 测试学生超过一页时按学号顺序全部导出，筛选条件在每一页上生效
+func TestExportPages(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	registerClasses(t, sm, "28", "29")
+	count := exportPageSize*2 + 5
+	for id := count; id >= 1; id-- {
+		class := "28"
+		if id%2 == 0 {
+			class = "29"
+		}
+		sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: id, Class: class, Scores: map[string]float64{"Math": 90}}})
+	}
+
+	var buf bytes.Buffer
+	if err := sm.ExportStudents(&buf, ExportCSV, StudentQuery{Class: "28"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != count/2+2 {
+		t.Fatalf("Expected header and %d rows, got %d", count/2+1, len(lines)-1)
+	}
+	for i, line := range lines[1:] {
+		if want := "undergraduate," + strconv.Itoa(2*i+1) + ","; !strings.HasPrefix(line, want) {
+			t.Fatalf("Expected row %d to start with %q, got %q", i, want, line)
+		}
+	}
+}
+
+// TestExportComponentsRoundTrip 测试组成部分成绩导出为“课程.组成部分”列，导入后保留组成部分并按权重计算总评成绩
+func TestExportComponentsRoundTrip(t *testing.T) {
+	sm := newComponentTestManager(t, NewMemoryStore())
+	sm.AddScoreComponent(1, "MATH101", "2025-2026-1", "homework", 90, "")
+	sm.AddScoreComponent(1, "MATH101", "2025-2026-1", "final", 80, "")
+	sm.AddScore(1, "ENG101", "2025-2026-1", 75)
+	original, _ := sm.QueryStudentDetail(1)
+
+	for _, format := range []string{ExportCSV, ExportXLSX} {
+		var buf bytes.Buffer
+		if err := sm.ExportStudents(&buf, format, StudentQuery{}); err != nil {
+			t.Fatalf("%s: expected no error, got %v", format, err)
+		}
+		if format == ExportCSV {
+			header := strings.SplitN(buf.String(), "\n", 2)[0]
+			if !strings.HasSuffix(header, ",ENG101,MATH101,MATH101.final,MATH101.homework") {
+				t.Errorf("Unexpected header %q", header)
+			}
+		}
+		imported := newComponentTestManager(t, NewMemoryStore())
+		imported.DeleteStudent(1)
+		report, err := imported.Import(context.Background(), &buf, ImportOptions{Format: format, Atomic: true})
+		if err != nil || len(report.Accepted) != 2 || report.RolledBack {
+			t.Fatalf("%s: expected a row for each score, got %+v %v", format, report, err)
+		}
+		copied, _ := imported.QueryStudentDetail(1)
+		if !reflect.DeepEqual(original, copied) {
+			t.Errorf("%s: expected round trip of %+v, got %+v", format, original, copied)
+		}
+	}
+
+	// 总评成绩必须与按组成部分计算的一致，只填组成部分时计算总评成绩
+	data := "id,name,class,MATH101,MATH101.homework,MATH101.final\n2,hao,28,90,90,80\n3,li,28,,90,80"
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader(data), ImportOptions{})
+	if err != nil || len(report.Rejected) != 1 || report.Rejected[0].Line != 2 || len(report.Accepted) != 1 {
+		t.Fatalf("Expected the mismatching total to be rejected, got %+v %v", report, err)
+	}
+	if score, err := sm.QueryScore(3, "MATH101", ""); err != nil || score != 58 {
+		t.Errorf("Expected total 58 computed from the components, got %v %v", score, err)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 绩点换算公式名称
+const (
+	FormulaStandard4 = "standard4" // 标准 4.0：90 分以上 4.0，80 分 3.0，70 分 2.0，60 分 1.0
+	FormulaPKU       = "pku"       // 北京大学公式：4 - 3 × (100 - x)² / 1600，60 分以下为 0
+	FormulaCustom    = "custom"    // 使用策略中配置的分数段
+)
+
+// passingPercent 折算为百分制后的及格线
+const passingPercent = 60
+
+// defaultCourseCredits 未配置学分的课程按 1 学分计算
+const defaultCourseCredits = 1.0
+
+// GPABand 自定义绩点分数段：百分制成绩不低于 Min 时取 Points
+type GPABand struct {
+	Min    float64 `json:"min"`
+	Points float64 `json:"points"`
+}
+
+// standard4Bands 标准 4.0 的分数段
+var standard4Bands = []GPABand{
+	{Min: 90, Points: 4.0},
+	{Min: 80, Points: 3.0},
+	{Min: 70, Points: 2.0},
+	{Min: 60, Points: 1.0},
+}
+
+// gpaFormulas 支持的绩点换算公式
+var gpaFormulas = []string{FormulaCustom, FormulaPKU, FormulaStandard4}
+
+// CourseGPA 单门课程的学分和绩点
+type CourseGPA struct {
+	Course     string   `json:"course"`
+	Credits    float64  `json:"credits"`
+	Score      float64  `json:"score"`
+	Scale      string   `json:"scale"`
+	Percent    *float64 `json:"percent,omitempty"`     // 折算后的百分制成绩，等级制和通过制课程没有
+	GradePoint *float64 `json:"grade_point,omitempty"` // 绩点，通过制课程不计绩点
+	Passed     bool     `json:"passed"`
+}
+
+// GPAResult 学生的学分、加权平均分和绩点
+type GPAResult struct {
+	StudentID       int         `json:"id"`
+	Formula         string      `json:"formula"`
+	TotalCredits    float64     `json:"total_credits"`    // 已有成绩课程的学分之和
+	EarnedCredits   float64     `json:"earned_credits"`   // 及格或通过课程的学分之和
+	WeightedAverage float64     `json:"weighted_average"` // 数值型记分制课程按学分加权的百分制平均分
+	GPA             float64     `json:"gpa"`              // 计绩点课程按学分加权的平均绩点
+	Courses         []CourseGPA `json:"courses"`
+}
+
+// CreditsFor 返回课程学分
+func (gp *GradingPolicy) CreditsFor(courseName string) float64 {
+	if rule, ok := gp.Courses[courseName]; ok && rule.Credits != nil {
+		return *rule.Credits
+	}
+	return defaultCourseCredits
+}
+
+// checkGPAFormula 检查绩点公式及自定义分数段
+func (gp *GradingPolicy) checkGPAFormula() error {
+	switch gp.GPAFormula {
+	case "", FormulaStandard4, FormulaPKU:
+		return nil
+	case FormulaCustom:
+		if len(gp.GPABands) == 0 {
+			return fmt.Errorf("gpa formula %q requires gpa_bands", FormulaCustom)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown gpa formula %q, must be one of %s", gp.GPAFormula, strings.Join(gpaFormulas, ", "))
+	}
+}
+
+// gradePoint 按公式把百分制成绩换算为绩点
+func (gp *GradingPolicy) gradePoint(formula string, percent float64) float64 {
+	switch formula {
+	case FormulaPKU:
+		if percent < passingPercent {
+			return 0
+		}
+		return 4 - 3*(100-percent)*(100-percent)/1600
+	case FormulaCustom:
+		return bandPoints(gp.GPABands, percent)
+	default:
+		return bandPoints(standard4Bands, percent)
+	}
+}
+
+// bandPoints 返回成绩所在分数段的绩点，低于所有分数段时为 0
+func bandPoints(bands []GPABand, percent float64) float64 {
+	sorted := append([]GPABand(nil), bands...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Min > sorted[j].Min
+	})
+	for _, band := range sorted {
+		if percent >= band.Min {
+			return band.Points
+		}
+	}
+	return 0
+}
+
+// round2 保留两位小数
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// ComputeGPA 计算学生的总学分、加权平均分和绩点
+// formula 为空时使用成绩校验策略中配置的公式，未配置时使用标准 4.0。
+// 字母等级制课程直接使用保存的绩点；通过制课程只计学分，不计入平均分和绩点。
+// 已注册课程的学分取自课程信息，其余课程取自成绩校验策略
+func (sm *StudentManager) ComputeGPA(studentID int, formula string) (*GPAResult, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	policy := sm.grading
+	if formula == "" {
+		formula = policy.GPAFormula
+	}
+	if formula == "" {
+		formula = FormulaStandard4
+	}
+	if formula == FormulaCustom && len(policy.GPABands) == 0 {
+		return nil, &ValidationError{Field: "formula", Rule: "gpa_bands", Message: "no custom gpa bands are configured"}
+	}
+	if formula != FormulaStandard4 && formula != FormulaPKU && formula != FormulaCustom {
+		return nil, &ValidationError{Field: "formula", Message: fmt.Sprintf("must be one of %s", strings.Join(gpaFormulas, ", "))}
+	}
+
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, studentNotFound(studentID)
+	}
+
+	catalog, err := sm.catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GPAResult{StudentID: studentID, Formula: formula, Courses: []CourseGPA{}}
+	courses := make([]string, 0, len(record.GetScores()))
+	for course := range record.GetScores() {
+		courses = append(courses, course)
+	}
+	sort.Strings(courses)
+
+	var averageCredits, averageSum, gpaCredits, gpaSum float64
+	for _, course := range courses {
+		score := record.GetScores()[course]
+		scale, err := policy.ScaleFor(course)
+		if err != nil {
+			return nil, err
+		}
+		entry := CourseGPA{
+			Course:  course,
+			Credits: policy.CreditsFor(course),
+			Score:   score,
+			Scale:   scale.Name,
+		}
+		// 已注册的课程以课程信息中的学分为准
+		if registered, ok := catalog.find(course); ok && registered.Code == course {
+			entry.Credits = registered.Credits
+		}
+
+		entry.Passed = scale.Passed(score)
+		switch scale.Name {
+		case ScalePassFail:
+		case ScaleLetter:
+			point := score
+			entry.GradePoint = &point
+		default:
+			percent := scale.Percent(score)
+			point := policy.gradePoint(formula, percent)
+			percent = round2(percent)
+			point = round2(point)
+			entry.Percent = &percent
+			entry.GradePoint = &point
+			averageCredits += entry.Credits
+			averageSum += entry.Credits * percent
+		}
+
+		if entry.GradePoint != nil {
+			gpaCredits += entry.Credits
+			gpaSum += entry.Credits * *entry.GradePoint
+		}
+		result.TotalCredits += entry.Credits
+		if entry.Passed {
+			result.EarnedCredits += entry.Credits
+		}
+		result.Courses = append(result.Courses, entry)
+	}
+
+	if averageCredits > 0 {
+		result.WeightedAverage = round2(averageSum / averageCredits)
+	}
+	if gpaCredits > 0 {
+		result.GPA = round2(gpaSum / gpaCredits)
+	}
+	return result, nil
+}
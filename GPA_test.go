@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// newGPATestManager 创建带有多种记分制课程成绩的 StudentManager
+func newGPATestManager(t *testing.T) *StudentManager {
+	t.Helper()
+	credits := func(value float64) *float64 { return &value }
+	policy := NewGradingPolicy()
+	policy.Courses["Math"] = CourseGrading{Credits: credits(4)}
+	policy.Courses["Physics"] = CourseGrading{Scale: ScaleFive, Credits: credits(3)}
+	policy.Courses["English"] = CourseGrading{Scale: ScaleLetter, Credits: credits(2)}
+	policy.Courses["PE"] = CourseGrading{Scale: ScalePassFail, Credits: credits(1)}
+
+	sm := NewStudentManager()
+	registerClasses(t, sm, "28")
+	sm.SetGradingPolicy(policy)
+	for _, course := range []Course{
+		{Code: "Math", Name: "Math", Credits: 4},
+		{Code: "Physics", Name: "Physics", Credits: 3},
+		{Code: "English", Name: "English", Credits: 2},
+		{Code: "PE", Name: "PE", Credits: 1},
+	} {
+		sm.AddCourse(course)
+	}
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	sm.AddScore(1, "Math", "", 95)
+	sm.AddScore(1, "Physics", "", 4.5)
+	sm.AddScore(1, "English", "", 3.7)
+	sm.AddScore(1, "PE", "", 1)
+	return sm
+}
+
+// TestComputeGPA 测试不同绩点公式下的学分、加权平均分和绩点
+func TestComputeGPA(t *testing.T) {
+	sm := newGPATestManager(t)
+
+	tests := []struct {
+		formula string
+		gpa     float64
+	}{
+		{"", 3.93},
+		{FormulaStandard4, 3.93},
+		{FormulaPKU, 3.85},
+	}
+	for _, tt := range tests {
+		result, err := sm.ComputeGPA(1, tt.formula)
+		if err != nil {
+			t.Fatalf("formula %q: expected no error, got %v", tt.formula, err)
+		}
+		if result.GPA != tt.gpa {
+			t.Errorf("formula %q: expected GPA %v, got %v", tt.formula, tt.gpa, result.GPA)
+		}
+		if result.TotalCredits != 10 || result.EarnedCredits != 10 {
+			t.Errorf("formula %q: expected 10 credits, got %v / %v", tt.formula, result.TotalCredits, result.EarnedCredits)
+		}
+		if result.WeightedAverage != 92.86 {
+			t.Errorf("formula %q: expected weighted average 92.86, got %v", tt.formula, result.WeightedAverage)
+		}
+		if len(result.Courses) != 4 {
+			t.Errorf("formula %q: expected 4 courses, got %v", tt.formula, result.Courses)
+		}
+	}
+}
+
+// TestComputeGPACustomBands 测试自定义分数段及不及格课程
+func TestComputeGPACustomBands(t *testing.T) {
+	sm := newGPATestManager(t)
+	policy := sm.GradingPolicy()
+	policy.GPAFormula = FormulaCustom
+	policy.GPABands = []GPABand{{Min: 60, Points: 1}, {Min: 85, Points: 4}}
+	sm.ModifyScore(1, "Physics", "", 2.5, "复核")
+	sm.ModifyScore(1, "PE", "", 0, "复核")
+
+	result, err := sm.ComputeGPA(1, "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// (4 × 4 + 3 × 0 + 2 × 3.7) / 9：Math 95 → 4，Physics 50% → 0，English 3.7
+	if result.GPA != 2.6 {
+		t.Errorf("Expected GPA 2.6, got %v", result.GPA)
+	}
+	if result.TotalCredits != 10 || result.EarnedCredits != 6 {
+		t.Errorf("Expected 10 total and 6 earned credits, got %v / %v", result.TotalCredits, result.EarnedCredits)
+	}
+}
+
+// TestComputeGPAErrors 测试未知公式和不存在的学生
+func TestComputeGPAErrors(t *testing.T) {
+	sm := newGPATestManager(t)
+
+	var validationErr *ValidationError
+	if _, err := sm.ComputeGPA(1, "wes"); !errors.As(err, &validationErr) {
+		t.Errorf("Expected validation error for unknown formula, got %v", err)
+	}
+	if _, err := sm.ComputeGPA(1, FormulaCustom); !errors.As(err, &validationErr) {
+		t.Errorf("Expected validation error without custom bands, got %v", err)
+	}
+	if _, err := sm.ComputeGPA(2, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	sm.AddStudent(&Graduate{Student: Student{Name: "hao", StudentID: 2}})
+	result, err := sm.ComputeGPA(2, "")
+	if err != nil || result.GPA != 0 || result.TotalCredits != 0 {
+		t.Errorf("Expected empty result for student without scores, got %+v %v", result, err)
+	}
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// GradingScale 记分制
+// 数值型记分制只限制范围和小数位数；等级制和通过制用 Grades 把等级映射为保存的数值
+type GradingScale struct {
+	Name      string             `json:"name"`
+	Min       float64            `json:"min"`
+	Max       float64            `json:"max"`
+	Precision int                `json:"precision"`
+	Grades    map[string]float64 `json:"grades,omitempty"`
+}
+
+// 内置记分制名称
+const (
+	ScaleHundred  = "hundred"   // 百分制
+	ScaleFive     = "five"      // 五分制
+	ScaleLetter   = "letter"    // 字母等级制，按绩点保存
+	ScalePassFail = "pass_fail" // 通过/不通过
+)
+
+// gradingScales 内置的记分制
+var gradingScales = map[string]GradingScale{
+	ScaleHundred: {Name: ScaleHundred, Min: 0, Max: 100, Precision: 1},
+	ScaleFive:    {Name: ScaleFive, Min: 0, Max: 5, Precision: 1},
+	ScaleLetter: {Name: ScaleLetter, Min: 0, Max: 4, Precision: 1, Grades: map[string]float64{
+		"A": 4.0, "A-": 3.7, "B+": 3.3, "B": 3.0, "B-": 2.7, "C+": 2.3,
+		"C": 2.0, "C-": 1.7, "D+": 1.3, "D": 1.0, "F": 0,
+	}},
+	ScalePassFail: {Name: ScalePassFail, Min: 0, Max: 1, Precision: 0, Grades: map[string]float64{
+		"P": 1, "F": 0,
+	}},
+}
+
+// GradingScales 按名称排序返回内置的记分制
+func GradingScales() []GradingScale {
+	scales := make([]GradingScale, 0, len(gradingScales))
+	for _, scale := range gradingScales {
+		scales = append(scales, scale)
+	}
+	sort.Slice(scales, func(i, j int) bool {
+		return scales[i].Name < scales[j].Name
+	})
+	return scales
+}
+
+// Round 按记分制的小数位数四舍五入
+func (gs GradingScale) Round(score float64) float64 {
+	factor := math.Pow10(gs.Precision)
+	return math.Round(score*factor) / factor
+}
+
+// GradeOf 返回数值对应的等级，数值型记分制或没有对应等级时返回空字符串
+func (gs GradingScale) GradeOf(score float64) string {
+	for grade, value := range gs.Grades {
+		if value == score {
+			return grade
+		}
+	}
+	return ""
+}
+
+// Percent 把成绩折算为百分制
+func (gs GradingScale) Percent(score float64) float64 {
+	if gs.Max > gs.Min {
+		return (score - gs.Min) / (gs.Max - gs.Min) * 100
+	}
+	return score
+}
+
+// Passed 判断成绩是否及格：通过制为 P，字母等级制高于 F，数值型记分制折算为百分制后不低于 60 分
+func (gs GradingScale) Passed(score float64) bool {
+	switch gs.Name {
+	case ScalePassFail:
+		return score == gs.Grades["P"]
+	case ScaleLetter:
+		return score > 0
+	default:
+		return round2(gs.Percent(score)) >= passingPercent
+	}
+}
+
+// CourseGrading 单门课程的记分规则和学分，未设置的项使用默认值
+type CourseGrading struct {
+	Scale     string   `json:"scale,omitempty"`
+	Min       *float64 `json:"min,omitempty"`
+	Max       *float64 `json:"max,omitempty"`
+	Precision *int     `json:"precision,omitempty"`
+	Credits   *float64 `json:"credits,omitempty"`
+}
+
+// GradingPolicy 成绩校验策略：默认记分制、各课程的记分规则和学分，以及绩点换算公式
+type GradingPolicy struct {
+	DefaultScale string                   `json:"default_scale"`
+	Courses      map[string]CourseGrading `json:"courses,omitempty"`
+	GPAFormula   string                   `json:"gpa_formula,omitempty"`
+	GPABands     []GPABand                `json:"gpa_bands,omitempty"`
+}
+
+// NewGradingPolicy 创建默认的成绩校验策略，所有课程使用百分制
+func NewGradingPolicy() *GradingPolicy {
+	return &GradingPolicy{
+		DefaultScale: ScaleHundred,
+		Courses:      make(map[string]CourseGrading),
+	}
+}
+
+// LoadGradingPolicy 从 JSON 文件加载成绩校验策略
+func LoadGradingPolicy(path string) (*GradingPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read grading policy %s: %w", path, err)
+	}
+	policy := NewGradingPolicy()
+	if err := json.Unmarshal(data, policy); err != nil {
+		return nil, fmt.Errorf("parse grading policy %s: %w", path, err)
+	}
+	if err := policy.Check(); err != nil {
+		return nil, fmt.Errorf("grading policy %s: %w", path, err)
+	}
+	return policy, nil
+}
+
+// Check 检查策略引用的记分制是否存在、范围是否合理
+func (gp *GradingPolicy) Check() error {
+	if _, ok := gradingScales[gp.DefaultScale]; !ok {
+		return fmt.Errorf("unknown default scale %q", gp.DefaultScale)
+	}
+	for course, rule := range gp.Courses {
+		scale, err := gp.ScaleFor(course)
+		if err != nil {
+			return err
+		}
+		if scale.Min > scale.Max {
+			return fmt.Errorf("course %s: min %v is greater than max %v", course, scale.Min, scale.Max)
+		}
+		if rule.Precision != nil && *rule.Precision < 0 {
+			return fmt.Errorf("course %s: precision must not be negative", course)
+		}
+		if rule.Credits != nil && (*rule.Credits < 0 || math.IsNaN(*rule.Credits)) {
+			return fmt.Errorf("course %s: credits must not be negative", course)
+		}
+	}
+	return gp.checkGPAFormula()
+}
+
+// ScaleFor 返回课程实际使用的记分制，课程规则中的范围和小数位数覆盖记分制的默认值
+func (gp *GradingPolicy) ScaleFor(courseName string) (GradingScale, error) {
+	rule := gp.Courses[courseName]
+	name := rule.Scale
+	if name == "" {
+		name = gp.DefaultScale
+	}
+	scale, ok := gradingScales[name]
+	if !ok {
+		return GradingScale{}, fmt.Errorf("course %s: unknown scale %q", courseName, name)
+	}
+	if rule.Min != nil {
+		scale.Min = *rule.Min
+	}
+	if rule.Max != nil {
+		scale.Max = *rule.Max
+	}
+	if rule.Precision != nil {
+		scale.Precision = *rule.Precision
+	}
+	return scale, nil
+}
+
+// ValidateScore 按课程的记分规则校验成绩
+func (gp *GradingPolicy) ValidateScore(courseName string, score float64) error {
+	if strings.TrimSpace(courseName) == "" {
+		return &ValidationError{Field: "course_name", Rule: "required", Message: "must not be empty"}
+	}
+	scale, err := gp.ScaleFor(courseName)
+	if err != nil {
+		return err
+	}
+
+	if math.IsNaN(score) || math.IsInf(score, 0) {
+		return &ValidationError{Field: "score", Rule: "finite", Message: "must be a finite number"}
+	}
+	if score < scale.Min {
+		return &ValidationError{Field: "score", Rule: "min", Message: fmt.Sprintf("must be at least %v on the %s scale", scale.Min, scale.Name)}
+	}
+	if score > scale.Max {
+		return &ValidationError{Field: "score", Rule: "max", Message: fmt.Sprintf("must be at most %v on the %s scale", scale.Max, scale.Name)}
+	}
+	if scale.Grades != nil {
+		if scale.GradeOf(score) == "" {
+			return &ValidationError{Field: "score", Rule: "grade", Message: fmt.Sprintf("must be one of the %s grades %s", scale.Name, strings.Join(scale.gradeNames(), ", "))}
+		}
+		return nil
+	}
+	// 允许浮点误差，例如 0.1 + 0.2
+	factor := math.Pow10(scale.Precision)
+	if math.Abs(score*factor-math.Round(score*factor)) > 1e-6 {
+		return &ValidationError{Field: "score", Rule: "precision", Message: fmt.Sprintf("must have at most %d decimal places", scale.Precision)}
+	}
+	return nil
+}
+
+// ParseGrade 把等级（如 A-、P）转换为课程记分制下保存的数值
+func (gp *GradingPolicy) ParseGrade(courseName, grade string) (float64, error) {
+	scale, err := gp.ScaleFor(courseName)
+	if err != nil {
+		return 0, err
+	}
+	value, ok := scale.Grades[strings.ToUpper(strings.TrimSpace(grade))]
+	if !ok {
+		if scale.Grades == nil {
+			return 0, &ValidationError{Field: "grade", Rule: "grade", Message: fmt.Sprintf("course %s uses the numeric %s scale, submit a score instead", courseName, scale.Name)}
+		}
+		return 0, &ValidationError{Field: "grade", Rule: "grade", Message: fmt.Sprintf("must be one of the %s grades %s", scale.Name, strings.Join(scale.gradeNames(), ", "))}
+	}
+	return value, nil
+}
+
+// gradeNames 按对应数值从高到低返回等级名称
+func (gs GradingScale) gradeNames() []string {
+	names := make([]string, 0, len(gs.Grades))
+	for grade := range gs.Grades {
+		names = append(names, grade)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return gs.Grades[names[i]] > gs.Grades[names[j]]
+	})
+	return names
+}
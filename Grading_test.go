@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTestGradingPolicy 创建包含各种记分制课程的策略
+func newTestGradingPolicy() *GradingPolicy {
+	maxScore := 150.0
+	precision := 0
+	policy := NewGradingPolicy()
+	policy.Courses["English"] = CourseGrading{Scale: ScaleLetter}
+	policy.Courses["PE"] = CourseGrading{Scale: ScalePassFail}
+	policy.Courses["Chinese"] = CourseGrading{Max: &maxScore, Precision: &precision}
+	policy.Courses["Physics"] = CourseGrading{Scale: ScaleFive}
+	return policy
+}
+
+// TestValidateScore 测试按课程记分规则校验成绩
+func TestValidateScore(t *testing.T) {
+	policy := newTestGradingPolicy()
+
+	tests := []struct {
+		course string
+		score  float64
+		rule   string
+	}{
+		{"Math", 95.5, ""},
+		{"Math", 0.1 + 0.2, ""},
+		{"Math", 100, ""},
+		{"Math", 1000, "max"},
+		{"Math", -1, "min"},
+		{"Math", math.NaN(), "finite"},
+		{"Math", math.Inf(1), "finite"},
+		{"Math", 95.55, "precision"},
+		{"Chinese", 140, ""},
+		{"Chinese", 140.5, "precision"},
+		{"Physics", 4.5, ""},
+		{"Physics", 6, "max"},
+		{"English", 3.7, ""},
+		{"English", 3.5, "grade"},
+		{"PE", 1, ""},
+		{"PE", 0.5, "grade"},
+		{"", 90, "required"},
+	}
+
+	for _, tt := range tests {
+		err := policy.ValidateScore(tt.course, tt.score)
+		if tt.rule == "" {
+			if err != nil {
+				t.Errorf("%s %v: expected no error, got %v", tt.course, tt.score, err)
+			}
+			continue
+		}
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) || validationErr.Rule != tt.rule {
+			t.Errorf("%s %v: expected %s violation, got %v", tt.course, tt.score, tt.rule, err)
+		}
+	}
+}
+
+// TestParseGrade 测试等级换算为成绩数值
+func TestParseGrade(t *testing.T) {
+	policy := newTestGradingPolicy()
+
+	value, err := policy.ParseGrade("English", "a-")
+	if err != nil || value != 3.7 {
+		t.Errorf("Expected 3.7 for grade A-, got %v %v", value, err)
+	}
+	value, err = policy.ParseGrade("PE", "P")
+	if err != nil || value != 1 {
+		t.Errorf("Expected 1 for grade P, got %v %v", value, err)
+	}
+	if _, err := policy.ParseGrade("English", "E"); err == nil {
+		t.Errorf("Expected error for unknown grade, got nil")
+	}
+	if _, err := policy.ParseGrade("Math", "A"); err == nil {
+		t.Errorf("Expected error for grade on a numeric scale, got nil")
+	}
+
+	scale, _ := policy.ScaleFor("English")
+	if grade := scale.GradeOf(3.3); grade != "B+" {
+		t.Errorf("Expected grade B+, got %q", grade)
+	}
+}
+
+// TestLoadGradingPolicy 测试从文件加载成绩校验策略
+func TestLoadGradingPolicy(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "grading.json")
+	os.WriteFile(path, []byte(`{"default_scale":"five","courses":{"PE":{"scale":"pass_fail"}}}`), 0o644)
+
+	policy, err := LoadGradingPolicy(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if scale, _ := policy.ScaleFor("Math"); scale.Name != ScaleFive {
+		t.Errorf("Expected default scale five, got %s", scale.Name)
+	}
+	if scale, _ := policy.ScaleFor("PE"); scale.Name != ScalePassFail {
+		t.Errorf("Expected PE to use pass_fail, got %s", scale.Name)
+	}
+
+	os.WriteFile(path, []byte(`{"default_scale":"hundred","courses":{"PE":{"scale":"ten"}}}`), 0o644)
+	if _, err := LoadGradingPolicy(path); err == nil {
+		t.Errorf("Expected error for unknown scale, got nil")
+	}
+}
+
+// TestAddScoreValidation 测试 AddScore 和 ModifyScore 拒绝不合法的成绩
+func TestAddScoreValidation(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28")
+	sm.SetGradingPolicy(newTestGradingPolicy())
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+
+	var validationErr *ValidationError
+	for _, score := range []float64{-1, 1000, math.NaN()} {
+		if err := sm.AddScore(1, "Math", "", score); !errors.As(err, &validationErr) {
+			t.Errorf("Expected validation error for score %v, got %v", score, err)
+		}
+	}
+	if err := sm.AddScore(1, "Math", "", 95); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.ModifyScore(1, "Math", "", 101, "复核"); !errors.As(err, &validationErr) || validationErr.Rule != "max" {
+		t.Errorf("Expected max violation, got %v", err)
+	}
+	if score, _ := sm.QueryScore(1, "Math", ""); score != 95 {
+		t.Errorf("Expected rejected modification not to be saved, got %v", score)
+	}
+
+	grade, err := sm.ParseGrade("English", "B")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.AddScore(1, "English", "", grade); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if sm.GradeOf("English", grade) != "B" {
+		t.Errorf("Expected grade B, got %q", sm.GradeOf("English", grade))
+	}
+}
+
+// TestRespondValidationError 测试校验错误以结构化的 422 响应返回
+func TestRespondValidationError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	respondError(c, newTestGradingPolicy().ValidateScore("Math", 1000))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", w.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected JSON body, got %s", w.Body.String())
+	}
+	if body["field"] != "score" || body["rule"] != "max" || body["message"] == "" || body["error"] == "" {
+		t.Errorf("Expected structured validation error, got %v", body)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// 研究生学位类型
+const (
+	DegreeMaster = "master"
+	DegreePhD    = "phd"
+)
+
+// 研究生论文答辩状态
+const (
+	DefenseNotStarted = "not_started"
+	DefenseScheduled  = "scheduled"
+	DefensePassed     = "passed"
+	DefenseFailed     = "failed"
+)
+
+// 研究生特有字段的最大长度（按字符计）
+const (
+	maxAdvisorLength      = 50
+	maxResearchAreaLength = 100
+	maxThesisTitleLength  = 200
+)
+
+// graduateProfileFields 研究生特有的字段名，顺序即 CSV 导入时第 6 列起的列顺序
+var graduateProfileFields = []string{"advisor", "research_area", "degree_type", "thesis_title", "defense_status"}
+
+// ProfileFields 返回研究生特有的字段名
+func (g *Graduate) ProfileFields() []string {
+	return graduateProfileFields
+}
+
+// UpdateProfile 更新研究生特有的字段
+func (g *Graduate) UpdateProfile(updates map[string]interface{}) error {
+	targets := map[string]*string{
+		"advisor":        &g.Advisor,
+		"research_area":  &g.ResearchArea,
+		"degree_type":    &g.DegreeType,
+		"thesis_title":   &g.ThesisTitle,
+		"defense_status": &g.DefenseStatus,
+	}
+	for _, field := range graduateProfileFields {
+		value, ok := updates[field]
+		if !ok {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return &ValidationError{Field: field, Message: "must be a string"}
+		}
+		*targets[field] = strings.TrimSpace(text)
+	}
+	return nil
+}
+
+// Validate 校验研究生特有的字段
+func (g *Graduate) Validate() error {
+	if err := validateTextLength("advisor", g.Advisor, maxAdvisorLength); err != nil {
+		return err
+	}
+	if err := validateTextLength("research_area", g.ResearchArea, maxResearchAreaLength); err != nil {
+		return err
+	}
+	if err := validateTextLength("thesis_title", g.ThesisTitle, maxThesisTitleLength); err != nil {
+		return err
+	}
+
+	switch g.DegreeType {
+	case "", DegreeMaster, DegreePhD:
+	default:
+		return &ValidationError{
+			Field:   "degree_type",
+			Message: fmt.Sprintf("must be %q or %q", DegreeMaster, DegreePhD),
+		}
+	}
+
+	switch g.DefenseStatus {
+	case "", DefenseNotStarted:
+	case DefenseScheduled, DefensePassed, DefenseFailed:
+		// 安排或完成答辩前必须确定论文题目
+		if g.ThesisTitle == "" {
+			return &ValidationError{
+				Field:   "thesis_title",
+				Message: fmt.Sprintf("is required when defense status is %q", g.DefenseStatus),
+			}
+		}
+	default:
+		return &ValidationError{
+			Field: "defense_status",
+			Message: fmt.Sprintf("must be one of %q, %q, %q or %q",
+				DefenseNotStarted, DefenseScheduled, DefensePassed, DefenseFailed),
+		}
+	}
+	return nil
+}
+
+// validateTextLength 校验文本字段不超过最大长度
+func validateTextLength(field, value string, maxLength int) error {
+	if utf8.RuneCountInString(value) > maxLength {
+		return &ValidationError{
+			Field:   field,
+			Message: fmt.Sprintf("must be at most %d characters", maxLength),
+		}
+	}
+	return nil
+}
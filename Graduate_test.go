@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestGraduateValidate 测试研究生特有字段的校验
+func TestGraduateValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		graduate Graduate
+		field    string
+	}{
+		{"empty profile", Graduate{}, ""},
+		{"complete profile", Graduate{
+			Advisor:       "张教授",
+			ResearchArea:  "机器学习",
+			DegreeType:    DegreePhD,
+			ThesisTitle:   "图神经网络研究",
+			DefenseStatus: DefensePassed,
+		}, ""},
+		{"unknown degree type", Graduate{DegreeType: "bachelor"}, "degree_type"},
+		{"unknown defense status", Graduate{DefenseStatus: "done"}, "defense_status"},
+		{"defense without thesis title", Graduate{DefenseStatus: DefenseScheduled}, "thesis_title"},
+		{"advisor too long", Graduate{Advisor: string(make([]rune, maxAdvisorLength+1))}, "advisor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.graduate.Validate()
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+				t.Errorf("Expected validation error on %s, got %v", tt.field, err)
+			}
+		})
+	}
+}
+
+// TestModifyGraduateProfile 测试通过 ModifyStudent 修改研究生特有字段
+func TestModifyGraduateProfile(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "students.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerClasses(t, sm, "27", "28", "29")
+	defer sm.Close()
+
+	err = sm.AddStudent(&Graduate{
+		Student:    Student{Name: "hao", StudentID: 2, Gender: "female", Class: "27"},
+		Advisor:    "李老师",
+		DegreeType: DegreeMaster,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+
+	// 添加字段不合法的研究生
+	err = sm.AddStudent(&Graduate{
+		Student:    Student{Name: "li", StudentID: 3},
+		DegreeType: "bachelor",
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Errorf("Expected validation error, got %v", err)
+	}
+	if _, err := sm.QueryStudent(3); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected invalid graduate not to be added, got %v", err)
+	}
+
+	// 修改研究生特有字段
+	err = sm.ModifyStudent(2, map[string]interface{}{
+		"class":          "29",
+		"research_area":  "计算机视觉",
+		"thesis_title":   "  目标检测方法研究 ",
+		"defense_status": DefenseScheduled,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	detail, err := sm.QueryStudentDetail(2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	graduate := detail.(*Graduate)
+	if graduate.Class != "29" || graduate.Advisor != "李老师" || graduate.ResearchArea != "计算机视觉" ||
+		graduate.ThesisTitle != "目标检测方法研究" || graduate.DefenseStatus != DefenseScheduled {
+		t.Errorf("Expected graduate profile to be updated, got %+v", graduate)
+	}
+
+	// 不合法的修改整体不生效
+	err = sm.ModifyStudent(2, map[string]interface{}{
+		"name":        "hao modified",
+		"degree_type": "doctor",
+	})
+	if !errors.As(err, &validationErr) || validationErr.Field != "degree_type" {
+		t.Errorf("Expected validation error on degree_type, got %v", err)
+	}
+	err = sm.ModifyStudent(2, map[string]interface{}{"advisor": 42})
+	if !errors.As(err, &validationErr) || validationErr.Field != "advisor" {
+		t.Errorf("Expected validation error on advisor, got %v", err)
+	}
+	student, _ := sm.QueryStudent(2)
+	if student.Name != "hao" {
+		t.Errorf("Expected rejected update not to be saved, got name %q", student.Name)
+	}
+
+	// 本科生没有研究生字段，修改时忽略
+	if err := sm.ModifyStudent(1, map[string]interface{}{"advisor": "李老师"}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,837 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 导入流水线的并发参数
+const (
+	defaultImportWorkers  = 4 // 默认并发解析的协程数
+	importWindowPerWorker = 8 // 每个解析协程对应的在途行数上限
+
+	importBatchRows  = 500                    // 每批保存的行数上限，整批只写入一次底层存储
+	importBatchDelay = 100 * time.Millisecond // 超过这段时间没有新的行时保存已攒下的行
+)
+
+// importColumns 没有表头时每行固定的前几列：type、id、name、gender、class，之后为类型特有的字段
+const importColumns = 5
+
+// importHeaderAliases 表头别名，键为去掉首尾空格并转为小写后的表头，值为学生字段名
+// 既不是别名也不是任何学生类型特有字段的列按课程成绩导入，表头即课程名
+var importHeaderAliases = map[string]string{
+	"type":       "type",
+	"类型":         "type",
+	"学生类型":       "type",
+	"id":         "id",
+	"student_id": "id",
+	"学号":         "id",
+	"name":       "name",
+	"姓名":         "name",
+	"gender":     "gender",
+	"sex":        "gender",
+	"性别":         "gender",
+	"class":      "class",
+	"班级":         "class",
+	"term":       "term",
+	"学期":         "term",
+	"makeup":     "makeup",
+	"补考":         "makeup",
+	"补考成绩":       "makeup",
+	"导师":         "advisor",
+	"研究方向":       "research_area",
+	"学位类型":       "degree_type",
+	"论文题目":       "thesis_title",
+	"答辩状态":       "defense_status",
+}
+
+// ImportRow 单行的导入结果，XLSX 的行同时给出工作表名
+type ImportRow struct {
+	Sheet     string `json:"sheet,omitempty"`
+	Line      int    `json:"line"`
+	StudentID int    `json:"id,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ImportOptions 导入方式
+type ImportOptions struct {
+	Upsert bool // 学号已存在时覆盖学生信息，否则按学号重复处理
+	DryRun bool // 只校验并报告导入结果，不保存任何数据
+	Atomic bool // 全部行导入成功才保存，任何一行失败时整批回滚
+
+	Format string // 文件格式，ImportFormatCSV 或 ImportFormatXLSX，空表示 CSV
+	Sheet  string // XLSX 中要导入的工作表，空表示全部工作表
+
+	Workers  int                 // 并发解析的协程数，0 表示默认值
+	Progress func(*ImportReport) // 每保存一批行后在保存协程中调用，用于报告进度
+
+	Actor Actor // 审计日志中记录的操作者，为空时使用调用方的 StudentManager
+}
+
+// ImportReport 导入报告，按行号列出新增、覆盖、被拒绝和学号重复的行
+type ImportReport struct {
+	Total      int         `json:"total"`
+	Accepted   []ImportRow `json:"accepted"`
+	Updated    []ImportRow `json:"updated"`
+	Rejected   []ImportRow `json:"rejected"`
+	Duplicates []ImportRow `json:"duplicates"`
+	DryRun     bool        `json:"dry_run,omitempty"`
+	RolledBack bool        `json:"rolled_back,omitempty"` // 全部成功模式下有失败的行，整批未保存
+}
+
+// newImportReport 创建空的导入报告
+func newImportReport() *ImportReport {
+	return &ImportReport{Accepted: []ImportRow{}, Updated: []ImportRow{}, Rejected: []ImportRow{}, Duplicates: []ImportRow{}}
+}
+
+// importScore 随学生一起导入的一门课程成绩
+type importScore struct {
+	course string
+	term   string
+	score  float64
+	makeup *float64 // 补考成绩，只能在只有一门课程成绩的行中填写
+	// components 组成部分成绩，不为空时 score 为按课程权重计算的总评成绩
+	components map[string]float64
+}
+
+// importRecord 解析后的一行，err 不为空时表示该行无法解析
+type importRecord struct {
+	seq     int
+	sheet   string
+	line    int
+	student StudentInterface
+	scores  []importScore
+	err     error
+}
+
+// importCourseColumn 成绩列
+type importCourseColumn struct {
+	column int
+	course string
+}
+
+// importLayout 各字段所在的列
+type importLayout struct {
+	fields  map[string]int // 学生字段名 → 列号
+	courses []importCourseColumn
+}
+
+// profileFieldNames 按学生类型名和字段顺序返回所有已注册学生类型的特有字段名，不含重复
+func profileFieldNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, kind := range StudentTypes() {
+		student, err := NewStudentOfType(kind)
+		if err != nil {
+			continue
+		}
+		if updater, ok := student.(ProfileUpdater); ok {
+			for _, field := range updater.ProfileFields() {
+				if !seen[field] {
+					seen[field] = true
+					names = append(names, field)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// headerCell 去掉表头单元格的首尾空格和 Excel 导出时带的 BOM
+func headerCell(cell string) string {
+	return strings.TrimSpace(strings.TrimPrefix(cell, "\ufeff"))
+}
+
+// isImportHeader 判断第一行是否为表头：有一列是学号的表头或别名
+func isImportHeader(record []string) bool {
+	for _, cell := range record {
+		if importHeaderAliases[strings.ToLower(headerCell(cell))] == "id" {
+			return true
+		}
+	}
+	return false
+}
+
+// newImportLayout 根据表头确定各字段所在的列，表头必须包含学号和姓名
+func newImportLayout(header []string) (*importLayout, error) {
+	layout := &importLayout{fields: make(map[string]int)}
+	profileFields := make(map[string]bool)
+	for _, field := range profileFieldNames() {
+		profileFields[field] = true
+	}
+	courses := make(map[string]bool)
+	for column, cell := range header {
+		cell = headerCell(cell)
+		if cell == "" {
+			continue
+		}
+		key := strings.ToLower(cell)
+		field, ok := importHeaderAliases[key]
+		if !ok && profileFields[key] {
+			field, ok = key, true
+		}
+		if !ok {
+			if courses[cell] {
+				return nil, &ValidationError{Field: "header", Rule: "unique", Message: fmt.Sprintf("duplicate course column %q", cell)}
+			}
+			courses[cell] = true
+			layout.courses = append(layout.courses, importCourseColumn{column: column, course: cell})
+			continue
+		}
+		if _, exists := layout.fields[field]; exists {
+			return nil, &ValidationError{Field: "header", Rule: "unique", Message: fmt.Sprintf("duplicate column for %s", field)}
+		}
+		layout.fields[field] = column
+	}
+	for _, field := range []string{"id", "name"} {
+		if _, ok := layout.fields[field]; !ok {
+			return nil, &ValidationError{Field: "header", Rule: "required", Message: fmt.Sprintf("missing column %s", field)}
+		}
+	}
+	return layout, nil
+}
+
+// positionalImportLayout 没有表头时按固定列序确定各字段所在的列，类型特有字段紧随其后
+func positionalImportLayout(record []string) *importLayout {
+	layout := &importLayout{fields: map[string]int{"type": 0, "id": 1, "name": 2, "gender": 3, "class": 4}}
+	if student, err := NewStudentOfType(strings.TrimSpace(record[0])); err == nil {
+		if updater, ok := student.(ProfileUpdater); ok {
+			for i, field := range updater.ProfileFields() {
+				layout.fields[field] = importColumns + i
+			}
+		}
+	}
+	return layout
+}
+
+// resolveColumn 把成绩列的列名换算为课程代码，列名为“课程.组成部分”（例如 MATH.homework）时同时返回组成部分
+// 列名本身是已注册的课程时按课程成绩处理，课程未注册时返回 ValidationError
+func (catalog courseCatalog) resolveColumn(column string) (code, component string, err error) {
+	if _, ok := catalog.find(column); !ok {
+		if i := strings.LastIndex(column, "."); i > 0 && i < len(column)-1 {
+			if course, ok := catalog.find(column[:i]); ok {
+				return course.Code, column[i+1:], nil
+			}
+		}
+	}
+	code, err = catalog.resolve(column)
+	return code, "", err
+}
+
+// parse 把 CSV 的一行解析为学生及其成绩，不合法时返回原因
+// 成绩列的列名按已注册的课程换算为课程代码
+func (layout *importLayout) parse(record []string, policy *GradingPolicy, catalog courseCatalog) (StudentInterface, []importScore, error) {
+	cell := func(column int) string {
+		if column < len(record) {
+			return strings.TrimSpace(record[column])
+		}
+		return ""
+	}
+	value := func(field string) string {
+		if column, ok := layout.fields[field]; ok {
+			return cell(column)
+		}
+		return ""
+	}
+
+	kind := value("type")
+	if kind == "" {
+		kind = defaultStudentType
+	}
+	student, err := NewStudentOfType(kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	studentID, err := strconv.Atoi(value("id"))
+	if err != nil {
+		return nil, nil, &ValidationError{Field: "id", Rule: "integer", Message: fmt.Sprintf("%q is not an integer", value("id"))}
+	}
+	if studentID <= 0 {
+		return nil, nil, &ValidationError{Field: "id", Rule: "min", Message: "must be a positive integer"}
+	}
+	if value("name") == "" {
+		return nil, nil, &ValidationError{Field: "name", Rule: "required", Message: "must not be empty"}
+	}
+
+	base := student.GetBase()
+	base.StudentID = studentID
+	base.Name = value("name")
+	base.Gender = value("gender")
+	base.Class = value("class")
+	// 类型特有的字段，例如研究生的导师和研究方向
+	if updater, ok := student.(ProfileUpdater); ok {
+		updates := make(map[string]interface{})
+		for _, field := range updater.ProfileFields() {
+			if v := value(field); v != "" {
+				updates[field] = v
+			}
+		}
+		if err := updater.UpdateProfile(updates); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 成绩记在学期列的学期下，没有学期列或为空时为未指定学期
+	term := value("term")
+	if err := ValidateTerm(term); err != nil {
+		return nil, nil, err
+	}
+
+	// 成绩列可以填写数值，等级制和通过制课程也可以填写等级；空白表示没有成绩
+	var scores []importScore
+	components := make(map[string]map[string]float64)
+	for _, course := range layout.courses {
+		text := cell(course.column)
+		if text == "" {
+			continue
+		}
+		code, component, err := catalog.resolveColumn(course.course)
+		if err != nil {
+			return nil, nil, scoreFieldError(err, course.course)
+		}
+		if component != "" {
+			score, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, nil, &ValidationError{Field: "components." + component, Rule: "number", Message: fmt.Sprintf("%q is not a number", text)}
+			}
+			if components[code] == nil {
+				components[code] = make(map[string]float64)
+			}
+			components[code][component] = score
+			continue
+		}
+		score, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			if score, err = policy.ParseGrade(code, text); err != nil {
+				return nil, nil, scoreFieldError(err, course.course)
+			}
+		}
+		if err := policy.ValidateScore(code, score); err != nil {
+			return nil, nil, scoreFieldError(err, course.course)
+		}
+		scores = append(scores, importScore{course: code, term: term, score: score})
+	}
+
+	// 有组成部分成绩的课程按导入开始时的权重计算总评成绩，同时填写的总评成绩必须与之一致
+	for code, scored := range components {
+		weights, scale, err := componentWeights(catalog, policy, code)
+		if err != nil {
+			return nil, nil, err
+		}
+		for component, score := range scored {
+			if err := validateComponent(policy, code, component, score, weights); err != nil {
+				return nil, nil, err
+			}
+		}
+		total := computeScore(weights, scored, scale)
+		found := false
+		for i := range scores {
+			if scores[i].course != code {
+				continue
+			}
+			if scores[i].score != total {
+				return nil, nil, &ValidationError{Field: "scores." + code, Rule: "components", Message: fmt.Sprintf("total %v does not match %v computed from the component scores", scores[i].score, total)}
+			}
+			scores[i].components = scored
+			found = true
+		}
+		if !found {
+			scores = append(scores, importScore{course: code, term: term, score: total, components: scored})
+		}
+	}
+
+	// 补考列对应该行唯一的一门课程成绩，例如导出文件中每行一个学期的一门课程
+	if text := value("makeup"); text != "" {
+		if len(scores) != 1 {
+			return nil, nil, &ValidationError{Field: "makeup", Rule: "single_course", Message: "a makeup score needs exactly one course score in the row"}
+		}
+		code := scores[0].course
+		makeup, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			if makeup, err = policy.ParseGrade(code, text); err != nil {
+				return nil, nil, makeupFieldError(err)
+			}
+		}
+		if err := policy.ValidateScore(code, makeup); err != nil {
+			return nil, nil, makeupFieldError(err)
+		}
+		scores[0].makeup = &makeup
+	}
+	return student, scores, nil
+}
+
+// registrySnapshot 返回课程和班级的副本，暂存导入期间使用，调用方需持有锁
+func (sm *StudentManager) registrySnapshot() (*MemoryStore, error) {
+	snapshot := NewMemoryStore()
+	courses, err := sm.courses.ListCourses()
+	if err != nil {
+		return nil, err
+	}
+	for _, course := range courses {
+		snapshot.SaveCourse(course)
+	}
+	classes, err := sm.classes.ListClasses()
+	if err != nil {
+		return nil, err
+	}
+	for _, class := range classes {
+		snapshot.SaveClass(class)
+	}
+	return snapshot, nil
+}
+
+// termScore 返回导入的成绩对应的学期成绩记录，用于与已有成绩比较
+func (score importScore) termScore() TermScore {
+	return TermScore{Course: score.course, Term: score.term, Score: score.score, Makeup: score.makeup, Components: score.components}
+}
+
+// importStudent 保存导入的一行学生及其成绩，created 表示是否为新增
+// continued 为 true 时学生已由同一批导入中之前的行保存，只保存成绩。
+// 一行作为一个整体保存：先对照已有记录检查成绩，与已有成绩完全相同的跳过，不同时返回 ErrConflict；
+// 再把学生信息和成绩写入暂存存储，全部成功才提交，失败的行不会只保存了学生信息。调用方需持有锁
+func (sm *StudentManager) importStudent(student StudentInterface, scores []importScore, upsert, continued bool) (bool, error) {
+	studentID := student.GetID()
+	existing, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return false, err
+	}
+	pending := scores
+	if exists && (upsert || continued) {
+		pending = nil
+		current := existing.GetBase()
+		for _, score := range scores {
+			if i := current.findTermScore(score.course, score.term); i >= 0 {
+				if !current.TermScores[i].Equal(score.termScore()) {
+					return false, scoreConflict(studentID, score.course, score.term)
+				}
+				continue
+			}
+			pending = append(pending, score)
+		}
+	}
+
+	staging := newStagingStore(sm.store, nil)
+	row := *sm
+	row.store = staging
+	created := false
+	if !continued {
+		if created, err = row.saveStudent(student, upsert); err != nil {
+			return false, err
+		}
+	}
+	// 成绩已在解析时校验，逐门按 AddScore 和 RecordMakeup 的方式保存
+	for _, score := range pending {
+		if err := row.addScore(studentID, score.course, score.term, score.score); err != nil {
+			return false, err
+		}
+		// 组成部分成绩与按权重计算的总评成绩一起保存，之后修改组成部分时重新计算总评成绩
+		if len(score.components) > 0 {
+			record, _, err := staging.Get(studentID)
+			if err != nil {
+				return false, err
+			}
+			student := record.GetBase()
+			student.TermScores[student.findTermScore(score.course, score.term)].Components = score.components
+			if err := staging.Save(record); err != nil {
+				return false, err
+			}
+		}
+		if score.makeup != nil {
+			if err := row.recordMakeup(studentID, score.course, score.term, *score.makeup, ""); err != nil {
+				return false, err
+			}
+		}
+	}
+	if err := staging.commit(); err != nil {
+		return false, err
+	}
+	return created, nil
+}
+
+// importBatch 在一次加锁中保存一批按读取顺序排列的行，并把各行的结果写入报告
+// 各行先提交到整批的暂存存储，最后一起写入底层存储，例如文件存储整批只重写一次数据文件；
+// 写入失败时整批没有保存，本批成功的行改为被拒绝
+func (sm *StudentManager) importBatch(records []importRecord, upsert bool, profiles map[int]string, report *ImportReport) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditImport, "")()
+
+	staging := newStagingStore(sm.store, nil)
+	staged := *sm
+	staged.store = staging
+	type result struct {
+		row  ImportRow
+		kind *[]ImportRow
+	}
+	results := make([]result, 0, len(records))
+	var saved []int // 本批记下学生信息的学号，写入失败时撤销
+	for _, record := range records {
+		report.Total++
+		row := ImportRow{Sheet: record.sheet, Line: record.line}
+		if record.err != nil {
+			row.Reason = record.err.Error()
+			results = append(results, result{row, &report.Rejected})
+			continue
+		}
+		row.StudentID = record.student.GetID()
+		profile := importProfile(record.student)
+		previous, continued := profiles[row.StudentID]
+		continued = continued && previous == profile && len(record.scores) > 0
+		created, err := staged.importStudent(record.student, record.scores, upsert, continued)
+		if err == nil && !continued {
+			profiles[row.StudentID] = profile
+			saved = append(saved, row.StudentID)
+		}
+		switch {
+		case err == nil && (created || continued):
+			results = append(results, result{row, &report.Accepted})
+		case err == nil:
+			results = append(results, result{row, &report.Updated})
+		case errors.Is(err, ErrConflict):
+			row.Reason = err.Error()
+			results = append(results, result{row, &report.Duplicates})
+		default:
+			row.Reason = err.Error()
+			results = append(results, result{row, &report.Rejected})
+		}
+	}
+
+	if err := staging.commit(); err != nil {
+		for _, id := range saved {
+			delete(profiles, id)
+		}
+		for i := range results {
+			if results[i].kind == &report.Accepted || results[i].kind == &report.Updated {
+				results[i].row.Reason = err.Error()
+				results[i].kind = &report.Rejected
+			}
+		}
+	}
+	for _, result := range results {
+		*result.kind = append(*result.kind, result.row)
+	}
+}
+
+// importProfile 返回一行学生信息的摘要，用于识别同一学生的后续行
+func importProfile(student StudentInterface) string {
+	data, err := json.Marshal(student)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// contextReader 在 ctx 取消后停止读取
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read 实现 io.Reader 接口
+func (cr contextReader) Read(p []byte) (int, error) {
+	if err := cr.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return cr.r.Read(p)
+}
+
+// rowError 表示单行无法读取，跳过该行后可以继续读取
+type rowError struct {
+	err error
+}
+
+// Error 实现 error 接口
+func (e rowError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e rowError) Unwrap() error {
+	return e.err
+}
+
+// importSource 逐行读取待导入的数据，例如 CSV 文件或 XLSX 的一个工作表
+type importSource interface {
+	// next 返回下一行及其行号，读完时返回 io.EOF，单行无法读取时返回 rowError
+	next() (record []string, line int, err error)
+}
+
+// csvSource 从 CSV 读取
+type csvSource struct {
+	reader *csv.Reader
+	line   int
+}
+
+// newCSVSource 创建 CSV 数据源，ctx 取消后停止读取
+func newCSVSource(ctx context.Context, r io.Reader) *csvSource {
+	reader := csv.NewReader(contextReader{ctx: ctx, r: r})
+	// 不同类型的学生列数不同，例如研究生带有额外的资料列
+	reader.FieldsPerRecord = -1
+	return &csvSource{reader: reader}
+}
+
+// next 实现 importSource 接口
+func (cs *csvSource) next() ([]string, int, error) {
+	record, err := cs.reader.Read()
+	if err == io.EOF {
+		return nil, 0, err
+	}
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, parseErr.StartLine, rowError{parseErr.Err}
+		}
+		return nil, cs.line + 1, err
+	}
+	cs.line, _ = cs.reader.FieldPos(0)
+	return record, cs.line, nil
+}
+
+// importSheet 一组共用表头的行：CSV 文件或 XLSX 的一个工作表
+type importSheet struct {
+	name   string // 工作表名，CSV 为空
+	class  string // 班级为空的行使用的班级
+	source importSource
+	layout *importLayout // 没有表头时为空，按固定列序读取
+
+	// 第一行不是表头时作为数据行
+	first     []string
+	firstLine int
+	firstErr  error
+}
+
+// readHeader 读取第一行，是表头时确定各字段所在的列
+func (sheet *importSheet) readHeader() error {
+	sheet.first, sheet.firstLine, sheet.firstErr = sheet.source.next()
+	if sheet.firstErr != nil || !isImportHeader(sheet.first) {
+		return nil
+	}
+	layout, err := newImportLayout(sheet.first)
+	if err != nil {
+		var validationErr *ValidationError
+		if sheet.name != "" && errors.As(err, &validationErr) {
+			validationErr.Message = fmt.Sprintf("sheet %s: %s", sheet.name, validationErr.Message)
+		}
+		return err
+	}
+	sheet.layout = layout
+	return nil
+}
+
+// importRow 读取到的一行，seq 为读取顺序，用于按原顺序保存
+type importRow struct {
+	seq    int
+	sheet  *importSheet
+	line   int
+	record []string
+	err    error
+}
+
+// ImportCSV 导入 CSV 数据，跳过不合法的行继续导入，并返回逐行的导入报告
+// 第一行包含学号列（id、student_id 或 学号）时作为表头，各列可以任意顺序，
+// 其余不认识的列按课程成绩导入；否则按 type、id、name、gender、class 的固定列序读取。
+// ctx 取消后停止导入并返回已处理部分的报告和 ctx 的错误；普通模式下已保存的行不会撤销
+func (sm *StudentManager) ImportCSV(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	return sm.importSheets(ctx, []*importSheet{{source: newCSVSource(ctx, r)}}, opts)
+}
+
+// importSheets 依次导入各组数据
+// 一个协程负责读取，固定数量的协程并发解析，解析结果由调用方的协程按读取顺序保存；
+// 在途的行数有上限，保存跟不上时读取协程会等待。
+// 试运行和全部成功模式下先写入暂存存储，只在读取底层存储时短暂加锁，其他请求不会被整批导入阻塞；
+// 试运行结束后丢弃暂存的修改，全部成功模式下没有失败的行才在持有锁时检查冲突并提交到底层存储
+func (sm *StudentManager) importSheets(ctx context.Context, sheets []*importSheet, opts ImportOptions) (*ImportReport, error) {
+	if opts.Actor != (Actor{}) {
+		sm = sm.As(opts.Actor)
+	}
+	// 先读取各组的第一行判断是否为表头，表头不合法时整批不导入
+	for _, sheet := range sheets {
+		if err := sheet.readHeader(); err != nil {
+			return nil, err
+		}
+		if err := ctx.Err(); err != nil {
+			return newImportReport(), err
+		}
+	}
+	// 记分规则、课程和班级取导入开始时的快照，解析和暂存期间不持有锁
+	sm.mu.Lock()
+	policy := sm.grading
+	catalog, err := sm.catalog()
+	var registry *MemoryStore
+	if err == nil && (opts.DryRun || opts.Atomic) {
+		registry, err = sm.registrySnapshot()
+	}
+	sm.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	parse := func(row importRow) importRecord {
+		layout := row.sheet.layout
+		if layout == nil {
+			if len(row.record) < importColumns {
+				return importRecord{err: fmt.Errorf("expected at least %d columns, got %d", importColumns, len(row.record))}
+			}
+			layout = positionalImportLayout(row.record)
+		}
+		student, scores, err := layout.parse(row.record, policy, catalog)
+		if err == nil && student.GetBase().Class == "" {
+			student.GetBase().Class = row.sheet.class
+		}
+		return importRecord{student: student, scores: scores, err: err}
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = defaultImportWorkers
+	}
+	rows := make(chan importRow, workers)
+	results := make(chan importRecord, workers)
+	// 在途行数的上限：读取一行前占用一个名额，保存后释放
+	window := make(chan struct{}, workers*importWindowPerWorker)
+
+	// 读取协程，只由它关闭 rows
+	go func() {
+		defer close(rows)
+		seq := 0
+		send := func(row importRow) bool {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return false
+			}
+			row.seq = seq
+			seq++
+			rows <- row
+			return true
+		}
+
+		for _, sheet := range sheets {
+			record, line, err := sheet.first, sheet.firstLine, sheet.firstErr
+			if sheet.layout != nil {
+				record, line, err = sheet.source.next()
+			}
+			for ; err != io.EOF; record, line, err = sheet.source.next() {
+				var skip rowError
+				if err != nil && !errors.As(err, &skip) {
+					// 读取底层数据失败时无法继续
+					send(importRow{sheet: sheet, line: line, err: err})
+					return
+				}
+				if !send(importRow{sheet: sheet, line: line, record: record, err: err}) {
+					return
+				}
+			}
+		}
+	}()
+
+	// 解析协程，全部退出后关闭 results
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for row := range rows {
+				parsed := importRecord{err: row.err}
+				if row.err == nil {
+					parsed = parse(row)
+				}
+				parsed.seq = row.seq
+				parsed.sheet = row.sheet.name
+				parsed.line = row.line
+				results <- parsed
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	report := newImportReport()
+	report.DryRun = opts.DryRun
+	target := sm
+	var staging *stagingStore
+	if opts.DryRun || opts.Atomic {
+		staging = newStagingStore(sm.store, sm.mu)
+		target = &StudentManager{store: staging, courses: registry, classes: registry, grading: policy, mu: new(sync.Mutex)}
+	}
+
+	// 本批已保存的学生，学号和学生信息都相同且带有成绩的后续行只导入成绩，例如导出文件中同一学生不同学期的成绩
+	profiles := make(map[int]string)
+	// 按读取顺序攒下的行，攒够一批或一段时间没有新的行时一起保存
+	var batch []importRecord
+	flush := func() {
+		if len(batch) == 0 || ctx.Err() != nil {
+			return
+		}
+		target.importBatch(batch, opts.Upsert, profiles, report)
+		batch = batch[:0]
+		if opts.Progress != nil {
+			opts.Progress(report)
+		}
+	}
+
+	// 解析结果可能乱序到达，按读取顺序保存；取消后只接收不保存，让各协程退出
+	pending := make(map[int]importRecord)
+	next := 0
+	idle := time.NewTimer(importBatchDelay)
+	defer idle.Stop()
+	for open := true; open; {
+		select {
+		case record, ok := <-results:
+			if !ok {
+				open = false
+				break
+			}
+			pending[record.seq] = record
+			for {
+				ready, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				next++
+				if ctx.Err() == nil {
+					batch = append(batch, ready)
+					if len(batch) >= importBatchRows {
+						flush()
+					}
+				}
+				<-window
+			}
+		case <-idle.C:
+			// 数据源停顿时先保存已攒下的行，进度不会停在已读取的行之前
+			flush()
+		}
+		idle.Reset(importBatchDelay)
+	}
+	flush()
+
+	if err := ctx.Err(); err != nil {
+		return report, err
+	}
+	if opts.DryRun || staging == nil {
+		return report, nil
+	}
+	if len(report.Rejected) > 0 || len(report.Duplicates) > 0 {
+		report.RolledBack = true
+		return report, nil
+	}
+	// 只在提交时持有锁，暂存期间被其他请求修改过的学生使整批回滚；提交的修改记为导入
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditImport, "")()
+	if err := staging.commit(); err != nil {
+		report.RolledBack = true
+		return report, err
+	}
+	return report, nil
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 导入任务状态
+const (
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+	ImportJobCancelled = "cancelled"
+)
+
+// maxFinishedImportJobs 保留的已结束导入任务数，超出时删除最早创建的
+const maxFinishedImportJobs = 100
+
+// ImportJob 导入任务的状态和进度
+type ImportJob struct {
+	ID         string        `json:"id"`
+	Owner      string        `json:"owner,omitempty"` // 创建任务的操作者
+	Status     string        `json:"status"`
+	Processed  int           `json:"processed"` // 已处理的行数
+	Accepted   int           `json:"accepted"`
+	Updated    int           `json:"updated"`
+	Rejected   int           `json:"rejected"`
+	Duplicates int           `json:"duplicates"`
+	BytesRead  int64         `json:"bytes_read"`
+	BytesTotal int64         `json:"bytes_total"`
+	Percent    float64       `json:"percent"`               // 按已读取的字节数估算的进度
+	ETASeconds *float64      `json:"eta_seconds,omitempty"` // 按当前速度估算的剩余秒数，无法估算时为空
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Report     *ImportReport `json:"report,omitempty"` // 任务结束后的完整导入报告
+}
+
+// importJob 运行中的导入任务
+type importJob struct {
+	mu        sync.Mutex
+	job       ImportJob
+	bytesRead atomic.Int64
+	cancel    context.CancelFunc
+}
+
+// snapshot 返回任务当前状态的副本，并计算进度和剩余时间
+func (j *importJob) snapshot() ImportJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job := j.job
+	job.BytesRead = j.bytesRead.Load()
+	if job.Status != ImportJobRunning {
+		if job.Status == ImportJobCompleted {
+			job.Percent = 100
+		}
+		return job
+	}
+	if job.BytesTotal > 0 {
+		job.Percent = round2(float64(job.BytesRead) / float64(job.BytesTotal) * 100)
+		if job.BytesRead > 0 && job.BytesRead <= job.BytesTotal {
+			elapsed := time.Since(job.StartedAt).Seconds()
+			eta := round2(elapsed * float64(job.BytesTotal-job.BytesRead) / float64(job.BytesRead))
+			job.ETASeconds = &eta
+		}
+	}
+	return job
+}
+
+// countingReader 统计已读取的字节数
+type countingReader struct {
+	r     io.Reader
+	count *atomic.Int64
+}
+
+// Read 实现 io.Reader 接口
+func (cr countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.count.Add(int64(n))
+	return n, err
+}
+
+// ImportJobs 管理后台运行的导入任务
+type ImportJobs struct {
+	sm    *StudentManager
+	mu    sync.Mutex
+	jobs  map[string]*importJob
+	order []string // 按创建顺序排列的任务 ID，用于清理已结束的任务
+}
+
+// NewImportJobs 创建导入任务管理器
+func NewImportJobs(sm *StudentManager) *ImportJobs {
+	return &ImportJobs{
+		sm:   sm,
+		jobs: make(map[string]*importJob),
+	}
+}
+
+// newImportJobID 生成随机的任务 ID
+func newImportJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// Start 在后台按 opts.Format 导入 src 中的 CSV 或 XLSX 数据并立即返回任务，size 为数据的字节数，用于估算进度
+// 任务结束后关闭 src
+func (ij *ImportJobs) Start(src io.ReadCloser, size int64, opts ImportOptions) (ImportJob, error) {
+	id, err := newImportJobID()
+	if err != nil {
+		src.Close()
+		return ImportJob{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &importJob{
+		job:    ImportJob{ID: id, Owner: opts.Actor.Name, Status: ImportJobRunning, BytesTotal: size, StartedAt: time.Now()},
+		cancel: cancel,
+	}
+
+	ij.mu.Lock()
+	ij.jobs[id] = job
+	ij.order = append(ij.order, id)
+	ij.prune()
+	ij.mu.Unlock()
+
+	opts.Progress = func(report *ImportReport) {
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		job.job.Processed = report.Total
+		job.job.Accepted = len(report.Accepted)
+		job.job.Updated = len(report.Updated)
+		job.job.Rejected = len(report.Rejected)
+		job.job.Duplicates = len(report.Duplicates)
+	}
+	go func() {
+		defer src.Close()
+		defer cancel()
+		report, err := ij.sm.Import(ctx, countingReader{r: src, count: &job.bytesRead}, opts)
+
+		job.mu.Lock()
+		defer job.mu.Unlock()
+		finished := time.Now()
+		job.job.FinishedAt = &finished
+		job.job.Report = report
+		switch {
+		case err == nil:
+			job.job.Status = ImportJobCompleted
+		case errors.Is(err, context.Canceled):
+			job.job.Status = ImportJobCancelled
+		default:
+			job.job.Status = ImportJobFailed
+			job.job.Error = err.Error()
+		}
+	}()
+	return job.snapshot(), nil
+}
+
+// prune 删除超出保留数量的已结束任务，调用方需持有锁
+func (ij *ImportJobs) prune() {
+	finished := 0
+	for _, id := range ij.order {
+		if ij.jobs[id].snapshot().Status != ImportJobRunning {
+			finished++
+		}
+	}
+	kept := ij.order[:0]
+	for _, id := range ij.order {
+		if finished > maxFinishedImportJobs && ij.jobs[id].snapshot().Status != ImportJobRunning {
+			delete(ij.jobs, id)
+			finished--
+			continue
+		}
+		kept = append(kept, id)
+	}
+	ij.order = kept
+}
+
+// find 查找导入任务，owner 不为空时其他操作者创建的任务按不存在处理，不暴露任务是否存在
+func (ij *ImportJobs) find(id, owner string) (*importJob, error) {
+	ij.mu.Lock()
+	job, exists := ij.jobs[id]
+	ij.mu.Unlock()
+	if !exists || (owner != "" && job.job.Owner != owner) {
+		return nil, fmt.Errorf("import job %s %w", id, ErrNotFound)
+	}
+	return job, nil
+}
+
+// Get 返回导入任务的状态和进度，owner 为空时可以查询任何人的任务，否则只能查询 owner 创建的任务
+func (ij *ImportJobs) Get(id, owner string) (ImportJob, error) {
+	job, err := ij.find(id, owner)
+	if err != nil {
+		return ImportJob{}, err
+	}
+	return job.snapshot(), nil
+}
+
+// Cancel 取消导入任务，已结束的任务保持原状态，owner 的含义与 Get 相同
+// 取消是异步的，返回的任务可能仍处于运行状态
+func (ij *ImportJobs) Cancel(id, owner string) (ImportJob, error) {
+	job, err := ij.find(id, owner)
+	if err != nil {
+		return ImportJob{}, err
+	}
+	job.cancel()
+	return job.snapshot(), nil
+}
+
+// spoolFile 上传内容的临时副本，关闭时删除
+type spoolFile struct {
+	*os.File
+}
+
+// Close 关闭并删除临时文件
+func (f spoolFile) Close() error {
+	err := f.File.Close()
+	if removeErr := os.Remove(f.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// spoolUpload 把上传的内容复制到临时文件，请求结束后后台任务仍可读取
+func spoolUpload(r io.Reader) (spoolFile, int64, error) {
+	file, err := os.CreateTemp("", "import-*")
+	if err != nil {
+		return spoolFile{}, 0, err
+	}
+	spooled := spoolFile{file}
+	size, err := io.Copy(file, r)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		spooled.Close()
+		return spoolFile{}, 0, err
+	}
+	return spooled, size, nil
+}
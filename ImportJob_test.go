@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitImportJob 等待导入任务满足条件，超时则测试失败
+func waitImportJob(t *testing.T, jobs *ImportJobs, id string, done func(ImportJob) bool) ImportJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err := jobs.Get(id, "")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if done(job) {
+			return job
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for import job, last state %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestImportJob 测试后台导入任务完成后报告进度和导入结果
+func TestImportJob(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	jobs := NewImportJobs(sm)
+
+	var data strings.Builder
+	data.WriteString("id,name,Math\n")
+	for id := 1; id <= 500; id++ {
+		fmt.Fprintf(&data, "%d,student%d,%d\n", id, id, 50+id%50)
+	}
+	data.WriteString("0,zero,90\n")
+
+	job, err := jobs.Start(io.NopCloser(strings.NewReader(data.String())), int64(data.Len()), ImportOptions{Workers: 8})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if job.ID == "" || job.Status != ImportJobRunning {
+		t.Errorf("Expected running job with an ID, got %+v", job)
+	}
+
+	job = waitImportJob(t, jobs, job.ID, func(job ImportJob) bool { return job.Status != ImportJobRunning })
+	if job.Status != ImportJobCompleted || job.Percent != 100 || job.FinishedAt == nil {
+		t.Errorf("Expected completed job, got %+v", job)
+	}
+	if job.Processed != 501 || job.Accepted != 500 || job.Rejected != 1 || job.BytesRead != int64(data.Len()) {
+		t.Errorf("Unexpected progress %+v", job)
+	}
+	// 并发解析后仍按行号顺序保存和报告
+	if job.Report == nil || len(job.Report.Accepted) != 500 {
+		t.Fatalf("Expected full report, got %+v", job.Report)
+	}
+	for i, row := range job.Report.Accepted {
+		if row.Line != i+2 || row.StudentID != i+1 {
+			t.Fatalf("Expected row %d at line %d, got %+v", i+1, i+2, row)
+		}
+	}
+	if score, err := sm.QueryScore(500, "Math", ""); err != nil || score != 50 {
+		t.Errorf("Expected imported score 50, got %v %v", score, err)
+	}
+}
+
+// TestImportJobCancel 测试取消运行中的导入任务
+func TestImportJobCancel(t *testing.T) {
+	sm := NewStudentManager()
+	jobs := NewImportJobs(sm)
+
+	pr, pw := io.Pipe()
+	go pw.Write([]byte("id,name\n1,wei\n"))
+	job, err := jobs.Start(pr, 0, ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	waitImportJob(t, jobs, job.ID, func(job ImportJob) bool { return job.Processed == 1 })
+
+	if _, err := jobs.Cancel(job.ID, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 取消后后续数据不再导入
+	go func() {
+		pw.Write([]byte("2,hao\n"))
+		pw.Close()
+	}()
+	job = waitImportJob(t, jobs, job.ID, func(job ImportJob) bool { return job.Status != ImportJobRunning })
+	if job.Status != ImportJobCancelled {
+		t.Errorf("Expected cancelled job, got %+v", job)
+	}
+	if _, err := sm.QueryStudent(2); err == nil {
+		t.Errorf("Expected rows after cancellation not to be imported")
+	}
+
+	if _, err := jobs.Get("missing", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if _, err := jobs.Cancel("missing", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+// TestImportJobOwner 测试其他操作者按不存在处理导入任务，不能查询和取消
+func TestImportJobOwner(t *testing.T) {
+	jobs := NewImportJobs(NewStudentManager())
+	job, err := jobs.Start(io.NopCloser(strings.NewReader("id,name\n1,wei")), 0, ImportOptions{Actor: Actor{Name: "apikey:lms"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if job.Owner != "apikey:lms" {
+		t.Errorf("Expected the job to record its owner, got %+v", job)
+	}
+	if _, err := jobs.Get(job.ID, "apikey:other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected job of another actor to be not found, got %v", err)
+	}
+	if _, err := jobs.Cancel(job.ID, "apikey:other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected job of another actor not to be cancelled, got %v", err)
+	}
+	waitImportJob(t, jobs, job.ID, func(job ImportJob) bool { return job.Status == ImportJobCompleted })
+	if job, err := jobs.Get(job.ID, "apikey:lms"); err != nil || job.Accepted != 1 {
+		t.Errorf("Expected the owner to see the job, got %+v %v", job, err)
+	}
+}
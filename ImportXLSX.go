@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// 导入文件格式
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatXLSX = "xlsx"
+)
+
+// zipSignature XLSX 文件（zip 压缩包）的文件头
+var zipSignature = []byte("PK\x03\x04")
+
+// DetectImportFormat 根据文件名和文件开头的字节判断导入文件格式，无法识别时按 CSV 处理
+func DetectImportFormat(filename string, head []byte) string {
+	if strings.EqualFold(filepath.Ext(filename), ".xlsx") || bytes.HasPrefix(head, zipSignature) {
+		return ImportFormatXLSX
+	}
+	return ImportFormatCSV
+}
+
+// Import 按 opts.Format 导入 CSV 或 XLSX 数据，格式为空时按 CSV 处理
+func (sm *StudentManager) Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	switch opts.Format {
+	case "", ImportFormatCSV:
+		return sm.ImportCSV(ctx, r, opts)
+	case ImportFormatXLSX:
+		return sm.ImportXLSX(ctx, r, opts)
+	default:
+		return nil, &ValidationError{Field: "format", Message: fmt.Sprintf("must be %s or %s", ImportFormatCSV, ImportFormatXLSX)}
+	}
+}
+
+// xlsxSource 从 XLSX 的一个工作表读取
+type xlsxSource struct {
+	ctx  context.Context
+	rows *excelize.Rows
+	line int
+}
+
+// next 实现 importSource 接口，跳过空行，行号与工作表中的行号一致
+func (xs *xlsxSource) next() ([]string, int, error) {
+	for {
+		if err := xs.ctx.Err(); err != nil {
+			return nil, xs.line + 1, err
+		}
+		if !xs.rows.Next() {
+			if err := xs.rows.Error(); err != nil {
+				return nil, xs.line + 1, err
+			}
+			return nil, 0, io.EOF
+		}
+		xs.line++
+		// 读取单元格的原始值，避免数字按显示格式被四舍五入
+		record, err := xs.rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, xs.line, rowError{err}
+		}
+		for _, cell := range record {
+			if strings.TrimSpace(cell) != "" {
+				return record, xs.line, nil
+			}
+		}
+	}
+}
+
+// ImportXLSX 导入 XLSX 工作簿，每个工作表的第一行可以是表头，各行的校验与 ImportCSV 相同
+// opts.Sheet 不为空时只导入该工作表，否则按顺序导入全部工作表。
+// 工作簿有多个工作表时每个工作表对应一个班级，班级为空的行使用工作表名作为班级
+func (sm *StudentManager) ImportXLSX(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportReport, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, &ValidationError{Field: "file", Message: "not a valid xlsx workbook"}
+	}
+	defer file.Close()
+
+	names := file.GetSheetList()
+	multiSheet := len(names) > 1
+	if opts.Sheet != "" {
+		found := false
+		for _, name := range names {
+			found = found || name == opts.Sheet
+		}
+		if !found {
+			return nil, &ValidationError{Field: "sheet", Message: fmt.Sprintf("workbook has no sheet %q, available sheets are %s", opts.Sheet, strings.Join(names, ", "))}
+		}
+		names = []string{opts.Sheet}
+	}
+
+	sheets := make([]*importSheet, 0, len(names))
+	for _, name := range names {
+		rows, err := file.Rows(name)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		sheet := &importSheet{name: name, source: &xlsxSource{ctx: ctx, rows: rows}}
+		if multiSheet {
+			sheet.class = name
+		}
+		sheets = append(sheets, sheet)
+	}
+	return sm.importSheets(ctx, sheets, opts)
+}
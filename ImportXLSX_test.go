@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// newTestWorkbook 创建每个班级一个工作表的成绩单
+func newTestWorkbook(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	file := excelize.NewFile()
+	defer file.Close()
+	file.SetSheetName("Sheet1", "28")
+	file.SetSheetRow("28", "A1", &[]interface{}{"学号", "姓名", "性别", "Math"})
+	file.SetSheetRow("28", "A2", &[]interface{}{1, "wei", "male", 95.5})
+	file.SetSheetRow("28", "A4", &[]interface{}{2, "hao", "male", 101})
+	file.NewSheet("29")
+	file.SetSheetRow("29", "A1", &[]interface{}{"姓名", "学号", "班级", "类型", "导师"})
+	file.SetSheetRow("29", "A2", &[]interface{}{"li", 3, "", "graduate", "zhang"})
+	file.SetSheetRow("29", "A3", &[]interface{}{"chen", 4, "30", "", ""})
+
+	var buf bytes.Buffer
+	if err := file.Write(&buf); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return &buf
+}
+
+// TestImportXLSX 测试导入多工作表的工作簿，每个工作表对应一个班级
+func TestImportXLSX(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	registerClasses(t, sm, "28", "29", "30")
+	report, err := sm.ImportXLSX(context.Background(), newTestWorkbook(t), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Total != 4 || len(report.Accepted) != 3 || len(report.Rejected) != 1 {
+		t.Fatalf("Expected 3 accepted and 1 rejected row, got %+v", report)
+	}
+	// 空行被跳过，行号与工作表一致
+	rejected := report.Rejected[0]
+	if rejected.Sheet != "28" || rejected.Line != 4 || !strings.Contains(rejected.Reason, "scores.Math") {
+		t.Errorf("Expected sheet 28 line 4 rejected for Math, got %+v", rejected)
+	}
+	if accepted := report.Accepted[1]; accepted.Sheet != "29" || accepted.Line != 2 || accepted.StudentID != 3 {
+		t.Errorf("Expected sheet 29 line 2 accepted, got %+v", accepted)
+	}
+
+	student, _ := sm.QueryStudent(1)
+	if student == nil || student.Class != "28" || student.Scores["Math"] != 95.5 {
+		t.Errorf("Expected student 1 in class 28 with Math 95.5, got %+v", student)
+	}
+	detail, _ := sm.QueryStudentDetail(3)
+	if graduate, ok := detail.(*Graduate); !ok || graduate.Class != "29" || graduate.Advisor != "zhang" {
+		t.Errorf("Expected graduate in class 29, got %+v", detail)
+	}
+	// 班级列有值时不使用工作表名
+	if student, _ := sm.QueryStudent(4); student == nil || student.Class != "30" {
+		t.Errorf("Expected student 4 to keep class 30, got %+v", student)
+	}
+}
+
+// TestImportXLSXSheet 测试只导入指定的工作表以及不合法的工作簿
+func TestImportXLSXSheet(t *testing.T) {
+	sm := NewStudentManager()
+	registerClasses(t, sm, "29", "30")
+	report, err := sm.Import(context.Background(), newTestWorkbook(t), ImportOptions{Format: ImportFormatXLSX, Sheet: "29"})
+	if err != nil || report.Total != 2 || len(report.Accepted) != 2 {
+		t.Fatalf("Expected 2 rows from sheet 29, got %+v %v", report, err)
+	}
+	if _, err := sm.QueryStudent(1); err == nil {
+		t.Errorf("Expected sheet 28 not to be imported")
+	}
+
+	var validationErr *ValidationError
+	if _, err := sm.ImportXLSX(context.Background(), newTestWorkbook(t), ImportOptions{Sheet: "31"}); !errors.As(err, &validationErr) || validationErr.Field != "sheet" {
+		t.Errorf("Expected validation error for unknown sheet, got %v", err)
+	}
+	if _, err := sm.ImportXLSX(context.Background(), strings.NewReader("id,name\n1,wei"), ImportOptions{}); !errors.As(err, &validationErr) || validationErr.Field != "file" {
+		t.Errorf("Expected validation error for invalid workbook, got %v", err)
+	}
+}
+
+// TestImportXLSXRoundTrip 测试导出的 XLSX 可以原样导入
+func TestImportXLSXRoundTrip(t *testing.T) {
+	sm := newExportTestManager(t)
+	var buf bytes.Buffer
+	if err := sm.ExportStudents(&buf, ExportXLSX, StudentQuery{}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	imported := NewStudentManager()
+	imported.SetGradingPolicy(sm.GradingPolicy())
+	registerCourses(t, imported, "Math", "English")
+	registerClasses(t, imported, "28", "29")
+	report, err := imported.ImportXLSX(context.Background(), &buf, ImportOptions{})
+	if err != nil || len(report.Accepted) != 4 {
+		t.Fatalf("Expected 4 imported rows, got %+v %v", report, err)
+	}
+	student, _ := imported.QueryStudent(1)
+	if student.Class != "28" || student.Scores["Math"] != 95.5 || student.Scores["English"] != 3.7 {
+		t.Errorf("Unexpected imported student %+v", student)
+	}
+}
+
+// TestDetectImportFormat 测试按文件名和文件头识别导入格式
+func TestDetectImportFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		head     string
+		format   string
+	}{
+		{"scores.csv", "id,", ImportFormatCSV},
+		{"scores.XLSX", "", ImportFormatXLSX},
+		{"upload", "PK\x03\x04", ImportFormatXLSX},
+		{"", "", ImportFormatCSV},
+	}
+	for _, tt := range tests {
+		if format := DetectImportFormat(tt.filename, []byte(tt.head)); format != tt.format {
+			t.Errorf("%q: expected %s, got %s", tt.filename, tt.format, format)
+		}
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestImportCSV 测试导入时跳过不合法的行，并按行号报告结果
+func TestImportCSV(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28", "29")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+
+	data := strings.Join([]string{
+		"undergraduate,1,wei,male,28",
+		"undergraduate,2,hao,male,28",
+		"graduate,3,li,female,29,zhang,ml,master",
+		"undergraduate,0,zero,male,28",
+		"undergraduate,abc,bad,male,28",
+		"undergraduate,4,short",
+		"exchange_student,5,kim,female,28",
+		"graduate,6,chen,male,29,,,bachelor",
+		"undergraduate,2,hao,male,28",
+		`undergraduate,7,"bad"quote,male,28`,
+		"undergraduate,8,,male,28",
+	}, "\n")
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader(data), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if report.Total != 11 {
+		t.Errorf("Expected 11 rows, got %d", report.Total)
+	}
+	assertLines := func(kind string, rows []ImportRow, lines ...int) {
+		t.Helper()
+		if len(rows) != len(lines) {
+			t.Errorf("Expected %s lines %v, got %+v", kind, lines, rows)
+			return
+		}
+		for i, row := range rows {
+			if row.Line != lines[i] {
+				t.Errorf("Expected %s line %d, got %+v", kind, lines[i], row)
+			}
+			if kind != "accepted" && row.Reason == "" {
+				t.Errorf("Expected a reason for %s line %d", kind, row.Line)
+			}
+		}
+	}
+	assertLines("accepted", report.Accepted, 2, 3)
+	assertLines("rejected", report.Rejected, 4, 5, 6, 7, 8, 10, 11)
+	assertLines("duplicate", report.Duplicates, 1, 9)
+
+	if _, err := sm.QueryStudent(0); err == nil {
+		t.Errorf("Expected student with id 0 not to be imported")
+	}
+	student, err := sm.QueryStudentDetail(3)
+	if err != nil {
+		t.Fatalf("Expected graduate to be imported, got %v", err)
+	}
+	if graduate, ok := student.(*Graduate); !ok || graduate.Advisor != "zhang" || graduate.DegreeType != DegreeMaster {
+		t.Errorf("Expected graduate profile to be imported, got %+v", student)
+	}
+}
+
+// TestImportCSVHeader 测试按表头（含中文别名）任意列序导入，其余列按课程成绩导入
+func TestImportCSVHeader(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28", "29")
+	policy := NewGradingPolicy()
+	policy.Courses["English"] = CourseGrading{Scale: ScaleLetter}
+	sm.SetGradingPolicy(policy)
+
+	data := strings.Join([]string{
+		"\ufeff姓名,学号,Math,班级,类型,English,导师,性别",
+		"wei,1,95,28,,A-,,male",
+		"hao,2,,28,graduate,B,zhang,male",
+		"li,3,101,29,,,,female",
+		"zhang,4,90,29,,E,,female",
+	}, "\n")
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader(data), ImportOptions{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if report.Total != 4 || len(report.Accepted) != 2 || len(report.Rejected) != 2 {
+		t.Fatalf("Expected 2 accepted and 2 rejected rows, got %+v", report)
+	}
+	if report.Rejected[0].Line != 4 || !strings.Contains(report.Rejected[0].Reason, "scores.Math") {
+		t.Errorf("Expected line 4 rejected for Math, got %+v", report.Rejected[0])
+	}
+	if report.Rejected[1].Line != 5 || !strings.Contains(report.Rejected[1].Reason, "scores.English") {
+		t.Errorf("Expected line 5 rejected for English, got %+v", report.Rejected[1])
+	}
+
+	student, err := sm.QueryStudent(1)
+	if err != nil {
+		t.Fatalf("Expected student 1 to be imported, got %v", err)
+	}
+	if student.Name != "wei" || student.Class != "28" || student.Gender != "male" || student.Type != defaultStudentType {
+		t.Errorf("Unexpected student %+v", student)
+	}
+	if student.Scores["Math"] != 95 || student.Scores["English"] != 3.7 {
+		t.Errorf("Expected Math 95 and English 3.7, got %v", student.Scores)
+	}
+	detail, err := sm.QueryStudentDetail(2)
+	if err != nil {
+		t.Fatalf("Expected student 2 to be imported, got %v", err)
+	}
+	if graduate, ok := detail.(*Graduate); !ok || graduate.Advisor != "zhang" || len(graduate.Scores) != 1 {
+		t.Errorf("Expected graduate with advisor and one score, got %+v", detail)
+	}
+	if _, err := sm.QueryStudent(3); err == nil {
+		t.Errorf("Expected row with invalid score not to be imported")
+	}
+}
+
+// TestImportCSVHeaderErrors 测试缺少必需列或列名重复的表头
+func TestImportCSVHeaderErrors(t *testing.T) {
+	sm := NewStudentManager()
+	for _, header := range []string{"学号,班级", "id,name,姓名", "id,name,Math,Math"} {
+		var validationErr *ValidationError
+		if _, err := sm.ImportCSV(context.Background(), strings.NewReader(header+"\n1,wei,28"), ImportOptions{}); !errors.As(err, &validationErr) {
+			t.Errorf("%q: expected validation error, got %v", header, err)
+		}
+	}
+}
+
+// TestImportCSVDryRun 测试试运行只报告导入结果，不修改已有数据
+func TestImportCSVDryRun(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	registerClasses(t, sm, "28", "29")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+
+	data := "id,name,class,Math\n1,wei,29,90\n2,hao,28,85\n3,,28,70\n2,hao,28,80"
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader(data), ImportOptions{Upsert: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 最后一行试图覆盖第 3 行录入的成绩
+	if !report.DryRun || len(report.Updated) != 1 || len(report.Accepted) != 1 || len(report.Rejected) != 1 || len(report.Duplicates) != 1 {
+		t.Errorf("Expected 1 created, 1 updated, 1 rejected and 1 duplicate row, got %+v", report)
+	}
+
+	student, _ := sm.QueryStudent(1)
+	if student.Class != "28" || len(student.Scores) != 0 {
+		t.Errorf("Expected dry run not to modify student 1, got %+v", student)
+	}
+	if _, err := sm.QueryStudent(2); err == nil {
+		t.Errorf("Expected dry run not to create student 2")
+	}
+}
+
+// TestImportCSVAtomic 测试全部成功模式：有失败的行时整批回滚，否则全部保存
+func TestImportCSVAtomic(t *testing.T) {
+	store, err := NewFileStore(filepath.Join(t.TempDir(), "students.json"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerCourses(t, sm, "Math")
+	registerClasses(t, sm, "28")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader("id,name,Math\n2,hao,85\n3,li,101"), ImportOptions{Atomic: true})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !report.RolledBack || len(report.Accepted) != 1 || len(report.Rejected) != 1 {
+		t.Errorf("Expected rolled back report, got %+v", report)
+	}
+	if _, err := sm.QueryStudent(2); err == nil {
+		t.Errorf("Expected rolled back import not to create student 2")
+	}
+
+	report, err = sm.ImportCSV(context.Background(), strings.NewReader("id,name,Math\n2,hao,85\n3,li,95"), ImportOptions{Atomic: true})
+	if err != nil || report.RolledBack {
+		t.Fatalf("Expected import to be committed, got %+v %v", report, err)
+	}
+	for _, id := range []int{2, 3} {
+		if _, err := sm.QueryScore(id, "Math", ""); err != nil {
+			t.Errorf("Expected student %d and score to be committed, got %v", id, err)
+		}
+	}
+
+	report, err = sm.ImportCSV(context.Background(), strings.NewReader("id,name\n4,zhang\n1,wei"), ImportOptions{Atomic: true})
+	if err != nil || !report.RolledBack || len(report.Duplicates) != 1 {
+		t.Errorf("Expected duplicate row to roll back the batch, got %+v %v", report, err)
+	}
+	if _, err := sm.QueryStudent(4); err == nil {
+		t.Errorf("Expected rolled back import not to create student 4")
+	}
+}
+
+// TestImportCSVAtomicConcurrent 测试全部成功模式暂存期间不阻塞其他请求，读过的学生被修改时整批不保存
+func TestImportCSVAtomicConcurrent(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	added := false
+	progress := func(report *ImportReport) {
+		// 暂存期间不持有锁，其他请求可以读写学生
+		if !added {
+			added = true
+			if err := sm.AddStudent(&Undergraduate{Student{Name: "other", StudentID: 2}}); err != nil {
+				t.Errorf("Expected no error while the import is staged, got %v", err)
+			}
+		}
+	}
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader("id,name,Math\n2,hao,85\n3,li,95"), ImportOptions{Atomic: true, Progress: progress})
+	if !errors.Is(err, ErrConflict) || !report.RolledBack {
+		t.Fatalf("Expected conflicting import to be rolled back, got %+v %v", report, err)
+	}
+	if student, err := sm.QueryStudent(2); err != nil || student.Name != "other" {
+		t.Errorf("Expected concurrent student to be kept, got %v", student)
+	}
+	if _, err := sm.QueryStudent(3); err == nil {
+		t.Errorf("Expected rolled back import not to create student 3")
+	}
+}
+
+// TestImportUpsertRowUnit 测试覆盖导入时一行作为整体保存：成绩冲突的行不修改学生信息，与已有成绩相同的成绩跳过
+func TestImportUpsertRowUnit(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	sm.AddScore(1, "Math", "", 80)
+
+	data := "id,name,class,Math\n1,wei changed,28,90"
+	for _, dryRun := range []bool{true, false} {
+		report, err := sm.ImportCSV(context.Background(), strings.NewReader(data), ImportOptions{Upsert: true, DryRun: dryRun})
+		if err != nil || len(report.Duplicates) != 1 || len(report.Updated) != 0 {
+			t.Errorf("dry run %v: expected the conflicting row to be a duplicate, got %+v %v", dryRun, report, err)
+		}
+	}
+	if student, _ := sm.QueryStudent(1); student.Name != "wei" || student.Scores["Math"] != 80 {
+		t.Errorf("Expected the rejected row not to modify student 1, got %+v", student)
+	}
+
+	// 与已有成绩相同的成绩跳过，只保存学生信息和新的成绩
+	data = "id,name,class,Math,English\n1,wei changed,28,80,\n1,wei changed,28,,75"
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader(data), ImportOptions{Upsert: true})
+	if err != nil || len(report.Updated) != 1 || len(report.Accepted) != 1 || len(report.Duplicates) != 0 {
+		t.Fatalf("Expected identical scores to be skipped, got %+v %v", report, err)
+	}
+	student, _ := sm.QueryStudent(1)
+	if student.Name != "wei changed" || student.Scores["Math"] != 80 || student.Scores["English"] != 75 {
+		t.Errorf("Expected the profile and the new score to be saved, got %+v", student)
+	}
+}
+
+// TestImportCSVFileStoreBatches 测试超过一批的导入全部写入文件存储，每个学生一条审计记录
+func TestImportCSVFileStoreBatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerCourses(t, sm, "Math")
+
+	var data strings.Builder
+	data.WriteString("id,name,Math\n")
+	count := importBatchRows*2 + 1
+	for id := 1; id <= count; id++ {
+		fmt.Fprintf(&data, "%d,student%d,%d\n", id, id, id%100)
+	}
+	report, err := sm.ImportCSV(context.Background(), strings.NewReader(data.String()), ImportOptions{})
+	if err != nil || len(report.Accepted) != count {
+		t.Fatalf("Expected %d accepted rows, got %d %v", count, len(report.Accepted), err)
+	}
+	if _, total, _ := sm.QueryAudit(AuditQuery{Action: AuditImport}); total != count {
+		t.Errorf("Expected one audit entry per student, got %d", total)
+	}
+	sm.Close()
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error on reopen, got %v", err)
+	}
+	sm = NewStudentManagerWithStore(store)
+	defer sm.Close()
+	if score, err := sm.QueryScore(count, "Math", ""); err != nil || score != float64(count%100) {
+		t.Errorf("Expected the last row to be saved, got %v %v", score, err)
+	}
+}
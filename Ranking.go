@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 排名依据
+const (
+	RankByTotal   = "total"   // 总分
+	RankByAverage = "average" // 平均分
+	RankByCourse  = "course"  // 单门课程成绩
+)
+
+// 并列名次的处理方式
+const (
+	RankDense       = "dense"       // 密集排名：1, 2, 2, 3
+	RankCompetition = "competition" // 竞赛排名：1, 2, 2, 4
+)
+
+// RankingOptions 班级排名的条件
+type RankingOptions struct {
+	By     string // 排名依据，默认按总分
+	Course string // 按单门课程排名时的课程名
+	Method string // 并列名次的处理方式，默认竞赛排名
+}
+
+// RankingEntry 单个学生的名次
+type RankingEntry struct {
+	Rank       int     `json:"rank"`
+	StudentID  int     `json:"id"`
+	Name       string  `json:"name"`
+	Value      float64 `json:"value"`
+	Percentile float64 `json:"percentile"` // 班级中成绩不高于该学生的人数占比（百分比）
+}
+
+// ClassRanking 班级排名结果
+type ClassRanking struct {
+	Class    string         `json:"class"`
+	By       string         `json:"by"`
+	Course   string         `json:"course,omitempty"`
+	Method   string         `json:"method"`
+	Rankings []RankingEntry `json:"rankings"`
+}
+
+// normalize 补全默认值并检查排名条件
+func (opts *RankingOptions) normalize() error {
+	if opts.By == "" {
+		opts.By = RankByTotal
+	}
+	if opts.Method == "" {
+		opts.Method = RankCompetition
+	}
+	switch opts.By {
+	case RankByTotal, RankByAverage:
+	case RankByCourse:
+		if opts.Course == "" {
+			return &ValidationError{Field: "course", Rule: "required", Message: "is required when ranking by course"}
+		}
+	default:
+		return &ValidationError{Field: "by", Message: fmt.Sprintf("must be one of %s, %s or %s", RankByTotal, RankByAverage, RankByCourse)}
+	}
+	switch opts.Method {
+	case RankDense, RankCompetition:
+	default:
+		return &ValidationError{Field: "method", Message: fmt.Sprintf("must be %s or %s", RankDense, RankCompetition)}
+	}
+	return nil
+}
+
+// rankingValue 返回学生用于排名的成绩，没有可排名的成绩时 ok 为 false
+func rankingValue(student *Student, opts RankingOptions) (value float64, ok bool) {
+	switch opts.By {
+	case RankByCourse:
+		value, ok = student.Scores[opts.Course]
+		return value, ok
+	default:
+		if len(student.Scores) == 0 {
+			return 0, false
+		}
+		var total float64
+		for _, score := range student.Scores {
+			total += score
+		}
+		if opts.By == RankByAverage {
+			return round2(total / float64(len(student.Scores))), true
+		}
+		return round2(total), true
+	}
+}
+
+// RankClass 对班级中有成绩的学生排名，成绩相同的学生名次相同并按学号排列
+func (sm *StudentManager) RankClass(class string, opts RankingOptions) (*ClassRanking, error) {
+	if err := opts.normalize(); err != nil {
+		return nil, err
+	}
+	if opts.By != RankByCourse {
+		opts.Course = ""
+	}
+
+	sm.mu.Lock()
+	course, err := sm.lookupCourse(opts.Course)
+	if err != nil {
+		sm.mu.Unlock()
+		return nil, err
+	}
+	opts.Course = course
+	class, err = sm.lookupClass(class)
+	if err != nil {
+		sm.mu.Unlock()
+		return nil, err
+	}
+	students, err := sm.store.List()
+	sm.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	entries := []RankingEntry{}
+	for _, record := range students {
+		student := record.GetBase()
+		if student.Class != class {
+			continue
+		}
+		found = true
+		if value, ok := rankingValue(student, opts); ok {
+			entries = append(entries, RankingEntry{StudentID: student.StudentID, Name: student.Name, Value: value})
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("class %s %w", class, ErrNotFound)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Value != entries[j].Value {
+			return entries[i].Value > entries[j].Value
+		}
+		return entries[i].StudentID < entries[j].StudentID
+	})
+
+	total := len(entries)
+	for i := range entries {
+		switch {
+		case i > 0 && entries[i].Value == entries[i-1].Value:
+			entries[i].Rank = entries[i-1].Rank
+		case opts.Method == RankDense && i > 0:
+			entries[i].Rank = entries[i-1].Rank + 1
+		default:
+			entries[i].Rank = i + 1
+		}
+	}
+
+	// 成绩不高于该学生的人数：从后往前累计，并列学生取同一值
+	for i := total - 1; i >= 0; i-- {
+		atOrBelow := total - i
+		for j := i - 1; j >= 0 && entries[j].Value == entries[i].Value; j-- {
+			atOrBelow++
+		}
+		entries[i].Percentile = round2(float64(atOrBelow) / float64(total) * 100)
+	}
+	return &ClassRanking{Class: class, By: opts.By, Course: opts.Course, Method: opts.Method, Rankings: entries}, nil
+}
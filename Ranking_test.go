@@ -0,0 +1,76 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// newRankingTestManager 创建一个班级成绩有并列的 StudentManager
+func newRankingTestManager(t *testing.T) *StudentManager {
+	t.Helper()
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28", "29")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Class: "28", Scores: map[string]float64{"Math": 90, "English": 80}}})
+	sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 2, Class: "28", Scores: map[string]float64{"Math": 95, "English": 75}}})
+	sm.AddStudent(&Graduate{Student: Student{Name: "li", StudentID: 3, Class: "28", Scores: map[string]float64{"Math": 60}}})
+	sm.AddStudent(&Undergraduate{Student{Name: "zhang", StudentID: 4, Class: "28", Scores: map[string]float64{"Math": 100, "English": 100}}})
+	sm.AddStudent(&Undergraduate{Student{Name: "chen", StudentID: 5, Class: "28"}})
+	sm.AddStudent(&Undergraduate{Student{Name: "wang", StudentID: 6, Class: "29", Scores: map[string]float64{"Math": 100}}})
+	return sm
+}
+
+// TestRankClass 测试按总分、平均分和单门课程排名，以及并列名次和百分位
+func TestRankClass(t *testing.T) {
+	sm := newRankingTestManager(t)
+
+	tests := []struct {
+		opts        RankingOptions
+		ids         []int
+		ranks       []int
+		percentiles []float64
+	}{
+		// 总分：4=200，1=170，2=170，3=60；没有成绩的学生 5 不参与排名
+		{RankingOptions{}, []int{4, 1, 2, 3}, []int{1, 2, 2, 4}, []float64{100, 75, 75, 25}},
+		{RankingOptions{Method: RankDense}, []int{4, 1, 2, 3}, []int{1, 2, 2, 3}, []float64{100, 75, 75, 25}},
+		// 平均分：4=100，1=85，2=85，3=60
+		{RankingOptions{By: RankByAverage}, []int{4, 1, 2, 3}, []int{1, 2, 2, 4}, []float64{100, 75, 75, 25}},
+		// English：4=100，1=80，2=75，学生 3 没有该课程成绩
+		{RankingOptions{By: RankByCourse, Course: "English"}, []int{4, 1, 2}, []int{1, 2, 3}, []float64{100, 66.67, 33.33}},
+	}
+	for _, tt := range tests {
+		ranking, err := sm.RankClass("28", tt.opts)
+		if err != nil {
+			t.Fatalf("%+v: expected no error, got %v", tt.opts, err)
+		}
+		if len(ranking.Rankings) != len(tt.ids) {
+			t.Fatalf("%+v: expected %d entries, got %+v", tt.opts, len(tt.ids), ranking.Rankings)
+		}
+		for i, entry := range ranking.Rankings {
+			if entry.StudentID != tt.ids[i] || entry.Rank != tt.ranks[i] || entry.Percentile != tt.percentiles[i] {
+				t.Errorf("%+v: expected id %d rank %d percentile %v at %d, got %+v",
+					tt.opts, tt.ids[i], tt.ranks[i], tt.percentiles[i], i, entry)
+			}
+		}
+	}
+}
+
+// TestRankClassErrors 测试不合法的排名条件和不存在的班级
+func TestRankClassErrors(t *testing.T) {
+	sm := newRankingTestManager(t)
+
+	var validationErr *ValidationError
+	for _, opts := range []RankingOptions{{By: "median"}, {By: RankByCourse}, {Method: "ordinal"}} {
+		if _, err := sm.RankClass("28", opts); !errors.As(err, &validationErr) {
+			t.Errorf("%+v: expected validation error, got %v", opts, err)
+		}
+	}
+	if _, err := sm.RankClass("30", RankingOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	ranking, err := sm.RankClass("28", RankingOptions{By: RankByCourse, Course: "Chemistry"})
+	if err != nil || len(ranking.Rankings) != 0 {
+		t.Errorf("Expected empty ranking for course without scores, got %+v %v", ranking, err)
+	}
+}
@@ -0,0 +1,844 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations 按版本顺序排列的数据库结构迁移
+// 只能在末尾追加新的迁移，已发布的迁移不能修改
+var sqliteMigrations = []string{
+	// 1: 学生表与成绩表
+	`CREATE TABLE students (
+		id     INTEGER PRIMARY KEY,
+		name   TEXT NOT NULL,
+		gender TEXT NOT NULL,
+		class  TEXT NOT NULL
+	);
+	CREATE TABLE scores (
+		student_id INTEGER NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+		course     TEXT NOT NULL,
+		score      REAL NOT NULL,
+		PRIMARY KEY (student_id, course)
+	);`,
+	// 2: 学生类型及各类型特有的资料（JSON）
+	`ALTER TABLE students ADD COLUMN type TEXT NOT NULL DEFAULT 'undergraduate';
+	ALTER TABLE students ADD COLUMN profile TEXT NOT NULL DEFAULT '{}';
+	CREATE INDEX students_type ON students (type);`,
+	// 3: 课程表
+	`CREATE TABLE courses (
+		code     TEXT PRIMARY KEY,
+		name     TEXT NOT NULL,
+		credits  REAL NOT NULL,
+		semester TEXT NOT NULL DEFAULT '',
+		teacher  TEXT NOT NULL DEFAULT '',
+		type     TEXT NOT NULL
+	);`,
+	// 4: 成绩按课程和学期保存，原有成绩记为未指定学期
+	`CREATE TABLE term_scores (
+		student_id INTEGER NOT NULL REFERENCES students(id) ON DELETE CASCADE,
+		course     TEXT NOT NULL,
+		term       TEXT NOT NULL DEFAULT '',
+		score      REAL NOT NULL,
+		makeup     REAL,
+		PRIMARY KEY (student_id, course, term)
+	);
+	INSERT INTO term_scores (student_id, course, term, score) SELECT student_id, course, '', score FROM scores;
+	DROP TABLE scores;
+	ALTER TABLE term_scores RENAME TO scores;`,
+	// 5: 课程各组成部分的权重与成绩的组成部分（JSON）
+	`ALTER TABLE courses ADD COLUMN weights TEXT NOT NULL DEFAULT '{}';
+	ALTER TABLE scores ADD COLUMN components TEXT;`,
+	// 6: 班级表与调班记录
+	`CREATE TABLE classes (
+		id               TEXT PRIMARY KEY,
+		name             TEXT NOT NULL,
+		grade_year       INTEGER NOT NULL DEFAULT 0,
+		major            TEXT NOT NULL DEFAULT '',
+		homeroom_teacher TEXT NOT NULL DEFAULT '',
+		capacity         INTEGER NOT NULL DEFAULT 0
+	);
+	CREATE TABLE class_moves (
+		seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+		student_id INTEGER NOT NULL,
+		from_class TEXT NOT NULL,
+		to_class   TEXT NOT NULL,
+		operation  TEXT NOT NULL,
+		reason     TEXT NOT NULL DEFAULT '',
+		moved_at   TEXT NOT NULL
+	);`,
+	// 7: 本地用户与已吊销的令牌
+	`CREATE TABLE users (
+		username            TEXT PRIMARY KEY,
+		password_hash       TEXT NOT NULL,
+		created_at          TEXT NOT NULL,
+		password_changed_at TEXT NOT NULL
+	);
+	CREATE TABLE revoked_tokens (
+		token_id   TEXT PRIMARY KEY,
+		expires_at INTEGER NOT NULL
+	);`,
+	// 8: 用户角色，引入角色之前的用户都是管理员
+	`ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'admin';
+	ALTER TABLE users ADD COLUMN student_id INTEGER NOT NULL DEFAULT 0;`,
+	// 9: API 密钥，scopes 以空格分隔，时间为 RFC3339 文本，为空的时间保存为 NULL
+	`CREATE TABLE api_keys (
+		id           TEXT PRIMARY KEY,
+		name         TEXT NOT NULL,
+		scopes       TEXT NOT NULL,
+		secret_hash  TEXT NOT NULL,
+		created_by   TEXT NOT NULL DEFAULT '',
+		created_at   TEXT NOT NULL,
+		expires_at   TEXT,
+		rotated_at   TEXT,
+		last_used_at TEXT
+	);`,
+	// 10: 审计日志，只能追加，触发器拒绝修改和删除；时间为 Unix 纳秒，便于按范围查询
+	`CREATE TABLE audit_log (
+		seq        INTEGER PRIMARY KEY AUTOINCREMENT,
+		logged_at  INTEGER NOT NULL,
+		actor      TEXT NOT NULL,
+		request_id TEXT NOT NULL DEFAULT '',
+		action     TEXT NOT NULL DEFAULT '',
+		student_id INTEGER NOT NULL,
+		before     TEXT,
+		after      TEXT
+	);
+	CREATE INDEX audit_log_student ON audit_log (student_id);
+	CREATE INDEX audit_log_actor ON audit_log (actor);
+	CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+	BEGIN
+		SELECT RAISE(ABORT, 'audit log is append-only');
+	END;
+	CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+	BEGIN
+		SELECT RAISE(ABORT, 'audit log is append-only');
+	END;`,
+	// 11: 审计日志记录修改成绩和调班的原因
+	`ALTER TABLE audit_log ADD COLUMN reason TEXT NOT NULL DEFAULT '';`,
+}
+
+// SQLiteStore 基于嵌入式 SQLite 的存储
+// 学生与成绩分表保存，每次写操作都在一个事务中完成
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore 打开或创建 SQLite 数据库，并执行尚未应用的迁移
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database %s: %w", path, err)
+	}
+	// SQLite 同一时间只允许一个写者，读写统一走一个连接
+	db.SetMaxOpenConns(1)
+
+	ss := &SQLiteStore{db: db}
+	if err := ss.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return ss, nil
+}
+
+// migrate 执行尚未应用的迁移，每个迁移及其版本记录在同一事务中提交
+func (ss *SQLiteStore) migrate() error {
+	if _, err := ss.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := ss.db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("read schema version: %w", err)
+	}
+	if current > len(sqliteMigrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, len(sqliteMigrations))
+	}
+
+	for version := current + 1; version <= len(sqliteMigrations); version++ {
+		err := ss.withTx(func(tx *sql.Tx) error {
+			if _, err := tx.Exec(sqliteMigrations[version-1]); err != nil {
+				return err
+			}
+			_, err := tx.Exec(`INSERT INTO schema_migrations (version) VALUES (?)`, version)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("apply migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// withTx 在事务中执行 fn，fn 返回错误时回滚
+func (ss *SQLiteStore) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := ss.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// studentProfile 提取学生类型特有的字段，公共字段和成绩已有独立的列和表
+func studentProfile(student StudentInterface) (string, error) {
+	var fields map[string]json.RawMessage
+	data, err := json.Marshal(student)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", err
+	}
+
+	var baseFields map[string]json.RawMessage
+	data, err = json.Marshal(student.GetBase())
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(data, &baseFields); err != nil {
+		return "", err
+	}
+	for key := range baseFields {
+		delete(fields, key)
+	}
+
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// newStudentFromRow 根据学生表中的一行还原具体类型的学生
+func newStudentFromRow(studentID int, name, gender, class, kind, profile string) (StudentInterface, error) {
+	student, err := decodeStudentOfType(kind, []byte(profile))
+	if err != nil {
+		return nil, fmt.Errorf("decode student %d: %w", studentID, err)
+	}
+	base := student.GetBase()
+	base.StudentID = studentID
+	base.Name = name
+	base.Gender = gender
+	base.Class = class
+	return student, nil
+}
+
+// Get 按学号读取学生及其成绩
+func (ss *SQLiteStore) Get(studentID int) (StudentInterface, bool, error) {
+	var name, gender, class, kind, profile string
+	err := ss.db.QueryRow(`SELECT name, gender, class, type, profile FROM students WHERE id = ?`, studentID).
+		Scan(&name, &gender, &class, &kind, &profile)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("query student %d: %w", studentID, err)
+	}
+	student, err := newStudentFromRow(studentID, name, gender, class, kind, profile)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rows, err := ss.db.Query(`SELECT course, term, score, makeup, components FROM scores WHERE student_id = ?`, studentID)
+	if err != nil {
+		return nil, false, fmt.Errorf("query scores of student %d: %w", studentID, err)
+	}
+	defer rows.Close()
+	base := student.GetBase()
+	for rows.Next() {
+		var termScore TermScore
+		var components sql.NullString
+		if err := rows.Scan(&termScore.Course, &termScore.Term, &termScore.Score, &termScore.Makeup, &components); err != nil {
+			return nil, false, fmt.Errorf("scan score: %w", err)
+		}
+		if termScore.Components, err = decodeComponents(components); err != nil {
+			return nil, false, err
+		}
+		base.TermScores = append(base.TermScores, termScore)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, fmt.Errorf("query scores of student %d: %w", studentID, err)
+	}
+	base.rebuildScores()
+	return student, true, nil
+}
+
+// Save 在一个事务中写入学生信息并整体替换其成绩
+func (ss *SQLiteStore) Save(student StudentInterface) error {
+	base := student.GetBase()
+	profile, err := studentProfile(student)
+	if err != nil {
+		return fmt.Errorf("encode student %d: %w", base.StudentID, err)
+	}
+
+	err = ss.withTx(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO students (id, name, gender, class, type, profile) VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(id) DO UPDATE SET name = excluded.name, gender = excluded.gender, class = excluded.class,
+				type = excluded.type, profile = excluded.profile`,
+			base.StudentID, base.Name, base.Gender, base.Class, student.GetType(), profile)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`DELETE FROM scores WHERE student_id = ?`, base.StudentID); err != nil {
+			return err
+		}
+		for _, termScore := range base.termScores() {
+			components, err := encodeComponents(termScore.Components)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(`INSERT INTO scores (student_id, course, term, score, makeup, components) VALUES (?, ?, ?, ?, ?, ?)`,
+				base.StudentID, termScore.Course, termScore.Term, termScore.Score, termScore.Makeup, components); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save student %d: %w", base.StudentID, err)
+	}
+	return nil
+}
+
+// Delete 在一个事务中删除学生及其全部成绩
+func (ss *SQLiteStore) Delete(studentID int) error {
+	err := ss.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM scores WHERE student_id = ?`, studentID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM students WHERE id = ?`, studentID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("delete student %d: %w", studentID, err)
+	}
+	return nil
+}
+
+// List 按学号升序返回全部学生及其成绩
+func (ss *SQLiteStore) List() ([]StudentInterface, error) {
+	// LIMIT 为负数时 SQLite 不限制行数
+	return ss.ListAfter(math.MinInt64, -1)
+}
+
+// ListAfter 按学号升序返回学号大于 afterID 的至多 limit 个学生及其成绩
+func (ss *SQLiteStore) ListAfter(afterID, limit int) ([]StudentInterface, error) {
+	rows, err := ss.db.Query(`SELECT id, name, gender, class, type, profile FROM students WHERE id > ? ORDER BY id LIMIT ?`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list students: %w", err)
+	}
+	defer rows.Close()
+
+	var students []StudentInterface
+	byID := make(map[int]*Student)
+	for rows.Next() {
+		var studentID int
+		var name, gender, class, kind, profile string
+		if err := rows.Scan(&studentID, &name, &gender, &class, &kind, &profile); err != nil {
+			return nil, fmt.Errorf("scan student: %w", err)
+		}
+		student, err := newStudentFromRow(studentID, name, gender, class, kind, profile)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, student)
+		byID[studentID] = student.GetBase()
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list students: %w", err)
+	}
+	if len(students) == 0 {
+		return students, nil
+	}
+
+	// 只读取这一页学生的成绩
+	lastID := students[len(students)-1].GetID()
+	scoreRows, err := ss.db.Query(`SELECT student_id, course, term, score, makeup, components FROM scores WHERE student_id > ? AND student_id <= ?`, afterID, lastID)
+	if err != nil {
+		return nil, fmt.Errorf("list scores: %w", err)
+	}
+	defer scoreRows.Close()
+	for scoreRows.Next() {
+		var studentID int
+		var termScore TermScore
+		var components sql.NullString
+		if err := scoreRows.Scan(&studentID, &termScore.Course, &termScore.Term, &termScore.Score, &termScore.Makeup, &components); err != nil {
+			return nil, fmt.Errorf("scan score: %w", err)
+		}
+		if termScore.Components, err = decodeComponents(components); err != nil {
+			return nil, err
+		}
+		student, ok := byID[studentID]
+		if !ok {
+			continue
+		}
+		student.TermScores = append(student.TermScores, termScore)
+	}
+	if err := scoreRows.Err(); err != nil {
+		return nil, fmt.Errorf("list scores: %w", err)
+	}
+	for _, student := range byID {
+		student.rebuildScores()
+	}
+	return students, nil
+}
+
+// Close 关闭数据库连接
+func (ss *SQLiteStore) Close() error {
+	return ss.db.Close()
+}
+
+// SaveCourse 保存课程，已存在则覆盖
+func (ss *SQLiteStore) SaveCourse(course Course) error {
+	weights := []byte("{}")
+	if len(course.Weights) > 0 {
+		var err error
+		if weights, err = json.Marshal(course.Weights); err != nil {
+			return fmt.Errorf("encode weights of course %s: %w", course.Code, err)
+		}
+	}
+	_, err := ss.db.Exec(`INSERT INTO courses (code, name, credits, semester, teacher, type, weights) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(code) DO UPDATE SET name = excluded.name, credits = excluded.credits, semester = excluded.semester,
+			teacher = excluded.teacher, type = excluded.type, weights = excluded.weights`,
+		course.Code, course.Name, course.Credits, course.Semester, course.Teacher, course.Type, string(weights))
+	if err != nil {
+		return fmt.Errorf("save course %s: %w", course.Code, err)
+	}
+	return nil
+}
+
+// DeleteCourse 删除课程
+func (ss *SQLiteStore) DeleteCourse(code string) error {
+	if _, err := ss.db.Exec(`DELETE FROM courses WHERE code = ?`, code); err != nil {
+		return fmt.Errorf("delete course %s: %w", code, err)
+	}
+	return nil
+}
+
+// ListCourses 按课程代码升序返回全部课程
+func (ss *SQLiteStore) ListCourses() ([]Course, error) {
+	rows, err := ss.db.Query(`SELECT code, name, credits, semester, teacher, type, weights FROM courses ORDER BY code`)
+	if err != nil {
+		return nil, fmt.Errorf("list courses: %w", err)
+	}
+	defer rows.Close()
+	courses := []Course{}
+	for rows.Next() {
+		var course Course
+		var weights string
+		if err := rows.Scan(&course.Code, &course.Name, &course.Credits, &course.Semester, &course.Teacher, &course.Type, &weights); err != nil {
+			return nil, fmt.Errorf("scan course: %w", err)
+		}
+		if err := json.Unmarshal([]byte(weights), &course.Weights); err != nil {
+			return nil, fmt.Errorf("decode weights of course %s: %w", course.Code, err)
+		}
+		if len(course.Weights) == 0 {
+			course.Weights = nil
+		}
+		courses = append(courses, course)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list courses: %w", err)
+	}
+	return courses, nil
+}
+
+// SaveClass 保存班级，已存在则覆盖
+func (ss *SQLiteStore) SaveClass(class Class) error {
+	_, err := ss.db.Exec(`INSERT INTO classes (id, name, grade_year, major, homeroom_teacher, capacity) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, grade_year = excluded.grade_year, major = excluded.major,
+			homeroom_teacher = excluded.homeroom_teacher, capacity = excluded.capacity`,
+		class.ID, class.Name, class.GradeYear, class.Major, class.HomeroomTeacher, class.Capacity)
+	if err != nil {
+		return fmt.Errorf("save class %s: %w", class.ID, err)
+	}
+	return nil
+}
+
+// DeleteClass 删除班级
+func (ss *SQLiteStore) DeleteClass(id string) error {
+	if _, err := ss.db.Exec(`DELETE FROM classes WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete class %s: %w", id, err)
+	}
+	return nil
+}
+
+// ListClasses 按班级编号升序返回全部班级
+func (ss *SQLiteStore) ListClasses() ([]Class, error) {
+	rows, err := ss.db.Query(`SELECT id, name, grade_year, major, homeroom_teacher, capacity FROM classes ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("list classes: %w", err)
+	}
+	defer rows.Close()
+	classes := []Class{}
+	for rows.Next() {
+		var class Class
+		if err := rows.Scan(&class.ID, &class.Name, &class.GradeYear, &class.Major, &class.HomeroomTeacher, &class.Capacity); err != nil {
+			return nil, fmt.Errorf("scan class: %w", err)
+		}
+		classes = append(classes, class)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list classes: %w", err)
+	}
+	return classes, nil
+}
+
+// AppendClassMoves 在一个事务中追加调班记录
+func (ss *SQLiteStore) AppendClassMoves(moves []ClassMove) error {
+	err := ss.withTx(func(tx *sql.Tx) error {
+		for _, move := range moves {
+			if _, err := tx.Exec(`INSERT INTO class_moves (student_id, from_class, to_class, operation, reason, moved_at) VALUES (?, ?, ?, ?, ?, ?)`,
+				move.StudentID, move.From, move.To, move.Operation, move.Reason, move.Time.UTC().Format(time.RFC3339Nano)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("append class moves: %w", err)
+	}
+	return nil
+}
+
+// ListClassMoves 按记录的先后顺序返回全部调班记录
+func (ss *SQLiteStore) ListClassMoves() ([]ClassMove, error) {
+	rows, err := ss.db.Query(`SELECT student_id, from_class, to_class, operation, reason, moved_at FROM class_moves ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("list class moves: %w", err)
+	}
+	defer rows.Close()
+	moves := []ClassMove{}
+	for rows.Next() {
+		var move ClassMove
+		var movedAt string
+		if err := rows.Scan(&move.StudentID, &move.From, &move.To, &move.Operation, &move.Reason, &movedAt); err != nil {
+			return nil, fmt.Errorf("scan class move: %w", err)
+		}
+		if move.Time, err = time.Parse(time.RFC3339Nano, movedAt); err != nil {
+			return nil, fmt.Errorf("parse class move time %q: %w", movedAt, err)
+		}
+		moves = append(moves, move)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list class moves: %w", err)
+	}
+	return moves, nil
+}
+
+// GetUser 按用户名读取用户
+func (ss *SQLiteStore) GetUser(username string) (User, bool, error) {
+	var user User
+	var createdAt, changedAt string
+	err := ss.db.QueryRow(`SELECT username, password_hash, role, student_id, created_at, password_changed_at FROM users WHERE username = ?`, username).
+		Scan(&user.Username, &user.PasswordHash, &user.Role, &user.StudentID, &createdAt, &changedAt)
+	if err == sql.ErrNoRows {
+		return User{}, false, nil
+	}
+	if err != nil {
+		return User{}, false, fmt.Errorf("query user %s: %w", username, err)
+	}
+	if err := parseUserTimes(&user, createdAt, changedAt); err != nil {
+		return User{}, false, err
+	}
+	return user, true, nil
+}
+
+// SaveUser 保存用户，已存在则覆盖
+func (ss *SQLiteStore) SaveUser(user User) error {
+	_, err := ss.db.Exec(`INSERT INTO users (username, password_hash, role, student_id, created_at, password_changed_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(username) DO UPDATE SET password_hash = excluded.password_hash, role = excluded.role,
+			student_id = excluded.student_id, password_changed_at = excluded.password_changed_at`,
+		user.Username, user.PasswordHash, user.Role, user.StudentID, user.CreatedAt.UTC().Format(time.RFC3339Nano), user.PasswordChangedAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return fmt.Errorf("save user %s: %w", user.Username, err)
+	}
+	return nil
+}
+
+// DeleteUser 删除用户
+func (ss *SQLiteStore) DeleteUser(username string) error {
+	if _, err := ss.db.Exec(`DELETE FROM users WHERE username = ?`, username); err != nil {
+		return fmt.Errorf("delete user %s: %w", username, err)
+	}
+	return nil
+}
+
+// ListUsers 按用户名升序返回全部用户
+func (ss *SQLiteStore) ListUsers() ([]User, error) {
+	rows, err := ss.db.Query(`SELECT username, password_hash, role, student_id, created_at, password_changed_at FROM users ORDER BY username`)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+	users := []User{}
+	for rows.Next() {
+		var user User
+		var createdAt, changedAt string
+		if err := rows.Scan(&user.Username, &user.PasswordHash, &user.Role, &user.StudentID, &createdAt, &changedAt); err != nil {
+			return nil, fmt.Errorf("scan user: %w", err)
+		}
+		if err := parseUserTimes(&user, createdAt, changedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	return users, nil
+}
+
+// parseUserTimes 解析用户的创建时间和修改密码的时间
+func parseUserTimes(user *User, createdAt, changedAt string) error {
+	var err error
+	if user.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return fmt.Errorf("parse created_at of user %s: %w", user.Username, err)
+	}
+	if user.PasswordChangedAt, err = time.Parse(time.RFC3339Nano, changedAt); err != nil {
+		return fmt.Errorf("parse password_changed_at of user %s: %w", user.Username, err)
+	}
+	return nil
+}
+
+// RevokeToken 在一个事务中记录已吊销的令牌并清理已过期的记录
+func (ss *SQLiteStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	err := ss.withTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM revoked_tokens WHERE expires_at < ?`, time.Now().Unix()); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT OR REPLACE INTO revoked_tokens (token_id, expires_at) VALUES (?, ?)`, tokenID, expiresAt.Unix())
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("revoke token: %w", err)
+	}
+	return nil
+}
+
+// TokenRevoked 判断令牌是否已吊销
+func (ss *SQLiteStore) TokenRevoked(tokenID string) (bool, error) {
+	var revoked bool
+	if err := ss.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE token_id = ?)`, tokenID).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("query revoked token: %w", err)
+	}
+	return revoked, nil
+}
+
+// apiKeyColumns 读取 API 密钥时查询的列，与 scanAPIKey 对应
+const apiKeyColumns = `id, name, scopes, secret_hash, created_by, created_at, expires_at, rotated_at, last_used_at`
+
+// GetAPIKey 按编号读取 API 密钥
+func (ss *SQLiteStore) GetAPIKey(id string) (APIKey, bool, error) {
+	key, err := scanAPIKey(ss.db.QueryRow(`SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return APIKey{}, false, nil
+	}
+	if err != nil {
+		return APIKey{}, false, fmt.Errorf("query api key %s: %w", id, err)
+	}
+	return key, true, nil
+}
+
+// SaveAPIKey 保存 API 密钥，已存在则覆盖
+func (ss *SQLiteStore) SaveAPIKey(key APIKey) error {
+	_, err := ss.db.Exec(`INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, scopes = excluded.scopes, secret_hash = excluded.secret_hash,
+			expires_at = excluded.expires_at, rotated_at = excluded.rotated_at, last_used_at = excluded.last_used_at`,
+		key.ID, key.Name, strings.Join(key.Scopes, " "), key.SecretHash, key.CreatedBy, key.CreatedAt.UTC().Format(time.RFC3339Nano),
+		encodeTime(key.ExpiresAt), encodeTime(key.RotatedAt), encodeTime(key.LastUsedAt))
+	if err != nil {
+		return fmt.Errorf("save api key %s: %w", key.ID, err)
+	}
+	return nil
+}
+
+// DeleteAPIKey 删除 API 密钥
+func (ss *SQLiteStore) DeleteAPIKey(id string) error {
+	if _, err := ss.db.Exec(`DELETE FROM api_keys WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("delete api key %s: %w", id, err)
+	}
+	return nil
+}
+
+// ListAPIKeys 按创建时间升序返回全部 API 密钥
+func (ss *SQLiteStore) ListAPIKeys() ([]APIKey, error) {
+	rows, err := ss.db.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	defer rows.Close()
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan api key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// scanAPIKey 读取一行 API 密钥
+func scanAPIKey(row interface{ Scan(...any) error }) (APIKey, error) {
+	var key APIKey
+	var scopes, createdAt string
+	var expiresAt, rotatedAt, lastUsedAt sql.NullString
+	if err := row.Scan(&key.ID, &key.Name, &scopes, &key.SecretHash, &key.CreatedBy, &createdAt, &expiresAt, &rotatedAt, &lastUsedAt); err != nil {
+		return APIKey{}, err
+	}
+	key.Scopes = strings.Fields(scopes)
+	var err error
+	if key.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt); err != nil {
+		return APIKey{}, fmt.Errorf("parse created_at of api key %s: %w", key.ID, err)
+	}
+	for _, field := range []struct {
+		value sql.NullString
+		dest  **time.Time
+	}{{expiresAt, &key.ExpiresAt}, {rotatedAt, &key.RotatedAt}, {lastUsedAt, &key.LastUsedAt}} {
+		if *field.dest, err = decodeTime(field.value); err != nil {
+			return APIKey{}, fmt.Errorf("parse time of api key %s: %w", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+// AppendAudit 追加审计记录，序号由 SQLite 分配
+func (ss *SQLiteStore) AppendAudit(entry AuditEntry) error {
+	_, err := ss.db.Exec(`INSERT INTO audit_log (logged_at, actor, request_id, action, reason, student_id, before, after) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Time.UnixNano(), entry.Actor, entry.RequestID, entry.Action, entry.Reason, entry.StudentID, encodeRaw(entry.Before), encodeRaw(entry.After))
+	if err != nil {
+		return fmt.Errorf("append audit entry: %w", err)
+	}
+	return nil
+}
+
+// QueryAudit 按序号降序返回满足条件的审计记录及其总数，Limit 为 0 时返回全部
+func (ss *SQLiteStore) QueryAudit(query AuditQuery) ([]AuditEntry, int, error) {
+	var where []string
+	var args []any
+	for _, cond := range []struct {
+		set    bool
+		clause string
+		arg    any
+	}{
+		{query.Actor != "", "actor = ?", query.Actor},
+		{query.Action != "", "action = ?", query.Action},
+		{query.StudentID != 0, "student_id = ?", query.StudentID},
+		{query.RequestID != "", "request_id = ?", query.RequestID},
+		{!query.Since.IsZero(), "logged_at >= ?", query.Since.UnixNano()},
+		{!query.Until.IsZero(), "logged_at < ?", query.Until.UnixNano()},
+	} {
+		if cond.set {
+			where = append(where, cond.clause)
+			args = append(args, cond.arg)
+		}
+	}
+	filter := ""
+	if len(where) > 0 {
+		filter = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := ss.db.QueryRow(`SELECT COUNT(*) FROM audit_log`+filter, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count audit entries: %w", err)
+	}
+	// SQLite 中 LIMIT -1 表示不限制
+	limit := query.Limit
+	if limit == 0 {
+		limit = -1
+	}
+	rows, err := ss.db.Query(`SELECT seq, logged_at, actor, request_id, action, reason, student_id, before, after FROM audit_log`+filter+
+		` ORDER BY seq DESC LIMIT ? OFFSET ?`, append(args, limit, query.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("query audit entries: %w", err)
+	}
+	defer rows.Close()
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var entry AuditEntry
+		var loggedAt int64
+		var before, after sql.NullString
+		if err := rows.Scan(&entry.Seq, &loggedAt, &entry.Actor, &entry.RequestID, &entry.Action, &entry.Reason, &entry.StudentID, &before, &after); err != nil {
+			return nil, 0, fmt.Errorf("scan audit entry: %w", err)
+		}
+		entry.Time = time.Unix(0, loggedAt).UTC()
+		if before.Valid {
+			entry.Before = json.RawMessage(before.String)
+		}
+		if after.Valid {
+			entry.After = json.RawMessage(after.String)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("query audit entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+// encodeRaw 把 JSON 保存为文本，为空时保存为 NULL
+func encodeRaw(data json.RawMessage) sql.NullString {
+	if len(data) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(data), Valid: true}
+}
+
+// encodeTime 把可以为空的时间编码为 RFC3339 文本，为空时保存为 NULL
+func encodeTime(t *time.Time) sql.NullString {
+	if t == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: t.UTC().Format(time.RFC3339Nano), Valid: true}
+}
+
+// decodeTime 解析 encodeTime 保存的时间
+func decodeTime(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// encodeComponents 把组成部分成绩编码为 JSON，没有组成部分时保存为 NULL
+func encodeComponents(components map[string]float64) (sql.NullString, error) {
+	if len(components) == 0 {
+		return sql.NullString{}, nil
+	}
+	data, err := json.Marshal(components)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("encode components: %w", err)
+	}
+	return sql.NullString{String: string(data), Valid: true}, nil
+}
+
+// decodeComponents 解码 JSON 格式的组成部分成绩
+func decodeComponents(data sql.NullString) (map[string]float64, error) {
+	if !data.Valid {
+		return nil, nil
+	}
+	var components map[string]float64
+	if err := json.Unmarshal([]byte(data.String), &components); err != nil {
+		return nil, fmt.Errorf("decode components: %w", err)
+	}
+	return components, nil
+}
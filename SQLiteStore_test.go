@@ -0,0 +1,159 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+// TestSQLiteStorePersistence 测试 SQLite 存储在重新打开后保留学生和成绩
+func TestSQLiteStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.db")
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerCourses(t, sm, "Math", "History")
+	registerClasses(t, sm, "27", "28", "29")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	sm.AddStudent(&Graduate{Student: Student{Name: "hao", StudentID: 2, Gender: "female", Class: "27"}})
+	if err := sm.AddScore(1, "Math", "", 95.0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.AddScore(1, "History", "", 80.0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.ModifyStudent(2, map[string]interface{}{"class": "29"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 重新打开时迁移不应重复执行
+	store, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error on reopen, got %v", err)
+	}
+	sm = NewStudentManagerWithStore(store)
+	defer sm.Close()
+
+	student, err := sm.QueryStudent(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if student.Name != "wei" || len(student.Scores) != 2 || student.Scores["Math"] != 95.0 || student.Scores["History"] != 80.0 {
+		t.Errorf("Expected student wei with two scores, got %v", student)
+	}
+	student, err = sm.QueryStudent(2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if student.Class != "29" {
+		t.Errorf("Expected class 29, got %v", student.Class)
+	}
+
+	students, err := store.List()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(students) != 2 || students[0].GetID() != 1 || students[1].GetID() != 2 {
+		t.Errorf("Expected students 1 and 2 in order, got %v", students)
+	}
+}
+
+// TestSQLiteStoreDeleteRemovesScores 测试删除学生时其成绩一并删除
+func TestSQLiteStoreDeleteRemovesScores(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "students.db"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerCourses(t, sm, "Math", "Science")
+	registerClasses(t, sm, "28")
+	defer sm.Close()
+
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	sm.AddScore(1, "Math", "", 95.0)
+	sm.AddScore(1, "Science", "", 88.0)
+	if err := sm.DeleteScore(1, "Science", ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var count int
+	store.db.QueryRow(`SELECT COUNT(*) FROM scores WHERE student_id = 1`).Scan(&count)
+	if count != 1 {
+		t.Errorf("Expected 1 score row, got %d", count)
+	}
+
+	if err := sm.DeleteStudent(1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	store.db.QueryRow(`SELECT COUNT(*) FROM scores WHERE student_id = 1`).Scan(&count)
+	if count != 0 {
+		t.Errorf("Expected scores to be deleted with the student, got %d rows", count)
+	}
+	if _, err := sm.QueryStudent(1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected student 1 to be deleted, got %v", err)
+	}
+}
+
+// TestSQLiteStoreMigrationVersion 测试迁移版本记录
+func TestSQLiteStoreMigrationVersion(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "students.db"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer store.Close()
+
+	var version int
+	if err := store.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("Expected schema version %d, got %d", len(sqliteMigrations), version)
+	}
+}
+
+// TestSQLiteStoreMigrateScoresToTerms 测试升级前保存的成绩迁移为未指定学期的成绩
+func TestSQLiteStoreMigrateScoresToTerms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	statements := []string{`CREATE TABLE schema_migrations (version INTEGER PRIMARY KEY)`}
+	for version := 1; version <= 3; version++ {
+		statements = append(statements, sqliteMigrations[version-1], fmt.Sprintf(`INSERT INTO schema_migrations (version) VALUES (%d)`, version))
+	}
+	statements = append(statements,
+		`INSERT INTO students (id, name, gender, class) VALUES (1, 'wei', 'male', '28')`,
+		`INSERT INTO scores (student_id, course, score) VALUES (1, 'Math', 95)`)
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	db.Close()
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerCourses(t, sm, "Math")
+	defer sm.Close()
+	if score, err := sm.QueryScore(1, "Math", ""); err != nil || score != 95 {
+		t.Errorf("Expected migrated untermed score 95, got %v %v", score, err)
+	}
+	if err := sm.AddScore(1, "Math", "2025-2026-1", 60); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if student, _ := sm.QueryStudent(1); student.Scores["Math"] != 60 || len(student.TermScores) != 2 {
+		t.Errorf("Expected two Math attempts with current score 60, got %+v", student)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// componentNotFound 返回成绩组成部分不存在的错误
+func componentNotFound(studentID int, courseName, term, component string) error {
+	return fmt.Errorf("%s score of course %s in term %q %w for student with id %d", component, courseName, term, ErrNotFound, studentID)
+}
+
+// componentConflict 返回组成部分成绩已存在的错误
+func componentConflict(studentID int, courseName, term, component string) error {
+	return fmt.Errorf("%s score of course %s in term %q of student with id %d %w, modify it with a reason instead", component, courseName, term, studentID, ErrConflict)
+}
+
+// componentScale 返回课程各组成部分的权重和记分制，课程未配置权重或不是数值型记分制时返回 ValidationError，调用方需持有锁
+func (sm *StudentManager) componentScale(courseName string) (map[string]float64, GradingScale, error) {
+	catalog, err := sm.catalog()
+	if err != nil {
+		return nil, GradingScale{}, err
+	}
+	return componentWeights(catalog, sm.grading, courseName)
+}
+
+// componentWeights 按课程目录和记分规则返回课程各组成部分的权重和记分制，导入时使用开始导入时的快照
+func componentWeights(catalog courseCatalog, policy *GradingPolicy, courseName string) (map[string]float64, GradingScale, error) {
+	course, ok := catalog.find(courseName)
+	if !ok || course.Code != courseName || len(course.Weights) == 0 {
+		return nil, GradingScale{}, &ValidationError{Field: "component", Rule: "weights", Message: fmt.Sprintf("course %s has no component weights", courseName)}
+	}
+	scale, err := policy.ScaleFor(courseName)
+	if err != nil {
+		return nil, GradingScale{}, err
+	}
+	if scale.Grades != nil {
+		return nil, GradingScale{}, &ValidationError{Field: "component", Rule: "numeric", Message: fmt.Sprintf("course %s uses the %s scale, component scores need a numeric scale", courseName, scale.Name)}
+	}
+	return course.Weights, scale, nil
+}
+
+// checkComponent 校验组成部分的名称和成绩，调用方需持有锁
+func (sm *StudentManager) checkComponent(courseName, component string, score float64, weights map[string]float64) error {
+	return validateComponent(sm.grading, courseName, component, score, weights)
+}
+
+// validateComponent 按记分规则校验组成部分的名称和成绩
+func validateComponent(policy *GradingPolicy, courseName, component string, score float64, weights map[string]float64) error {
+	if _, ok := weights[component]; !ok {
+		names := make([]string, 0, len(weights))
+		for name := range weights {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return &ValidationError{Field: "component", Rule: "weights", Message: fmt.Sprintf("course %s has no component %q, expected one of %s", courseName, component, strings.Join(names, ", "))}
+	}
+	if err := policy.ValidateScore(courseName, score); err != nil {
+		var validationErr *ValidationError
+		if errors.As(err, &validationErr) {
+			validationErr.Field = "components." + component
+		}
+		return err
+	}
+	return nil
+}
+
+// computeScore 按权重计算总评成绩并按记分制的小数位数四舍五入，未录入的组成部分按 0 分计算
+func computeScore(weights, components map[string]float64, scale GradingScale) float64 {
+	total := 0.0
+	for component, weight := range weights {
+		total += components[component] * weight / 100
+	}
+	return scale.Round(total)
+}
+
+// AddScoreComponent 录入学生一门课程在一个学期某个组成部分（如 homework、final）的成绩，并按课程的权重重新计算总评成绩
+// 该学期还没有成绩时新建，组成部分已有成绩时返回 ErrConflict，需通过 ModifyScoreComponent 填写原因后修改
+// 该学期已有直接录入的总评成绩时，改为按组成部分计算会替换原成绩，需要填写 reason，否则返回 ErrConflict
+func (sm *StudentManager) AddScoreComponent(studentID int, courseName, term, component string, score float64, reason string) error {
+	reason = strings.TrimSpace(reason)
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditAddScoreComponent, reason)()
+	return sm.setScoreComponent(studentID, courseName, term, component, score, true, reason)
+}
+
+// ModifyScoreComponent 修改学生已录入的组成部分成绩，并重新计算总评成绩，reason 为必填的修改原因
+func (sm *StudentManager) ModifyScoreComponent(studentID int, courseName, term, component string, score float64, reason string) error {
+	reason, err := requireReason(reason)
+	if err != nil {
+		return err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditModifyScoreComponent, reason)()
+	return sm.setScoreComponent(studentID, courseName, term, component, score, false, reason)
+}
+
+// setScoreComponent 保存组成部分成绩，create 为 false 时只修改已有的组成部分，调用方需持有锁
+func (sm *StudentManager) setScoreComponent(studentID int, courseName, term, component string, score float64, create bool, reason string) error {
+	courseName, err := sm.resolveCourse(courseName)
+	if err != nil {
+		return err
+	}
+	if err := ValidateTerm(term); err != nil {
+		return err
+	}
+	weights, scale, err := sm.componentScale(courseName)
+	if err != nil {
+		return err
+	}
+	if err := sm.checkComponent(courseName, component, score, weights); err != nil {
+		return err
+	}
+
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return studentNotFound(studentID)
+	}
+	student := record.GetBase()
+	i := student.findTermScore(courseName, term)
+	switch {
+	case i < 0 && !create:
+		return scoreNotFound(studentID, courseName, term)
+	case i < 0:
+		student.TermScores = append(student.TermScores, TermScore{Course: courseName, Term: term})
+		i = len(student.TermScores) - 1
+	case create && len(student.TermScores[i].Components) == 0 && reason == "":
+		// 直接录入的总评成绩会被只有一个组成部分的加权成绩替换
+		return fmt.Errorf("score of course %s in term %q of student with id %d %w as a total, provide a reason to replace it with a weighted score", courseName, term, studentID, ErrConflict)
+	}
+	termScore := &student.TermScores[i]
+	_, recorded := termScore.Components[component]
+	if !recorded && !create {
+		return componentNotFound(studentID, courseName, term, component)
+	}
+	if recorded && create {
+		return componentConflict(studentID, courseName, term, component)
+	}
+	if termScore.Components == nil {
+		termScore.Components = make(map[string]float64)
+	}
+	termScore.Components[component] = score
+	termScore.Score = computeScore(weights, termScore.Components, scale)
+	student.rebuildScores()
+	return sm.store.Save(record)
+}
+
+// recomputeComponentScores 课程的权重修改后重新计算已录入组成部分的总评成绩，调用方需持有锁
+// 取消权重时保留原有的总评成绩
+func (sm *StudentManager) recomputeComponentScores(course Course) error {
+	if len(course.Weights) == 0 {
+		return nil
+	}
+	scale, err := sm.grading.ScaleFor(course.Code)
+	if err != nil || scale.Grades != nil {
+		return err
+	}
+	students, err := sm.store.List()
+	if err != nil {
+		return err
+	}
+	for _, record := range students {
+		student := record.GetBase()
+		changed := false
+		for i, termScore := range student.TermScores {
+			if termScore.Course != course.Code || len(termScore.Components) == 0 {
+				continue
+			}
+			if score := computeScore(course.Weights, termScore.Components, scale); score != termScore.Score {
+				student.TermScores[i].Score = score
+				changed = true
+			}
+		}
+		if changed {
+			student.rebuildScores()
+			if err := sm.store.Save(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// newComponentTestManager 创建注册了按平时、期中、期末计算总评的高等数学课程的 StudentManager
+func newComponentTestManager(t *testing.T, store StudentStore) *StudentManager {
+	t.Helper()
+	sm := NewStudentManagerWithStore(store)
+	registerClasses(t, sm, "28")
+	course := Course{Code: "MATH101", Name: "高等数学", Credits: 4, Weights: map[string]float64{"homework": 20, "midterm": 30, "final": 50}}
+	if _, err := sm.AddCourse(course); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := sm.AddCourse(Course{Code: "ENG101", Name: "English", Credits: 2}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Class: "28"}})
+	return sm
+}
+
+// TestCourseWeights 测试课程权重的校验
+func TestCourseWeights(t *testing.T) {
+	sm := newComponentTestManager(t, NewMemoryStore())
+	tests := []struct {
+		weights map[string]float64
+		field   string
+	}{
+		{map[string]float64{"homework": 40, "final": 50}, "weights"},
+		{map[string]float64{"homework": 0, "final": 100}, "weights.homework"},
+		{map[string]float64{" ": 50, "final": 50}, "weights"},
+	}
+	for _, tt := range tests {
+		var validationErr *ValidationError
+		_, err := sm.AddCourse(Course{Code: "PHY101", Name: "Physics", Weights: tt.weights})
+		if !errors.As(err, &validationErr) || validationErr.Field != tt.field {
+			t.Errorf("%v: expected validation error on %s, got %v", tt.weights, tt.field, err)
+		}
+	}
+}
+
+// TestScoreComponents 测试录入和修改组成部分成绩后自动计算总评成绩
+func TestScoreComponents(t *testing.T) {
+	sm := newComponentTestManager(t, NewMemoryStore())
+
+	if err := sm.AddScoreComponent(1, "高等数学", "2025-2026-1", "homework", 90, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if score, _ := sm.QueryScore(1, "MATH101", "2025-2026-1"); score != 18 {
+		t.Errorf("Expected missing components to count as 0, got %v", score)
+	}
+	sm.AddScoreComponent(1, "MATH101", "2025-2026-1", "midterm", 80, "")
+	sm.AddScoreComponent(1, "MATH101", "2025-2026-1", "final", 75, "")
+	termScore, err := sm.QueryTermScore(1, "MATH101", "2025-2026-1")
+	if err != nil || termScore.Score != 79.5 || len(termScore.Components) != 3 {
+		t.Fatalf("Expected computed score 79.5 from 3 components, got %+v %v", termScore, err)
+	}
+
+	if err := sm.ModifyScoreComponent(1, "MATH101", "2025-2026-1", "final", 85, "复核"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if score, _ := sm.QueryScore(1, "MATH101", "2025-2026-1"); score != 84.5 {
+		t.Errorf("Expected recomputed score 84.5, got %v", score)
+	}
+	if err := sm.ModifyScoreComponent(1, "MATH101", "2024-2025-1", "final", 85, "复核"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected component in another term to be not found, got %v", err)
+	}
+
+	var validationErr *ValidationError
+	if err := sm.ModifyScore(1, "MATH101", "2025-2026-1", 90, "复核"); !errors.As(err, &validationErr) || validationErr.Rule != "computed" {
+		t.Errorf("Expected computed score not to be modified directly, got %v", err)
+	}
+	if err := sm.AddScoreComponent(1, "MATH101", "2025-2026-1", "lab", 90, ""); !errors.As(err, &validationErr) || validationErr.Field != "component" {
+		t.Errorf("Expected unknown component to be rejected, got %v", err)
+	}
+	if err := sm.AddScoreComponent(1, "MATH101", "2025-2026-1", "final", 120, ""); !errors.As(err, &validationErr) || validationErr.Field != "components.final" {
+		t.Errorf("Expected out of range component to be rejected, got %v", err)
+	}
+	if err := sm.AddScoreComponent(1, "ENG101", "", "final", 80, ""); !errors.As(err, &validationErr) || validationErr.Rule != "weights" {
+		t.Errorf("Expected course without weights to be rejected, got %v", err)
+	}
+
+	// 直接录入的总评成绩改为按组成部分计算时需要填写原因
+	sm.AddScore(1, "MATH101", "2024-2025-2", 85)
+	if err := sm.AddScoreComponent(1, "MATH101", "2024-2025-2", "final", 90, ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected a total score not to be replaced without a reason, got %v", err)
+	}
+	if score, _ := sm.QueryScore(1, "MATH101", "2024-2025-2"); score != 85 {
+		t.Errorf("Expected the total score to stay 85, got %v", score)
+	}
+	if err := sm.AddScoreComponent(1, "MATH101", "2024-2025-2", "final", 90, "改为按平时和期末计算"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if score, _ := sm.QueryScore(1, "MATH101", "2024-2025-2"); score != 45 {
+		t.Errorf("Expected the weighted score 45, got %v", score)
+	}
+
+	// 修改权重后重新计算已有的总评成绩，必须填写原因
+	course, _ := sm.QueryCourse("MATH101")
+	course.Weights = map[string]float64{"homework": 10, "midterm": 40, "final": 50}
+	if _, err := sm.ModifyCourse("MATH101", *course, " "); !errors.As(err, &validationErr) || validationErr.Field != "reason" {
+		t.Errorf("Expected reweighting without a reason to be rejected, got %v", err)
+	}
+	if _, err := sm.ModifyCourse("MATH101", *course, "调整权重"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if score, _ := sm.QueryScore(1, "MATH101", "2025-2026-1"); score != 83.5 {
+		t.Errorf("Expected score recomputed to 83.5 with new weights, got %v", score)
+	}
+	entries, _, _ := sm.QueryAudit(AuditQuery{Action: AuditRecomputeScores})
+	if len(entries) == 0 || entries[0].Reason != "调整权重" {
+		t.Errorf("Expected the recompute to be audited with the reason, got %+v", entries)
+	}
+
+	// 写入学生失败时课程的权重和学生的成绩都不修改
+	sm.auditor.log = failingAuditStore{}
+	course.Weights = map[string]float64{"homework": 50, "final": 50}
+	if _, err := sm.ModifyCourse("MATH101", *course, "再次调整"); err == nil {
+		t.Fatalf("Expected the failed recompute to be reported")
+	}
+	if course, _ := sm.QueryCourse("MATH101"); course.Weights["midterm"] != 40 {
+		t.Errorf("Expected the weights to be restored, got %v", course.Weights)
+	}
+	if score, _ := sm.QueryScore(1, "MATH101", "2025-2026-1"); score != 83.5 {
+		t.Errorf("Expected the score to stay 83.5, got %v", score)
+	}
+}
+
+// TestScoreComponentsWithStudent 测试随学生一起提交组成部分成绩，以及 SQLite 存储保存组成部分和权重
+func TestScoreComponentsWithStudent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := newComponentTestManager(t, store)
+	student := &Undergraduate{Student{Name: "hao", StudentID: 2, Class: "28", TermScores: []TermScore{
+		{Course: "高等数学", Term: "2025-2026-1", Score: 100, Components: map[string]float64{"homework": 100, "midterm": 60, "final": 70}},
+	}}}
+	if err := sm.AddStudent(student); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm.Close()
+
+	store, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error on reopen, got %v", err)
+	}
+	sm = NewStudentManagerWithStore(store)
+	defer sm.Close()
+	termScore, err := sm.QueryTermScore(2, "MATH101", "2025-2026-1")
+	if err != nil || termScore.Score != 73 || termScore.Components["midterm"] != 60 {
+		t.Errorf("Expected submitted score to be replaced by computed 73, got %+v %v", termScore, err)
+	}
+	course, err := sm.QueryCourse("MATH101")
+	if err != nil || course.Weights["final"] != 50 {
+		t.Errorf("Expected weights after reopen, got %+v %v", course, err)
+	}
+}
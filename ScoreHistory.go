@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ScoreChange 成绩历史中的一次变化，来自审计日志
+type ScoreChange struct {
+	Seq       int64      `json:"seq"` // 审计记录的序号，撤销修改时使用
+	Time      time.Time  `json:"time"`
+	Actor     string     `json:"actor"`
+	RequestID string     `json:"request_id,omitempty"`
+	Action    string     `json:"action"`
+	Reason    string     `json:"reason,omitempty"`
+	Term      string     `json:"term,omitempty"`
+	Before    *TermScore `json:"before,omitempty"` // 录入成绩时为空
+	After     *TermScore `json:"after,omitempty"`  // 删除成绩时为空
+}
+
+// requireReason 校验修改原因不能为空
+func requireReason(reason string) (string, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return "", &ValidationError{Field: "reason", Rule: "required", Message: "must not be empty"}
+	}
+	return reason, nil
+}
+
+// snapshotScores 返回审计记录中学生一门课程各学期的成绩，data 为空时返回 nil
+func snapshotScores(data json.RawMessage, courseName string) (map[string]TermScore, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var student Student
+	if err := json.Unmarshal(data, &student); err != nil {
+		return nil, fmt.Errorf("decode audit entry: %w", err)
+	}
+	scores := make(map[string]TermScore)
+	for _, record := range student.termScores() {
+		if record.Course == courseName {
+			scores[record.Term] = record
+		}
+	}
+	return scores, nil
+}
+
+// ScoreHistory 按时间顺序返回学生一门课程各学期成绩的每一次变化，包括操作者和修改原因
+func (sm *StudentManager) ScoreHistory(studentID int, courseName string) ([]ScoreChange, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	courseName, err := sm.lookupCourse(courseName)
+	if err != nil {
+		return nil, err
+	}
+	return sm.scoreHistory(studentID, courseName)
+}
+
+// scoreHistory 从审计日志中整理成绩历史，学生不存在且没有历史时返回 ErrNotFound，调用方需持有锁
+func (sm *StudentManager) scoreHistory(studentID int, courseName string) ([]ScoreChange, error) {
+	entries, _, err := sm.auditor.log.QueryAudit(AuditQuery{StudentID: studentID})
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		_, exists, err := sm.store.Get(studentID)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, studentNotFound(studentID)
+		}
+	}
+	history := []ScoreChange{}
+	// 审计日志按序号降序返回，从最早的记录开始比较
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		before, err := snapshotScores(entry.Before, courseName)
+		if err != nil {
+			return nil, err
+		}
+		after, err := snapshotScores(entry.After, courseName)
+		if err != nil {
+			return nil, err
+		}
+		terms := make(map[string]bool)
+		for term := range before {
+			terms[term] = true
+		}
+		for term := range after {
+			terms[term] = true
+		}
+		sorted := make([]string, 0, len(terms))
+		for term := range terms {
+			sorted = append(sorted, term)
+		}
+		sort.Strings(sorted)
+		for _, term := range sorted {
+			old, hadOld := before[term]
+			updated, hasNew := after[term]
+			if hadOld == hasNew && reflect.DeepEqual(old, updated) {
+				continue
+			}
+			change := ScoreChange{
+				Seq:       entry.Seq,
+				Time:      entry.Time,
+				Actor:     entry.Actor,
+				RequestID: entry.RequestID,
+				Action:    entry.Action,
+				Reason:    entry.Reason,
+				Term:      term,
+			}
+			if hadOld {
+				change.Before = &old
+			}
+			if hasNew {
+				change.After = &updated
+			}
+			history = append(history, change)
+		}
+	}
+	return history, nil
+}
+
+// RevertScore 把学生一门课程在一个学期的成绩恢复为成绩历史中序号为 seq 的修改之后的值，
+// 包括补考成绩和组成部分成绩；成绩已被删除时重新录入，reason 为必填的撤销原因。
+// 恢复组成部分成绩时按课程当前的权重重新计算总评成绩
+func (sm *StudentManager) RevertScore(studentID int, courseName, term string, seq int64, reason string) error {
+	reason, err := requireReason(reason)
+	if err != nil {
+		return err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditRevertScore, reason)()
+	courseName, err = sm.lookupCourse(courseName)
+	if err != nil {
+		return err
+	}
+	history, err := sm.scoreHistory(studentID, courseName)
+	if err != nil {
+		return err
+	}
+	var target *TermScore
+	found := false
+	for _, change := range history {
+		if change.Seq == seq && change.Term == term {
+			target, found = change.After, true
+			break
+		}
+	}
+	if !found {
+		return &ValidationError{Field: "seq", Rule: "history", Message: fmt.Sprintf("no change %d to course %s in term %q", seq, courseName, term)}
+	}
+	if target == nil {
+		return &ValidationError{Field: "seq", Rule: "deleted", Message: fmt.Sprintf("change %d deleted the score, there is no value to restore", seq)}
+	}
+	// 课程的权重可能已经修改，有组成部分的成绩按当前的权重重新计算总评成绩，组成部分已不在权重中时拒绝恢复
+	restored := *target
+	if len(restored.Components) > 0 {
+		weights, scale, err := sm.componentScale(courseName)
+		if err != nil {
+			return err
+		}
+		for component, score := range restored.Components {
+			if err := sm.checkComponent(courseName, component, score, weights); err != nil {
+				return err
+			}
+		}
+		restored.Score = computeScore(weights, restored.Components, scale)
+	}
+	target = &restored
+	// 记分规则可能已经修改，恢复的成绩同样需要符合当前的规则
+	if err := sm.validateScore(courseName, target.Score); err != nil {
+		return err
+	}
+	if target.Makeup != nil {
+		if err := sm.validateScore(courseName, *target.Makeup); err != nil {
+			return err
+		}
+	}
+
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return studentNotFound(studentID)
+	}
+	student := record.GetBase()
+	if i := student.findTermScore(courseName, term); i >= 0 {
+		if reflect.DeepEqual(student.TermScores[i], *target) {
+			return nil
+		}
+		student.TermScores[i] = *target
+	} else {
+		student.TermScores = append(student.TermScores, *target)
+	}
+	student.rebuildScores()
+	return sm.store.Save(record)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// TestScoreHistory 测试成绩历史按时间顺序记录每个学期的每一次变化、操作者和原因
+func TestScoreHistory(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	wei := sm.As(Actor{Name: "wei", RequestID: "req-1"})
+	wei.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 1}})
+	wei.AddScore(1, "Math", "2024-2025-1", 55)
+	wei.AddScore(1, "English", "2024-2025-1", 70)
+	sm.As(Actor{Name: "li"}).ModifyScore(1, "Math", "2024-2025-1", 58, "试卷漏判一题")
+	wei.RecordMakeup(1, "Math", "2024-2025-1", 75, "")
+	wei.AddScore(1, "Math", "2025-2026-1", 90)
+	wei.DeleteScore(1, "Math", "2025-2026-1")
+
+	history, err := sm.ScoreHistory(1, "Math")
+	if err != nil || len(history) != 5 {
+		t.Fatalf("Expected 5 changes of Math, got %+v %v", history, err)
+	}
+	actions := []string{AuditAddScore, AuditModifyScore, AuditRecordMakeup, AuditAddScore, AuditDeleteScore}
+	for i, change := range history {
+		if change.Action != actions[i] || (i > 0 && change.Seq <= history[i-1].Seq) {
+			t.Errorf("Expected change %d to be %s in order, got %+v", i, actions[i], change)
+		}
+	}
+	if first := history[0]; first.Before != nil || first.After.Score != 55 || first.Term != "2024-2025-1" || first.Actor != "wei" || first.RequestID != "req-1" {
+		t.Errorf("Expected the first score to be 55 added by wei, got %+v", first)
+	}
+	if modified := history[1]; modified.Before.Score != 55 || modified.After.Score != 58 || modified.Actor != "li" || modified.Reason != "试卷漏判一题" {
+		t.Errorf("Expected li to change 55 to 58 with the reason, got %+v", modified)
+	}
+	if deleted := history[4]; deleted.After != nil || deleted.Before.Score != 90 {
+		t.Errorf("Expected the deleted score to have no after, got %+v", deleted)
+	}
+
+	if history, err := sm.ScoreHistory(1, "Physics"); err != nil || len(history) != 0 {
+		t.Errorf("Expected empty history of a course without scores, got %+v %v", history, err)
+	}
+	if _, err := sm.ScoreHistory(2, "Math"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected not found for unknown student, got %v", err)
+	}
+}
+
+// TestRevertScore 测试把成绩恢复为历史中的值
+func TestRevertScore(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 1}})
+	sm.AddScore(1, "Math", "2024-2025-1", 55)
+	sm.ModifyScore(1, "Math", "2024-2025-1", 85, "录入错误")
+	sm.AddScore(1, "Math", "2025-2026-1", 90)
+	sm.DeleteScore(1, "Math", "2025-2026-1")
+	history, _ := sm.ScoreHistory(1, "Math")
+	added, deleted := history[0], history[3]
+
+	var validationErr *ValidationError
+	tests := []struct {
+		term   string
+		seq    int64
+		reason string
+		rule   string
+	}{
+		{"2024-2025-1", added.Seq, "", "required"},
+		{"2024-2025-1", 999, "成绩复核", "history"},
+		{"2025-2026-1", added.Seq, "成绩复核", "history"},
+		{"2025-2026-1", deleted.Seq, "成绩复核", "deleted"},
+	}
+	for _, tt := range tests {
+		if err := sm.RevertScore(1, "Math", tt.term, tt.seq, tt.reason); !errors.As(err, &validationErr) || validationErr.Rule != tt.rule {
+			t.Errorf("%+v: expected validation error %s, got %v", tt, tt.rule, err)
+		}
+	}
+
+	reviewer := sm.As(Actor{Name: "zhang", RequestID: "appeal-7"})
+	if err := reviewer.RevertScore(1, "Math", "2024-2025-1", added.Seq, "成绩复核维持原成绩"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if score, _ := sm.QueryScore(1, "Math", "2024-2025-1"); score != 55 {
+		t.Errorf("Expected score to be reverted to 55, got %v", score)
+	}
+	// 恢复已删除的成绩
+	if err := reviewer.RevertScore(1, "Math", "2025-2026-1", history[2].Seq, "误删"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if score, err := sm.QueryScore(1, "Math", "2025-2026-1"); err != nil || score != 90 {
+		t.Errorf("Expected deleted score to be restored to 90, got %v %v", score, err)
+	}
+
+	history, _ = sm.ScoreHistory(1, "Math")
+	if reverted := history[len(history)-2]; reverted.Action != AuditRevertScore || reverted.Actor != "zhang" ||
+		reverted.Reason != "成绩复核维持原成绩" || reverted.Before.Score != 85 || reverted.After.Score != 55 {
+		t.Errorf("Expected the revert in the history, got %+v", reverted)
+	}
+	// 成绩没有变化时不再记录
+	sm.RevertScore(1, "Math", "2024-2025-1", added.Seq, "重复提交")
+	if again, _ := sm.ScoreHistory(1, "Math"); len(again) != len(history) {
+		t.Errorf("Expected no new change when the score is unchanged, got %+v", again)
+	}
+}
+
+// TestScoreHistoryPersistence 测试 SQLite 存储在重新打开后保留修改原因
+func TestScoreHistoryPersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerCourses(t, sm, "Math")
+	sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 1}})
+	sm.AddScore(1, "Math", "", 55)
+	sm.ModifyScore(1, "Math", "", 60, "成绩复核")
+	sm.Close()
+
+	store, err = NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error on reopen, got %v", err)
+	}
+	sm = NewStudentManagerWithStore(store)
+	defer sm.Close()
+	history, err := sm.ScoreHistory(1, "Math")
+	if err != nil || len(history) != 2 || history[1].Reason != "成绩复核" || history[1].After.Score != 60 {
+		t.Errorf("Expected the reason after reopen, got %+v %v", history, err)
+	}
+}
+
+// TestScoreOverwrite 测试录入接口不能覆盖已有的成绩、组成部分成绩和补考成绩，只能填写原因后修改
+func TestScoreOverwrite(t *testing.T) {
+	sm := newComponentTestManager(t, NewMemoryStore())
+	sm.AddScore(1, "ENG101", "2024-2025-1", 45)
+	sm.RecordMakeup(1, "ENG101", "2024-2025-1", 55, "")
+	sm.AddScoreComponent(1, "MATH101", "2024-2025-1", "final", 80, "")
+
+	if err := sm.AddScore(1, "ENG101", "2024-2025-1", 90); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected existing score not to be overwritten, got %v", err)
+	}
+	if termScore, _ := sm.QueryTermScore(1, "ENG101", "2024-2025-1"); termScore.Score != 45 || termScore.Makeup == nil {
+		t.Errorf("Expected the score and its makeup to be kept, got %+v", termScore)
+	}
+	if err := sm.AddScoreComponent(1, "MATH101", "2024-2025-1", "final", 90, ""); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected existing component not to be overwritten, got %v", err)
+	}
+	var validationErr *ValidationError
+	if err := sm.RecordMakeup(1, "ENG101", "2024-2025-1", 70, " "); !errors.As(err, &validationErr) || validationErr.Field != "reason" {
+		t.Errorf("Expected a reason to change the makeup score, got %v", err)
+	}
+	if err := sm.RecordMakeup(1, "ENG101", "2024-2025-1", 70, "补考试卷复核"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	history, _ := sm.ScoreHistory(1, "ENG101")
+	if last := history[len(history)-1]; last.Action != AuditRecordMakeup || last.Reason != "补考试卷复核" || *last.After.Makeup != 70 {
+		t.Errorf("Expected the makeup change with its reason, got %+v", last)
+	}
+}
+
+// TestRevertScoreComponents 测试恢复组成部分成绩时按当前权重计算总评成绩，组成部分已不在权重中时拒绝恢复
+func TestRevertScoreComponents(t *testing.T) {
+	sm := newComponentTestManager(t, NewMemoryStore())
+	sm.AddScoreComponent(1, "MATH101", "2025-2026-1", "homework", 90, "")
+	sm.AddScoreComponent(1, "MATH101", "2025-2026-1", "final", 80, "")
+	history, _ := sm.ScoreHistory(1, "MATH101")
+	seq := history[len(history)-1].Seq
+	sm.ModifyScoreComponent(1, "MATH101", "2025-2026-1", "final", 60, "复核")
+
+	course, _ := sm.QueryCourse("MATH101")
+	course.Weights = map[string]float64{"homework": 40, "midterm": 10, "final": 50}
+	if _, err := sm.ModifyCourse("MATH101", *course, "调整权重"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.RevertScore(1, "MATH101", "2025-2026-1", seq, "复核维持原成绩"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	termScore, _ := sm.QueryTermScore(1, "MATH101", "2025-2026-1")
+	if termScore.Components["final"] != 80 || termScore.Score != 76 {
+		t.Errorf("Expected the total recomputed to 76 with the current weights, got %+v", termScore)
+	}
+
+	course.Weights = map[string]float64{"exam": 100}
+	sm.ModifyCourse("MATH101", *course, "只看考试")
+	var validationErr *ValidationError
+	if err := sm.RevertScore(1, "MATH101", "2025-2026-1", seq, "复核维持原成绩"); !errors.As(err, &validationErr) || validationErr.Rule != "weights" {
+		t.Errorf("Expected components outside the current weights to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// 学生列表分页默认值
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// StudentQuery 学生列表的查询条件
+type StudentQuery struct {
+	Class  string // 班级，精确匹配
+	Gender string // 性别，精确匹配
+	Type   string // 学生类型，精确匹配
+	Name   string // 姓名子串，不区分大小写
+	SortBy string // 排序字段，见 studentSortKeys，默认按学号
+	Desc   bool   // 是否降序
+	Offset int    // 跳过的条数
+	Limit  int    // 返回的最大条数，0 表示默认值
+}
+
+// studentSortKeys 支持排序的字段及其比较函数
+var studentSortKeys = map[string]func(a, b *Student) int{
+	"id":     func(a, b *Student) int { return cmp.Compare(a.StudentID, b.StudentID) },
+	"name":   func(a, b *Student) int { return cmp.Compare(a.Name, b.Name) },
+	"gender": func(a, b *Student) int { return cmp.Compare(a.Gender, b.Gender) },
+	"class":  func(a, b *Student) int { return cmp.Compare(a.Class, b.Class) },
+	"type":   func(a, b *Student) int { return cmp.Compare(a.Type, b.Type) },
+}
+
+// StudentSortFields 按名称排序返回支持排序的字段
+func StudentSortFields() []string {
+	fields := make([]string, 0, len(studentSortKeys))
+	for field := range studentSortKeys {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// matches 判断学生是否满足查询条件
+func (q StudentQuery) matches(student *Student) bool {
+	if q.Class != "" && student.Class != q.Class {
+		return false
+	}
+	if q.Gender != "" && student.Gender != q.Gender {
+		return false
+	}
+	if q.Type != "" && student.Type != q.Type {
+		return false
+	}
+	if q.Name != "" && !strings.Contains(strings.ToLower(student.Name), strings.ToLower(q.Name)) {
+		return false
+	}
+	return true
+}
+
+// matchingStudents 按学号升序返回满足筛选条件的全部学生，忽略排序和分页
+func (sm *StudentManager) matchingStudents(query StudentQuery) ([]StudentInterface, error) {
+	sm.mu.Lock()
+	students, err := sm.store.List()
+	sm.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]StudentInterface, 0, len(students))
+	for _, student := range students {
+		if query.matches(student.GetBase()) {
+			matched = append(matched, student)
+		}
+	}
+	return matched, nil
+}
+
+// eachStudentPage 按学号升序逐页读取学生，对每一页中满足筛选条件的学生调用 fn，忽略排序和分页
+// 只在读取每一页时持有锁，fn 中可以耗时写入输出
+func (sm *StudentManager) eachStudentPage(query StudentQuery, fn func(students []StudentInterface) error) error {
+	afterID := math.MinInt
+	for {
+		sm.mu.Lock()
+		page, err := listStudentsAfter(sm.store, afterID, exportPageSize)
+		sm.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		if len(page) == 0 {
+			return nil
+		}
+		afterID = page[len(page)-1].GetID()
+
+		matched := make([]StudentInterface, 0, len(page))
+		for _, student := range page {
+			if query.matches(student.GetBase()) {
+				matched = append(matched, student)
+			}
+		}
+		if err := fn(matched); err != nil {
+			return err
+		}
+		if len(page) < exportPageSize {
+			return nil
+		}
+	}
+}
+
+// ListStudents 按条件筛选、排序并分页返回学生，同时返回满足条件的总数
+func (sm *StudentManager) ListStudents(query StudentQuery) ([]StudentInterface, int, error) {
+	if query.SortBy == "" {
+		query.SortBy = "id"
+	}
+	compare, ok := studentSortKeys[query.SortBy]
+	if !ok {
+		return nil, 0, &ValidationError{
+			Field:   "sort",
+			Message: fmt.Sprintf("must be one of %s", strings.Join(StudentSortFields(), ", ")),
+		}
+	}
+	if query.Offset < 0 {
+		return nil, 0, &ValidationError{Field: "offset", Message: "must not be negative"}
+	}
+	if query.Limit < 0 || query.Limit > maxPageLimit {
+		return nil, 0, &ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", maxPageLimit)}
+	}
+	if query.Limit == 0 {
+		query.Limit = defaultPageLimit
+	}
+
+	matched, err := sm.matchingStudents(query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 相同排序值按学号升序，保证分页结果稳定
+	sort.SliceStable(matched, func(i, j int) bool {
+		a, b := matched[i].GetBase(), matched[j].GetBase()
+		result := compare(a, b)
+		if query.Desc {
+			result = -result
+		}
+		if result == 0 {
+			return a.StudentID < b.StudentID
+		}
+		return result < 0
+	})
+
+	total := len(matched)
+	if query.Offset >= total {
+		return []StudentInterface{}, total, nil
+	}
+	end := query.Offset + query.Limit
+	if end > total {
+		end = total
+	}
+	return matched[query.Offset:end], total, nil
+}
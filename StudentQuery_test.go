@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// newListTestManager 创建带有若干学生的 StudentManager
+func newListTestManager(t *testing.T) *StudentManager {
+	t.Helper()
+	sm := NewStudentManager()
+	registerClasses(t, sm, "27", "28")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	sm.AddStudent(&Graduate{Student: Student{Name: "hao", StudentID: 2, Gender: "female", Class: "27"}})
+	sm.AddStudent(&Undergraduate{Student{Name: "Wang Wei", StudentID: 3, Gender: "female", Class: "28"}})
+	sm.AddStudent(&Graduate{Student: Student{Name: "li", StudentID: 4, Gender: "male", Class: "28"}})
+	sm.AddStudent(&Undergraduate{Student{Name: "zhao", StudentID: 5, Gender: "male", Class: "27"}})
+	return sm
+}
+
+// studentIDs 提取学号，便于比较
+func studentIDs(students []StudentInterface) []int {
+	ids := make([]int, len(students))
+	for i, student := range students {
+		ids[i] = student.GetID()
+	}
+	return ids
+}
+
+// TestListStudents 测试学生列表的筛选、排序和分页
+func TestListStudents(t *testing.T) {
+	sm := newListTestManager(t)
+
+	tests := []struct {
+		name  string
+		query StudentQuery
+		ids   []int
+		total int
+	}{
+		{"default order", StudentQuery{}, []int{1, 2, 3, 4, 5}, 5},
+		{"filter by class", StudentQuery{Class: "28"}, []int{1, 3, 4}, 3},
+		{"filter by gender and type", StudentQuery{Gender: "male", Type: "undergraduate"}, []int{1, 5}, 2},
+		{"name substring ignores case", StudentQuery{Name: "WEI"}, []int{1, 3}, 2},
+		{"sort by name", StudentQuery{SortBy: "name"}, []int{3, 2, 4, 1, 5}, 5},
+		{"sort by class desc keeps id order for ties", StudentQuery{SortBy: "class", Desc: true}, []int{1, 3, 4, 2, 5}, 5},
+		{"offset and limit", StudentQuery{Offset: 1, Limit: 2}, []int{2, 3}, 5},
+		{"offset past the end", StudentQuery{Offset: 10}, []int{}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			students, total, err := sm.ListStudents(tt.query)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if total != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, total)
+			}
+			ids := studentIDs(students)
+			if len(ids) != len(tt.ids) {
+				t.Fatalf("Expected ids %v, got %v", tt.ids, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.ids[i] {
+					t.Fatalf("Expected ids %v, got %v", tt.ids, ids)
+				}
+			}
+		})
+	}
+}
+
+// TestListStudentsInvalidQuery 测试不合法的排序字段和分页参数
+func TestListStudentsInvalidQuery(t *testing.T) {
+	sm := newListTestManager(t)
+	var validationErr *ValidationError
+
+	for _, query := range []StudentQuery{
+		{SortBy: "age"},
+		{Offset: -1},
+		{Limit: maxPageLimit + 1},
+	} {
+		if _, _, err := sm.ListStudents(query); !errors.As(err, &validationErr) {
+			t.Errorf("Expected validation error for %+v, got %v", query, err)
+		}
+	}
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
-	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
-	"reflect"
+	"os"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,15 +25,26 @@ type StudentInterface interface {
 	GetClass() string
 	GetScores() map[string]float64
 	SetScores(scores map[string]float64)
+	// GetType 返回学生类型名，与 RegisterStudentType 注册的名称一致
+	GetType() string
+	// GetBase 返回内嵌的公共学生信息，用于统一修改姓名、班级和成绩
+	GetBase() *Student
 }
 
 // Student 结构体
 type Student struct {
-	Name      string             `json:"name"`
-	StudentID int                `json:"id"`
-	Gender    string             `json:"gender"`
-	Class     string             `json:"class"`
-	Scores    map[string]float64 `json:"scores"`
+	Name       string             `json:"name"`
+	StudentID  int                `json:"id"`
+	Gender     string             `json:"gender"`
+	Class      string             `json:"class"`
+	Type       string             `json:"type"`
+	Scores     map[string]float64 `json:"scores"`                // 每门课程最近一个学期的有效成绩
+	TermScores []TermScore        `json:"term_scores,omitempty"` // 按课程和学期记录的全部成绩
+}
+
+// GetBase 返回学生公共信息本身，内嵌 Student 的类型自动获得该方法
+func (s *Student) GetBase() *Student {
+	return s
 }
 
 // Undergraduate 本科生结构体
@@ -39,6 +55,11 @@ type Undergraduate struct {
 // Graduate 研究生结构体
 type Graduate struct {
 	Student
+	Advisor       string `json:"advisor,omitempty"`
+	ResearchArea  string `json:"research_area,omitempty"`
+	DegreeType    string `json:"degree_type,omitempty"`
+	ThesisTitle   string `json:"thesis_title,omitempty"`
+	DefenseStatus string `json:"defense_status,omitempty"`
 }
 
 // 实现 StudentInterface 接口方法
@@ -73,6 +94,11 @@ func (u *Undergraduate) SetScores(scores map[string]float64) {
 	u.Scores = scores
 }
 
+// GetType 获取学生类型
+func (u *Undergraduate) GetType() string {
+	return "undergraduate"
+}
+
 // GetID 获取学生ID
 func (g *Graduate) GetID() int {
 	return g.StudentID
@@ -103,60 +129,364 @@ func (g *Graduate) SetScores(scores map[string]float64) {
 	g.Scores = scores
 }
 
+// GetType 获取学生类型
+func (g *Graduate) GetType() string {
+	return "graduate"
+}
+
+// ErrNotFound 表示学生或成绩不存在，可通过 errors.Is 判断
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict 表示学生已存在，可通过 errors.Is 判断
+var ErrConflict = errors.New("already exists")
+
+// ErrInUse 表示数据仍被其他数据引用而不能删除，可通过 errors.Is 判断
+var ErrInUse = errors.New("still in use")
+
+// studentNotFound 返回学生不存在的错误
+func studentNotFound(studentID int) error {
+	return fmt.Errorf("student with id %d %w", studentID, ErrNotFound)
+}
+
+// scoreNotFound 返回课程成绩不存在的错误
+func scoreNotFound(studentID int, courseName, term string) error {
+	if term != "" {
+		return fmt.Errorf("score for course %s in term %s %w for student with id %d", courseName, term, ErrNotFound, studentID)
+	}
+	return fmt.Errorf("score for course %s %w for student with id %d", courseName, ErrNotFound, studentID)
+}
+
+// scoreConflict 返回课程成绩已存在的错误，已有的成绩只能通过需要填写原因的修改接口修改
+func scoreConflict(studentID int, courseName, term string) error {
+	if term != "" {
+		return fmt.Errorf("score for course %s in term %s of student with id %d %w, modify it with a reason instead", courseName, term, studentID, ErrConflict)
+	}
+	return fmt.Errorf("score for course %s of student with id %d %w, modify it with a reason instead", courseName, studentID, ErrConflict)
+}
+
+// studentConflict 返回学生已存在的错误
+func studentConflict(studentID int) error {
+	return fmt.Errorf("student with id %d %w", studentID, ErrConflict)
+}
+
+// ValidationError 表示字段取值不合法
+type ValidationError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"message"`
+}
+
+// Error 实现 error 接口
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Message)
+}
+
+// ProfileUpdater 由带有类型特有字段的学生类型实现，
+// ModifyStudent 和 CSV 导入通过它更新这些字段
+type ProfileUpdater interface {
+	// ProfileFields 按 CSV 列顺序返回类型特有的字段名
+	ProfileFields() []string
+	// UpdateProfile 根据字段名更新类型特有的字段，忽略不认识的字段
+	UpdateProfile(updates map[string]interface{}) error
+}
+
+// StudentValidator 由需要校验自身字段的学生类型实现，
+// AddStudent 和 ModifyStudent 在保存前调用
+type StudentValidator interface {
+	Validate() error
+}
+
 // StudentManager 结构体
 type StudentManager struct {
-	students map[int]*Student
-	mu       sync.Mutex
+	store   StudentStore
+	courses CourseStore
+	classes ClassStore
+	users   UserStore
+	apiKeys APIKeyStore
+	auditor *auditStore // 包装 store，记录学生的每次写入
+	grading *GradingPolicy
+	mu      *sync.Mutex // As 返回的 StudentManager 共享同一把锁
 }
 
 // NewStudentManager 初始化 StudentManager
-// 初始化了一个空的学生映射，用于后续添加和管理学生信息
+// 使用内存存储，用于后续添加和管理学生信息
 func NewStudentManager() *StudentManager {
+	return NewStudentManagerWithStore(NewMemoryStore())
+}
+
+// NewStudentManagerWithStore 使用指定的存储初始化 StudentManager
+// 存储同时实现 CourseStore、ClassStore、UserStore 时课程、班级和用户也保存在其中，否则只保存在内存中
+func NewStudentManagerWithStore(store StudentStore) *StudentManager {
+	fallback := NewMemoryStore()
+	courses, ok := store.(CourseStore)
+	if !ok {
+		courses = fallback
+	}
+	classes, ok := store.(ClassStore)
+	if !ok {
+		classes = fallback
+	}
+	users, ok := store.(UserStore)
+	if !ok {
+		users = fallback
+	}
+	apiKeys, ok := store.(APIKeyStore)
+	if !ok {
+		apiKeys = fallback
+	}
+	audit, ok := store.(AuditStore)
+	if !ok {
+		audit = fallback
+	}
+	auditor := &auditStore{StudentStore: store, log: audit}
 	return &StudentManager{
-		students: make(map[int]*Student),
+		store:   auditor,
+		auditor: auditor,
+		mu:      new(sync.Mutex),
+		courses: courses,
+		classes: classes,
+		users:   users,
+		apiKeys: apiKeys,
+		grading: NewGradingPolicy(),
+	}
+}
+
+// SetGradingPolicy 设置成绩校验策略
+func (sm *StudentManager) SetGradingPolicy(policy *GradingPolicy) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.grading = policy
+}
+
+// GradingPolicy 返回当前的成绩校验策略
+func (sm *StudentManager) GradingPolicy() *GradingPolicy {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.grading
+}
+
+// validateScore 按成绩校验策略校验成绩，调用方需持有锁
+func (sm *StudentManager) validateScore(courseName string, score float64) error {
+	return sm.grading.ValidateScore(courseName, score)
+}
+
+// ParseGrade 把等级换算为课程记分制下保存的数值
+func (sm *StudentManager) ParseGrade(courseName, grade string) (float64, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	courseName, err := sm.lookupCourse(courseName)
+	if err != nil {
+		return 0, err
+	}
+	return sm.grading.ParseGrade(courseName, grade)
+}
+
+// GradeOf 返回成绩在课程记分制下对应的等级，数值型记分制返回空字符串
+func (sm *StudentManager) GradeOf(courseName string, score float64) string {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	courseName, err := sm.lookupCourse(courseName)
+	if err != nil {
+		return ""
 	}
+	scale, err := sm.grading.ScaleFor(courseName)
+	if err != nil {
+		return ""
+	}
+	return scale.GradeOf(score)
+}
+
+// Close 关闭底层存储
+func (sm *StudentManager) Close() error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.store.Close()
 }
 
 // AddStudent 添加学生信息
-func (sm *StudentManager) AddStudent(student StudentInterface) {
+// 学号已存在时返回 ErrConflict，不会覆盖已有学生及其成绩；需要覆盖时使用 UpsertStudent
+func (sm *StudentManager) AddStudent(student StudentInterface) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditAddStudent, "")()
+	_, err := sm.saveStudent(student, false)
+	return err
+}
+
+// UpsertStudent 添加学生信息，学号已存在时覆盖学生信息
+// 覆盖时保留已有成绩，新提交的成绩只能新增课程或学期；与同一课程同一学期的已有成绩不同时返回 ErrConflict，
+// 需通过 ModifyScore 填写原因后修改。created 表示是否为新增
+func (sm *StudentManager) UpsertStudent(student StudentInterface) (created bool, err error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	defer sm.operation(AuditUpsertStudent, "")()
+	return sm.saveStudent(student, true)
+}
 
-	// 使用反射获取学生ID
-	val := reflect.ValueOf(student)
-	idField := val.MethodByName("GetID")
-	studentID := idField.Call(nil)[0].Interface().(int)
+// scoreFieldError 把成绩和课程校验错误的字段名改为 scores.<课程名>，便于定位是哪门课程
+func scoreFieldError(err error, courseName string) error {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) && (validationErr.Field == "score" || validationErr.Field == "grade" || validationErr.Field == "course_name") {
+		validationErr.Field = "scores." + courseName
+	}
+	return err
+}
+
+// makeupFieldError 把补考成绩的校验错误的字段改为 makeup
+func makeupFieldError(err error) error {
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		validationErr.Field = "makeup"
+	}
+	return err
+}
+
+// saveStudent 校验并保存学生信息，调用方需持有锁
+func (sm *StudentManager) saveStudent(student StudentInterface, upsert bool) (bool, error) {
+	// 只接受已注册的学生类型，保证存储中的数据可以按类型还原
+	if _, err := NewStudentOfType(student.GetType()); err != nil {
+		return false, err
+	}
+
+	// 校验类型特有的字段
+	if validator, ok := student.(StudentValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return false, err
+		}
+	}
+
+	// 只接受已有的班级，班级名称换算为班级编号
+	class, err := sm.resolveClass(student.GetClass())
+	if err != nil {
+		return false, err
+	}
+
+	// 校验随学生一起提交的成绩，只提交 scores 时按未指定学期处理，课程名换算为已注册的课程代码
+	catalog, err := sm.catalog()
+	if err != nil {
+		return false, err
+	}
+	submitted := student.GetBase().termScores()
+	for i, termScore := range submitted {
+		code, err := catalog.resolve(termScore.Course)
+		if err != nil {
+			return false, scoreFieldError(err, termScore.Course)
+		}
+		if err := ValidateTerm(termScore.Term); err != nil {
+			return false, err
+		}
+		// 提交了组成部分成绩时按课程的权重计算总评成绩
+		if len(termScore.Components) > 0 {
+			weights, scale, err := sm.componentScale(code)
+			if err != nil {
+				return false, scoreFieldError(err, termScore.Course)
+			}
+			for component, score := range termScore.Components {
+				if err := sm.checkComponent(code, component, score, weights); err != nil {
+					return false, err
+				}
+			}
+			submitted[i].Score = computeScore(weights, termScore.Components, scale)
+			termScore.Score = submitted[i].Score
+		}
+		if err := sm.validateScore(code, termScore.Score); err != nil {
+			return false, scoreFieldError(err, termScore.Course)
+		}
+		if termScore.Makeup != nil {
+			if err := sm.validateMakeup(code, termScore.Score, *termScore.Makeup); err != nil {
+				return false, scoreFieldError(err, termScore.Course)
+			}
+		}
+		submitted[i].Course = code
+	}
+
+	// 检查学号是否已存在
+	studentID := student.GetID()
+	existing, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return false, err
+	}
+	if exists && !upsert {
+		return false, studentConflict(studentID)
+	}
+	if exists {
+		// 覆盖时不能修改已有的成绩，已有成绩只能通过需要填写原因的修改接口修改；重复提交相同的成绩不算修改
+		current := existing.GetBase()
+		for _, termScore := range submitted {
+			if i := current.findTermScore(termScore.Course, termScore.Term); i >= 0 && !current.TermScores[i].Equal(termScore) {
+				return false, scoreConflict(studentID, termScore.Course, termScore.Term)
+			}
+		}
+	}
+	if !exists || existing.GetClass() != class.ID {
+		if err := sm.checkCapacity(class, 1); err != nil {
+			return false, err
+		}
+	}
+
+	// 拷贝一份再保存，保留具体类型并记录类型名
+	record, err := cloneStudent(student)
+	if err != nil {
+		return false, err
+	}
+	base := record.GetBase()
+	base.Type = record.GetType()
+	base.Class = class.ID
+	base.TermScores = nil
+	if exists {
+		// 覆盖时合并成绩，避免丢失已有的课程成绩，新提交的成绩只会新增课程或学期
+		base.TermScores = existing.GetBase().TermScores
+		if base.Scores == nil {
+			base.Scores = make(map[string]float64)
+		}
+	}
+	for _, termScore := range submitted {
+		if i := base.findTermScore(termScore.Course, termScore.Term); i >= 0 {
+			base.TermScores[i] = termScore
+		} else {
+			base.TermScores = append(base.TermScores, termScore)
+		}
+	}
+	base.rebuildScores()
 
-	// 将学生信息添加到学生管理器的映射中，使用学生ID作为键
-	sm.students[studentID] = &Student{
-		Name:      student.GetName(),
-		StudentID: student.GetID(),
-		Gender:    student.GetGender(),
-		Class:     student.GetClass(),
+	// 将学生信息保存到存储中，使用学生ID作为键
+	if err := sm.store.Save(record); err != nil {
+		return false, err
 	}
+	return !exists, nil
 }
 
 // DeleteStudent 删除学生信息
 func (sm *StudentManager) DeleteStudent(studentID int) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	// 检查学生ID是否存在于映射中
-	if _, exists := sm.students[studentID]; exists {
-		// 如果存在，则从映射中删除该学生
-		delete(sm.students, studentID)
-		return nil
+	defer sm.operation(AuditDeleteStudent, "")()
+	// 检查学生ID是否存在于存储中
+	_, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		// 如果存在，则从存储中删除该学生
+		return sm.store.Delete(studentID)
 	}
 	// 如果不存在，返回错误信息
-	return fmt.Errorf("student with id %d not found", studentID)
+	return studentNotFound(studentID)
 }
 
 // ModifyStudent 修改学生信息
 func (sm *StudentManager) ModifyStudent(studentID int, updates map[string]interface{}) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
+	defer sm.operation(AuditModifyStudent, "")()
 
-	// 检查学生ID是否存在于映射中
-	if student, exists := sm.students[studentID]; exists {
+	// 检查学生ID是否存在于存储中
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		student := record.GetBase()
 		// 更新学生信息
 		if name, ok := updates["name"].(string); ok {
 			student.Name = name
@@ -164,203 +494,855 @@ func (sm *StudentManager) ModifyStudent(studentID int, updates map[string]interf
 		if gender, ok := updates["gender"].(string); ok {
 			student.Gender = gender
 		}
-		if class, ok := updates["class"].(string); ok {
-			student.Class = class
+		// 修改班级时校验班级是否存在及其容量，并记录调班
+		var moves []ClassMove
+		if name, ok := updates["class"].(string); ok {
+			class, err := sm.resolveClass(name)
+			if err != nil {
+				return err
+			}
+			if class.ID != student.Class {
+				if err := sm.checkCapacity(class, 1); err != nil {
+					return err
+				}
+				moves = append(moves, ClassMove{StudentID: studentID, From: student.Class, To: class.ID, Operation: ClassMoveModify, Time: time.Now().UTC()})
+			}
+			student.Class = class.ID
+		}
+		// 更新并校验类型特有的字段，校验失败时不保存
+		if updater, ok := record.(ProfileUpdater); ok {
+			if err := updater.UpdateProfile(updates); err != nil {
+				return err
+			}
+		}
+		if validator, ok := record.(StudentValidator); ok {
+			if err := validator.Validate(); err != nil {
+				return err
+			}
+		}
+		if err := sm.store.Save(record); err != nil {
+			return err
+		}
+		if len(moves) == 0 {
+			return nil
 		}
-		return nil
+		return sm.classes.AppendClassMoves(moves)
 	}
 
 	// 如果不存在，返回错误信息
-	return fmt.Errorf("student with id %d not found", studentID)
+	return studentNotFound(studentID)
 }
 
-// AddScore 为学生添加成绩
-func (sm *StudentManager) AddScore(studentID int, courseName string, score float64) error {
+// AddScore 为学生添加一门课程在一个学期的成绩，term 为空表示未指定学期
+// 注册了课程后只接受已注册的课程，courseName 可以是课程代码或名称（不区分大小写），成绩按课程代码保存。
+// 同一课程同一学期已有成绩时返回 ErrConflict，需通过 ModifyScore 填写原因后修改；不同学期的成绩分别保存
+func (sm *StudentManager) AddScore(studentID int, courseName, term string, score float64) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	// 检查学生ID是否存在于映射中
-	if student, exists := sm.students[studentID]; exists {
-		// 如果存在且学生的成绩记录为空，初始化
-		if student.Scores == nil {
-			student.Scores = make(map[string]float64)
+	defer sm.operation(AuditAddScore, "")()
+	return sm.addScore(studentID, courseName, term, score)
+}
+
+// addScore 校验并保存学生成绩，调用方需持有锁
+func (sm *StudentManager) addScore(studentID int, courseName, term string, score float64) error {
+	courseName, err := sm.resolveCourse(courseName)
+	if err != nil {
+		return err
+	}
+	if err := ValidateTerm(term); err != nil {
+		return err
+	}
+	// 按课程的记分规则校验成绩
+	if err := sm.validateScore(courseName, score); err != nil {
+		return err
+	}
+	// 检查学生ID是否存在于存储中
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		student := record.GetBase()
+		// 已有的成绩不能覆盖，避免绕过修改原因并丢失补考和组成部分成绩
+		if student.findTermScore(courseName, term) >= 0 {
+			return scoreConflict(studentID, courseName, term)
 		}
 		// 将课程分数添加到学生的成绩记录中
-		student.Scores[courseName] = score
-		return nil
+		student.TermScores = append(student.TermScores, TermScore{Course: courseName, Term: term, Score: score})
+		student.rebuildScores()
+		return sm.store.Save(record)
 	}
 	// 如果不存在，返回错误信息
-	return fmt.Errorf("student with id %d not found", studentID)
+	return studentNotFound(studentID)
 }
 
-// DeleteScore 删除学生成绩
-func (sm *StudentManager) DeleteScore(studentID int, courseName string) error {
+// DeleteScore 删除学生一门课程在一个学期的成绩（包括补考成绩），term 为空表示未指定学期的成绩
+func (sm *StudentManager) DeleteScore(studentID int, courseName, term string) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	// 检查学生ID是否存在于映射中
-	if student, exists := sm.students[studentID]; exists {
+	defer sm.operation(AuditDeleteScore, "")()
+	courseName, err := sm.lookupCourse(courseName)
+	if err != nil {
+		return err
+	}
+	// 检查学生ID是否存在于存储中
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		student := record.GetBase()
 		// 检查学生是否有指定课程的成绩记录
-		if _, exists := student.Scores[courseName]; exists {
+		if i := student.findTermScore(courseName, term); i >= 0 {
 			// 如果课程成绩存在，删除课程成绩记录
-			delete(student.Scores, courseName)
-			return nil
+			student.TermScores = append(student.TermScores[:i], student.TermScores[i+1:]...)
+			student.rebuildScores()
+			return sm.store.Save(record)
 		}
 		// 如果课程成绩不存在，返回错误信息
-		return fmt.Errorf("score for course %s not found for student with id %d", courseName, studentID)
+		return scoreNotFound(studentID, courseName, term)
 	}
 	// 如果学生不存在，返回错误信息
-	return fmt.Errorf("student with id %d not found", studentID)
+	return studentNotFound(studentID)
 }
 
-// ModifyScore 修改学生成绩
-func (sm *StudentManager) ModifyScore(studentID int, courseName string, score float64) error {
+// ModifyScore 修改学生一门课程在一个学期的成绩，补考成绩保持不变，reason 为必填的修改原因，记录在成绩历史中
+// 按组成部分计算的总评成绩不能直接修改，返回 ValidationError
+func (sm *StudentManager) ModifyScore(studentID int, courseName, term string, score float64, reason string) error {
+	reason, err := requireReason(reason)
+	if err != nil {
+		return err
+	}
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	// 检查学生ID是否存在于映射中
-	if student, exists := sm.students[studentID]; exists {
+	defer sm.operation(AuditModifyScore, reason)()
+	courseName, err = sm.lookupCourse(courseName)
+	if err != nil {
+		return err
+	}
+	// 按课程的记分规则校验成绩
+	if err := sm.validateScore(courseName, score); err != nil {
+		return err
+	}
+	// 检查学生ID是否存在于存储中
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		student := record.GetBase()
 		// 检查学生是否有指定课程的成绩记录
-		if _, exists := student.Scores[courseName]; exists {
+		if i := student.findTermScore(courseName, term); i >= 0 {
+			// 按组成部分计算的总评成绩只能通过修改组成部分来修改
+			if len(student.TermScores[i].Components) > 0 {
+				return &ValidationError{Field: "score", Rule: "computed", Message: fmt.Sprintf("score of course %s is computed from its components, modify a component instead", courseName)}
+			}
 			// 如果课程成绩存在，更新课程成绩
-			student.Scores[courseName] = score
-			return nil
+			student.TermScores[i].Score = score
+			student.rebuildScores()
+			return sm.store.Save(record)
 		}
 		// 如果课程成绩不存在，返回错误信息
-		return fmt.Errorf("score for course %s not found for student with id %d", courseName, studentID)
+		return scoreNotFound(studentID, courseName, term)
 	}
 	// 如果学生不存在，返回错误信息
-	return fmt.Errorf("student with id %d not found", studentID)
+	return studentNotFound(studentID)
 }
 
 // QueryStudent 查询学生信息
 func (sm *StudentManager) QueryStudent(studentID int) (*Student, error) {
+	student, err := sm.QueryStudentDetail(studentID)
+	if err != nil {
+		return nil, err
+	}
+	// 返回公共学生信息，其中 Type 字段记录了学生类型
+	return student.GetBase(), nil
+}
+
+// QueryStudentDetail 查询学生信息，返回保存时的具体类型（本科生、研究生等）
+func (sm *StudentManager) QueryStudentDetail(studentID int) (StudentInterface, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	// 检查学生ID是否存在于映射中
-	if student, exists := sm.students[studentID]; exists {
+	// 检查学生ID是否存在于存储中
+	student, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
 		// 如果存在，返回学生信息
 		return student, nil
 	}
 	// 如果不存在，返回错误信息
-	return nil, fmt.Errorf("student with id %d not found", studentID)
+	return nil, studentNotFound(studentID)
+}
+
+// QueryStudentsByType 按学号升序查询指定类型的全部学生
+func (sm *StudentManager) QueryStudentsByType(kind string) ([]StudentInterface, error) {
+	if _, err := NewStudentOfType(kind); err != nil {
+		return nil, err
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	students, err := sm.store.List()
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]StudentInterface, 0, len(students))
+	for _, student := range students {
+		if student.GetType() == kind {
+			matched = append(matched, student)
+		}
+	}
+	return matched, nil
+}
+
+// QueryScore 查询学生一门课程在一个学期的有效成绩，参加了补考时返回补考成绩
+func (sm *StudentManager) QueryScore(studentID int, courseName, term string) (float64, error) {
+	termScore, err := sm.QueryTermScore(studentID, courseName, term)
+	if err != nil {
+		return 0, err
+	}
+	return termScore.Effective(), nil
 }
 
-// QueryScore 查询学生成绩
-func (sm *StudentManager) QueryScore(studentID int, courseName string) (float64, error) {
+// QueryTermScore 查询学生一门课程在一个学期的成绩记录，term 为空表示未指定学期的成绩
+func (sm *StudentManager) QueryTermScore(studentID int, courseName, term string) (*TermScore, error) {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	// 检查学生ID是否存在于映射中
-	if student, exists := sm.students[studentID]; exists {
+	courseName, err := sm.lookupCourse(courseName)
+	if err != nil {
+		return nil, err
+	}
+	// 检查学生ID是否存在于存储中
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		student := record.GetBase()
 		// 检查课程成绩是否存在
-		if score, exists := student.Scores[courseName]; exists {
+		if i := student.findTermScore(courseName, term); i >= 0 {
 			// 如果课程成绩存在，返回课程成绩
-			return score, nil
+			termScore := student.TermScores[i]
+			return &termScore, nil
 		}
 		// 如果课程成绩不存在，返回错误信息
-		return 0, fmt.Errorf("score for course %s not found for student with id %d", courseName, studentID)
+		return nil, scoreNotFound(studentID, courseName, term)
 	}
 	// 如果学生不存在，返回错误信息
-	return 0, fmt.Errorf("student with id %d not found", studentID)
+	return nil, studentNotFound(studentID)
+}
+
+// RecordMakeup 录入或修改学生一门课程在一个学期的补考成绩，只有该学期成绩不及格时才能补考
+// 已有补考成绩时视为修改，reason 为必填的修改原因
+func (sm *StudentManager) RecordMakeup(studentID int, courseName, term string, score float64, reason string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	defer sm.operation(AuditRecordMakeup, strings.TrimSpace(reason))()
+	return sm.recordMakeup(studentID, courseName, term, score, reason)
+}
+
+// recordMakeup 校验并保存补考成绩，调用方需持有锁
+func (sm *StudentManager) recordMakeup(studentID int, courseName, term string, score float64, reason string) error {
+	courseName, err := sm.lookupCourse(courseName)
+	if err != nil {
+		return err
+	}
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return studentNotFound(studentID)
+	}
+	student := record.GetBase()
+	i := student.findTermScore(courseName, term)
+	if i < 0 {
+		return scoreNotFound(studentID, courseName, term)
+	}
+	if student.TermScores[i].Makeup != nil {
+		if _, err := requireReason(reason); err != nil {
+			return err
+		}
+	}
+	if err := sm.validateMakeup(courseName, student.TermScores[i].Score, score); err != nil {
+		return err
+	}
+	student.TermScores[i].Makeup = &score
+	student.rebuildScores()
+	return sm.store.Save(record)
+}
+
+// validateMakeup 校验补考成绩，原成绩及格时不能补考，调用方需持有锁
+func (sm *StudentManager) validateMakeup(courseName string, original, makeup float64) error {
+	if err := sm.validateScore(courseName, makeup); err != nil {
+		return makeupFieldError(err)
+	}
+	scale, err := sm.grading.ScaleFor(courseName)
+	if err != nil {
+		return err
+	}
+	if scale.Passed(original) {
+		return &ValidationError{Field: "makeup", Rule: "failed", Message: "makeup exam is only allowed when the score is failed"}
+	}
+	return nil
+}
+
+// respondError 按错误类型写入错误响应，校验错误附带出错的字段和违反的规则
+func respondError(c *gin.Context, err error) {
+	body := gin.H{"error": err.Error()}
+	var validationErr *ValidationError
+	if errors.As(err, &validationErr) {
+		body["field"] = validationErr.Field
+		body["message"] = validationErr.Message
+		if validationErr.Rule != "" {
+			body["rule"] = validationErr.Rule
+		}
+	}
+	c.JSON(statusForError(err), body)
+}
+
+// abortConnection 关闭已开始写入响应的连接，客户端读到不完整的响应，而不是看起来正常结束的截断文件
+func abortConnection(c *gin.Context) {
+	c.Abort()
+	hijacker, ok := c.Writer.(interface{ Unwrap() http.ResponseWriter }).Unwrap().(http.Hijacker)
+	if !ok {
+		return
+	}
+	if conn, _, err := hijacker.Hijack(); err == nil {
+		conn.Close()
+	}
+}
+
+// statusForError 根据错误类型选择 HTTP 状态码
+func statusForError(err error) int {
+	var validationErr *ValidationError
+	if errors.Is(err, ErrNotFound) {
+		return http.StatusNotFound
+	}
+	if errors.Is(err, ErrConflict) || errors.Is(err, ErrInUse) {
+		return http.StatusConflict
+	}
+	if errors.Is(err, ErrUnauthorized) {
+		return http.StatusUnauthorized
+	}
+	if errors.Is(err, ErrForbidden) {
+		return http.StatusForbidden
+	}
+	if errors.As(err, &validationErr) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
+}
+
+// addStudent 新增学生并写入响应
+// 查询参数 upsert=true 时允许覆盖已存在的学生，覆盖成功返回 200，新增成功返回 201
+func addStudent(c *gin.Context, sm *StudentManager, student StudentInterface, label string) {
+	upsert, err := strconv.ParseBool(c.DefaultQuery("upsert", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upsert flag"})
+		return
+	}
+
+	created := true
+	if upsert {
+		created, err = sm.As(requestActor(c)).UpsertStudent(student)
+	} else {
+		err = sm.As(requestActor(c)).AddStudent(student)
+	}
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, gin.H{"message": label + " updated successfully"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"message": label + " added successfully"})
+}
+
+// scoreRequest 增加或修改成绩的请求体
+// 数值型记分制提交 score，等级制和通过制可以改为提交 grade（如 A-、P）；
+// term 为学期，makeup 为 true 时录入的是该学期的补考成绩；
+// component 不为空时录入的是该组成部分（如 homework、final）的成绩，总评成绩按课程的权重计算
+type scoreRequest struct {
+	CourseName string   `json:"course_name"`
+	Term       string   `json:"term"`
+	Score      *float64 `json:"score"`
+	Grade      string   `json:"grade"`
+	Makeup     bool     `json:"makeup"`
+	Component  string   `json:"component"`
+	Reason     string   `json:"reason"` // 修改成绩时必填
+}
+
+// value 返回请求中的成绩数值，提交等级时按课程记分制换算
+func (r scoreRequest) value(sm *StudentManager) (float64, error) {
+	if r.Grade != "" {
+		return sm.ParseGrade(r.CourseName, r.Grade)
+	}
+	if r.Score == nil {
+		return 0, &ValidationError{Field: "score", Rule: "required", Message: "score or grade is required"}
+	}
+	return *r.Score, nil
+}
+
+// check 校验请求中互斥的字段
+func (r scoreRequest) check() error {
+	if r.Component != "" && r.Makeup {
+		return &ValidationError{Field: "component", Rule: "makeup", Message: "makeup scores cannot be recorded for a component"}
+	}
+	return nil
+}
+
+// openStore 根据存储类型创建对应的存储实现
+func openStore(kind, path string) (StudentStore, error) {
+	switch kind {
+	case "memory":
+		return NewMemoryStore(), nil
+	case "file":
+		return NewFileStore(path)
+	case "sqlite":
+		return NewSQLiteStore(path)
+	default:
+		return nil, fmt.Errorf("unknown store type %q", kind)
+	}
 }
 
 func main() {
+	storeKind := flag.String("store", "file", "storage backend: memory, file or sqlite")
+	dataPath := flag.String("data", "students.json", "data file used by the file or sqlite store")
+	gradingPath := flag.String("grading", "", "JSON file with the grading scale of each course, defaults to the 100-point scale")
+	adminUser := flag.String("admin", "admin", "initial user created when no users exist, its password is read from SMS_ADMIN_PASSWORD or generated")
+	adminPasswordFile := flag.String("admin-password-file", "admin_password.txt", "file with mode 0600 the generated password of the initial user is written to")
+	accessTTL := flag.Duration("access-ttl", DefaultAccessTTL, "lifetime of access tokens")
+	refreshTTL := flag.Duration("refresh-ttl", DefaultRefreshTTL, "lifetime of refresh tokens")
+	flag.Parse()
+
+	// 创建存储，重启后数据不会丢失
+	store, err := openStore(*storeKind, *dataPath)
+	if err != nil {
+		log.Fatalf("open store: %v", err)
+	}
+
 	// 创建 Gin 引擎
 	r := gin.Default()
+	// 每个请求分配请求编号，记录在审计日志中并在 X-Request-ID 响应头中返回
+	r.Use(RequestID())
 	// 创建学生管理器
-	sm := NewStudentManager()
+	sm := NewStudentManagerWithStore(store)
+	defer sm.Close()
+	if *gradingPath != "" {
+		policy, err := LoadGradingPolicy(*gradingPath)
+		if err != nil {
+			log.Fatalf("load grading policy: %v", err)
+		}
+		sm.SetGradingPolicy(policy)
+	}
+	// 后台导入任务
+	imports := NewImportJobs(sm)
 
-	// 增加本科生信息
-	r.POST("/undergraduates", func(c *gin.Context) {
-		var undergraduate Undergraduate
-		if err := c.ShouldBindJSON(&undergraduate); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
+	// 令牌签名密钥从 SMS_JWT_SECRET 读取，未设置时随机生成，重启后之前签发的令牌全部失效
+	secret := []byte(os.Getenv("SMS_JWT_SECRET"))
+	if len(secret) == 0 {
+		key, err := randomToken(32)
+		if err != nil {
+			log.Fatalf("generate jwt secret: %v", err)
 		}
-		sm.AddStudent(&undergraduate)
-		c.JSON(http.StatusCreated, gin.H{"message": "Undergraduate added successfully"})
-	})
+		secret = []byte(key)
+		log.Printf("SMS_JWT_SECRET is not set, tokens will be invalid after restart")
+	}
+	auth := NewAuthenticator(sm, secret)
+	auth.AccessTTL = *accessTTL
+	auth.RefreshTTL = *refreshTTL
+	created, err := bootstrapUser(sm, *adminUser, os.Getenv("SMS_ADMIN_PASSWORD"), *adminPasswordFile)
+	if err != nil {
+		log.Fatalf("create initial user: %v", err)
+	}
+	if created && os.Getenv("SMS_ADMIN_PASSWORD") == "" {
+		log.Printf("created user %s, its generated password is in %s, change it with PUT /users/%s/password and delete the file", *adminUser, *adminPasswordFile, *adminUser)
+	}
 
-	// 增加研究生信息
-	r.POST("/graduates", func(c *gin.Context) {
-		var graduate Graduate
-		if err := c.ShouldBindJSON(&graduate); err != nil {
+	// 登录，用户名和密码正确时签发访问令牌和刷新令牌
+	r.POST("/auth/login", func(c *gin.Context) {
+		var credentials struct {
+			Username string `json:"username" binding:"required"`
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&credentials); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		sm.AddStudent(&graduate)
-		c.JSON(http.StatusCreated, gin.H{"message": "Graduate added successfully"})
-	})
-
-	// 删除学生信息
-	r.DELETE("/students/:id", func(c *gin.Context) {
-		studentID, err := strconv.Atoi(c.Param("id"))
+		tokens, err := auth.Login(credentials.Username, credentials.Password)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+			respondError(c, err)
 			return
 		}
-		if err := sm.DeleteStudent(studentID); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"message": "Student deleted successfully"})
+		c.JSON(http.StatusOK, tokens)
 	})
 
-	// 修改学生信息
-	r.PUT("/students/:id", func(c *gin.Context) {
-		studentID, err := strconv.Atoi(c.Param("id"))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
-			return
+	// 用刷新令牌换取新的令牌，旧的刷新令牌随即失效
+	r.POST("/auth/refresh", func(c *gin.Context) {
+		var request struct {
+			RefreshToken string `json:"refresh_token" binding:"required"`
 		}
-
-		var updates map[string]interface{}
-		if err := c.ShouldBindJSON(&updates); err != nil {
+		if err := c.ShouldBindJSON(&request); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-
-		if err := sm.ModifyStudent(studentID, updates); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		tokens, err := auth.Refresh(request.RefreshToken)
+		if err != nil {
+			respondError(c, err)
 			return
 		}
-
-		c.JSON(http.StatusOK, gin.H{"message": "Student modified successfully"})
+		c.JSON(http.StatusOK, tokens)
 	})
 
-	// 增加学生成绩
-	r.POST("/students/:id/scores", func(c *gin.Context) {
-		studentID, err := strconv.Atoi(c.Param("id"))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
-			return
-		}
-		var scoreData struct {
-			CourseName string  `json:"course_name"`
-			Score      float64 `json:"score"`
+	// 之后注册的路由都需要在 Authorization 请求头中提供有效的访问令牌，并按 accessPolicy 检查用户角色
+	r.Use(auth.Middleware(), Authorize(sm))
+
+	// 吊销令牌，请求体中没有 token 时吊销当前请求使用的访问令牌（退出登录）
+	r.POST("/auth/revoke", func(c *gin.Context) {
+		var request struct {
+			Token string `json:"token"`
 		}
-		if err := c.ShouldBindJSON(&scoreData); err != nil {
+		if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		if err := sm.AddScore(studentID, scoreData.CourseName, scoreData.Score); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if request.Token == "" {
+			request.Token, _ = bearerToken(c)
+		}
+		if err := auth.Revoke(request.Token); err != nil {
+			respondError(c, err)
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"message": "Score added successfully"})
+		c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
 	})
 
-	// 删除学生成绩
-	r.DELETE("/students/:id/scores/:course", func(c *gin.Context) {
-		studentID, err := strconv.Atoi(c.Param("id"))
+	// 查询当前用户
+	r.GET("/auth/me", func(c *gin.Context) {
+		c.JSON(http.StatusOK, CurrentUser(c))
+	})
+
+	// 创建用户，学生用户需要提供对应的学号
+	r.POST("/users", func(c *gin.Context) {
+		var request struct {
+			Username  string `json:"username" binding:"required"`
+			Password  string `json:"password" binding:"required"`
+			Role      string `json:"role" binding:"required"`
+			StudentID int    `json:"student_id"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, err := sm.AddUser(User{Username: request.Username, Role: request.Role, StudentID: request.StudentID}, request.Password)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, user)
+	})
+
+	// 查询全部用户
+	r.GET("/users", func(c *gin.Context) {
+		users, err := sm.ListUsers()
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"users": users})
+	})
+
+	// 修改用户的角色，立即生效
+	r.PUT("/users/:username", func(c *gin.Context) {
+		var request struct {
+			Role      string `json:"role" binding:"required"`
+			StudentID int    `json:"student_id"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		user, err := sm.ModifyUser(c.Param("username"), User{Role: request.Role, StudentID: request.StudentID})
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, user)
+	})
+
+	// 修改密码，之前签发给该用户的令牌全部失效，教师和学生只能修改自己的密码
+	r.PUT("/users/:username/password", func(c *gin.Context) {
+		var request struct {
+			Password string `json:"password" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := sm.SetPassword(c.Param("username"), request.Password); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+	})
+
+	// 删除用户
+	r.DELETE("/users/:username", func(c *gin.Context) {
+		if err := sm.DeleteUser(c.Param("username")); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	})
+
+	// 创建 API 密钥，完整的密钥只在响应中返回一次
+	r.POST("/api-keys", func(c *gin.Context) {
+		var request struct {
+			Name      string     `json:"name" binding:"required"`
+			Scopes    []string   `json:"scopes" binding:"required"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		key, secret, err := sm.CreateAPIKey(request.Name, request.Scopes, request.ExpiresAt, CurrentUser(c).Username)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"api_key": key, "key": secret})
+	})
+
+	// 查询全部 API 密钥
+	r.GET("/api-keys", func(c *gin.Context) {
+		keys, err := sm.ListAPIKeys()
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+	})
+
+	// 查询 API 密钥，包括最近使用时间
+	r.GET("/api-keys/:id", func(c *gin.Context) {
+		key, err := sm.QueryAPIKey(c.Param("id"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, key)
+	})
+
+	// 轮换 API 密钥，旧的密钥立即失效
+	r.POST("/api-keys/:id/rotate", func(c *gin.Context) {
+		key, secret, err := sm.RotateAPIKey(c.Param("id"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"api_key": key, "key": secret})
+	})
+
+	// 删除 API 密钥
+	r.DELETE("/api-keys/:id", func(c *gin.Context) {
+		if err := sm.DeleteAPIKey(c.Param("id")); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "API key deleted successfully"})
+	})
+
+	// 查询审计日志，最新的记录在前，支持按操作者、操作、学号、请求编号和时间范围筛选
+	r.GET("/audit", func(c *gin.Context) {
+		query := AuditQuery{
+			Actor:     c.Query("actor"),
+			Action:    c.Query("action"),
+			RequestID: c.Query("request_id"),
+		}
+		var err error
+		if id := c.Query("student_id"); id != "" {
+			if query.StudentID, err = strconv.Atoi(id); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+				return
+			}
+		}
+		for name, t := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+			if value := c.Query(name); value != "" {
+				if *t, err = time.Parse(time.RFC3339, value); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s, must be an RFC 3339 time", name)})
+					return
+				}
+			}
+		}
+		if query.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+		if query.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit))); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+
+		entries, total, err := sm.QueryAudit(query)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"total":   total,
+			"offset":  query.Offset,
+			"limit":   query.Limit,
+			"entries": entries,
+		})
+	})
+
+	// 查询记分制和各课程的记分规则
+	r.GET("/grading", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"policy": sm.GradingPolicy(),
+			"scales": GradingScales(),
+		})
+	})
+
+	// 增加本科生信息
+	r.POST("/undergraduates", func(c *gin.Context) {
+		var undergraduate Undergraduate
+		if err := c.ShouldBindJSON(&undergraduate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		addStudent(c, sm, &undergraduate, "Undergraduate")
+	})
+
+	// 增加研究生信息
+	r.POST("/graduates", func(c *gin.Context) {
+		var graduate Graduate
+		if err := c.ShouldBindJSON(&graduate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		addStudent(c, sm, &graduate, "Graduate")
+	})
+
+	// 按 type 字段增加任意已注册类型的学生信息
+	r.POST("/students", func(c *gin.Context) {
+		body, err := c.GetRawData()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(body, &header); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if header.Type == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Student type is required"})
+			return
+		}
+		student, err := decodeStudentOfType(header.Type, body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		addStudent(c, sm, student, "Student")
+	})
+
+	// 删除学生信息
+	r.DELETE("/students/:id", func(c *gin.Context) {
+		studentID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+			return
+		}
+		if err := sm.As(requestActor(c)).DeleteStudent(studentID); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Student deleted successfully"})
+	})
+
+	// 修改学生信息
+	r.PUT("/students/:id", func(c *gin.Context) {
+		studentID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+			return
+		}
+
+		var updates map[string]interface{}
+		if err := c.ShouldBindJSON(&updates); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := sm.As(requestActor(c)).ModifyStudent(studentID, updates); err != nil {
+			respondError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"message": "Student modified successfully"})
+	})
+
+	// 增加学生成绩
+	r.POST("/students/:id/scores", func(c *gin.Context) {
+		studentID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+			return
+		}
+		var scoreData scoreRequest
+		if err := c.ShouldBindJSON(&scoreData); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := scoreData.check(); err != nil {
+			respondError(c, err)
+			return
+		}
+		score, err := scoreData.value(sm)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		editor := sm.As(requestActor(c))
+		switch {
+		case scoreData.Component != "":
+			err = editor.AddScoreComponent(studentID, scoreData.CourseName, scoreData.Term, scoreData.Component, score, scoreData.Reason)
+		case scoreData.Makeup:
+			err = editor.RecordMakeup(studentID, scoreData.CourseName, scoreData.Term, score, scoreData.Reason)
+		default:
+			err = editor.AddScore(studentID, scoreData.CourseName, scoreData.Term, score)
+		}
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Score added successfully"})
+	})
+
+	// 删除学生成绩，路径中没有学期时删除未指定学期的成绩
+	deleteScore := func(c *gin.Context) {
+		studentID, err := strconv.Atoi(c.Param("id"))
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
 			return
 		}
 		courseName := c.Param("course")
-		if err := sm.DeleteScore(studentID, courseName); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if err := sm.As(requestActor(c)).DeleteScore(studentID, courseName, c.Param("term")); err != nil {
+			respondError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "Score deleted successfully"})
-	})
+	}
+	r.DELETE("/students/:id/scores/:course", deleteScore)
+	r.DELETE("/students/:id/scores/:course/:term", deleteScore)
 
 	// 修改学生成绩
 	r.PUT("/students/:id/scores", func(c *gin.Context) {
@@ -369,21 +1351,76 @@ func main() {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
 			return
 		}
-		var scoreData struct {
-			CourseName string  `json:"course_name"`
-			Score      float64 `json:"score"`
-		}
+		var scoreData scoreRequest
 		if err := c.ShouldBindJSON(&scoreData); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		if err := sm.ModifyScore(studentID, scoreData.CourseName, scoreData.Score); err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		if err := scoreData.check(); err != nil {
+			respondError(c, err)
+			return
+		}
+		score, err := scoreData.value(sm)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		editor := sm.As(requestActor(c))
+		switch {
+		case scoreData.Component != "":
+			err = editor.ModifyScoreComponent(studentID, scoreData.CourseName, scoreData.Term, scoreData.Component, score, scoreData.Reason)
+		case scoreData.Makeup:
+			err = editor.RecordMakeup(studentID, scoreData.CourseName, scoreData.Term, score, scoreData.Reason)
+		default:
+			err = editor.ModifyScore(studentID, scoreData.CourseName, scoreData.Term, score, scoreData.Reason)
+		}
+		if err != nil {
+			respondError(c, err)
 			return
 		}
 		c.JSON(http.StatusOK, gin.H{"message": "Score modified successfully"})
 	})
 
+	// 查询学生列表，支持筛选、排序和分页
+	r.GET("/students", func(c *gin.Context) {
+		query := StudentQuery{
+			Class:  c.Query("class"),
+			Gender: c.Query("gender"),
+			Type:   c.Query("type"),
+			Name:   c.Query("name"),
+			SortBy: c.Query("sort"),
+		}
+		switch c.DefaultQuery("order", "asc") {
+		case "asc":
+		case "desc":
+			query.Desc = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order, must be asc or desc"})
+			return
+		}
+		var err error
+		if query.Offset, err = strconv.Atoi(c.DefaultQuery("offset", "0")); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset"})
+			return
+		}
+		if query.Limit, err = strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultPageLimit))); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+
+		students, total, err := sm.ListStudents(query)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"total":    total,
+			"offset":   query.Offset,
+			"limit":    query.Limit,
+			"students": students,
+		})
+	})
+
 	// 查询学生信息
 	r.GET("/students/:id", func(c *gin.Context) {
 		// 获取路径参数 "id"
@@ -400,10 +1437,10 @@ func main() {
 			return
 		}
 
-		// 查询学生信息
-		student, err := sm.QueryStudent(studentID)
+		// 查询学生信息，保留本科生、研究生等具体类型
+		student, err := sm.QueryStudentDetail(studentID)
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respondError(c, err)
 			return
 		}
 
@@ -411,8 +1448,23 @@ func main() {
 		c.JSON(http.StatusOK, student)
 	})
 
-	// 查询学生成绩
-	r.GET("/students/:id/scores/:course", func(c *gin.Context) {
+	// 查询学生的学分、加权平均分和绩点
+	r.GET("/students/:id/gpa", func(c *gin.Context) {
+		studentID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+			return
+		}
+		result, err := sm.ComputeGPA(studentID, c.Query("formula"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	})
+
+	// 查询学生成绩，路径中没有学期时查询未指定学期的成绩
+	queryScore := func(c *gin.Context) {
 		// 获取路径参数 "id"
 		studentIDStr := c.Param("id")
 		if studentIDStr == "" {
@@ -435,92 +1487,419 @@ func main() {
 		}
 
 		// 查询学生成绩
-		score, err := sm.QueryScore(studentID, courseName)
+		termScore, err := sm.QueryTermScore(studentID, courseName, c.Param("term"))
 		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			respondError(c, err)
 			return
 		}
 
-		// 返回学生成绩
-		c.JSON(http.StatusOK, gin.H{"score": score})
+		// 返回有效成绩，参加了补考时同时返回原成绩和补考成绩，按组成部分计算时同时返回各组成部分的成绩；等级制和通过制课程同时返回等级
+		score := termScore.Effective()
+		response := gin.H{"course": termScore.Course, "score": score}
+		if termScore.Term != "" {
+			response["term"] = termScore.Term
+		}
+		if termScore.Makeup != nil {
+			response["original"] = termScore.Score
+			response["makeup"] = *termScore.Makeup
+		}
+		if len(termScore.Components) > 0 {
+			response["components"] = termScore.Components
+		}
+		if grade := sm.GradeOf(courseName, score); grade != "" {
+			response["grade"] = grade
+		}
+		c.JSON(http.StatusOK, response)
+	}
+	r.GET("/students/:id/scores/:course", queryScore)
+	r.GET("/students/:id/scores/:course/:term", queryScore)
+
+	// 查询学生一门课程的成绩历史，term 参数只返回该学期的变化（空值表示未指定学期）
+	r.GET("/students/:id/scores/:course/history", func(c *gin.Context) {
+		studentID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+			return
+		}
+		history, err := sm.ScoreHistory(studentID, c.Param("course"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if term, ok := c.GetQuery("term"); ok {
+			filtered := []ScoreChange{}
+			for _, change := range history {
+				if change.Term == term {
+					filtered = append(filtered, change)
+				}
+			}
+			history = filtered
+		}
+		c.JSON(http.StatusOK, history)
+	})
+
+	// 把学生一门课程在一个学期的成绩恢复为成绩历史中某次修改之后的值，用于成绩复核
+	r.POST("/students/:id/scores/:course/revert", func(c *gin.Context) {
+		studentID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+			return
+		}
+		var request struct {
+			Seq    int64  `json:"seq" binding:"required"`
+			Term   string `json:"term"`
+			Reason string `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if err := sm.As(requestActor(c)).RevertScore(studentID, c.Param("course"), request.Term, request.Seq, request.Reason); err != nil {
+			respondError(c, err)
+			return
+		}
+		termScore, err := sm.QueryTermScore(studentID, c.Param("course"), request.Term)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, termScore)
 	})
 
-	// 并发导入 CSV 数据
+	// 查询按学期排列的成绩单，包括重修和补考
+	r.GET("/students/:id/transcript", func(c *gin.Context) {
+		studentID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid student id"})
+			return
+		}
+		transcript, err := sm.Transcript(studentID)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, transcript)
+	})
+
+	// 班级排名，by 可选 total、average 或 course（需同时指定 course），method 可选 dense 或 competition
+	r.GET("/classes/:class/rankings", func(c *gin.Context) {
+		opts := RankingOptions{By: c.Query("by"), Course: c.Query("course"), Method: c.Query("method")}
+		ranking, err := sm.RankClass(c.Param("class"), opts)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, ranking)
+	})
+
+	// 创建班级
+	r.POST("/classes", func(c *gin.Context) {
+		var class Class
+		if err := c.ShouldBindJSON(&class); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		created, err := sm.AddClass(class)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, created)
+	})
+
+	// 查询全部班级
+	r.GET("/classes", func(c *gin.Context) {
+		classes, err := sm.ListClasses()
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"classes": classes})
+	})
+
+	// 按班级编号或名称查询班级
+	r.GET("/classes/:class", func(c *gin.Context) {
+		class, err := sm.QueryClass(c.Param("class"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, class)
+	})
+
+	// 修改班级信息，班级编号不能修改
+	r.PUT("/classes/:class", func(c *gin.Context) {
+		var class Class
+		if err := c.ShouldBindJSON(&class); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		modified, err := sm.ModifyClass(c.Param("class"), class)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, modified)
+	})
+
+	// 删除班级，班级中仍有学生时返回 409
+	r.DELETE("/classes/:class", func(c *gin.Context) {
+		if err := sm.DeleteClass(c.Param("class")); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Class deleted successfully"})
+	})
+
+	// 班级花名册
+	r.GET("/classes/:class/roster", func(c *gin.Context) {
+		roster, err := sm.ClassRoster(c.Param("class"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"students": roster, "total": len(roster)})
+	})
+
+	// 调入或调出班级的记录
+	r.GET("/classes/:class/moves", func(c *gin.Context) {
+		moves, err := sm.ClassMoves(c.Param("class"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"moves": moves})
+	})
+
+	// 把学生调到另一个班级
+	r.POST("/classes/:class/moves", func(c *gin.Context) {
+		var request classMoveRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		moves, err := sm.As(requestActor(c)).MoveStudents(c.Param("class"), request.To, request.StudentIDs, request.Reason)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"moves": moves})
+	})
+
+	// 把班级并入另一个班级，原班级删除
+	r.POST("/classes/:class/merge", func(c *gin.Context) {
+		var request classMergeRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		moves, err := sm.As(requestActor(c)).MergeClasses(c.Param("class"), request.Into, request.Reason)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"moves": moves})
+	})
+
+	// 从班级中拆分出新班级
+	r.POST("/classes/:class/split", func(c *gin.Context) {
+		var request classSplitRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		class, moves, err := sm.As(requestActor(c)).SplitClass(c.Param("class"), request.Class, request.StudentIDs, request.Reason)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"class": class, "moves": moves})
+	})
+
+	// 注册课程
+	r.POST("/courses", func(c *gin.Context) {
+		var course Course
+		if err := c.ShouldBindJSON(&course); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		created, err := sm.AddCourse(course)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, created)
+	})
+
+	// 查询全部课程
+	r.GET("/courses", func(c *gin.Context) {
+		courses, err := sm.ListCourses()
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"courses": courses})
+	})
+
+	// 按课程代码或名称查询课程
+	r.GET("/courses/:course", func(c *gin.Context) {
+		course, err := sm.QueryCourse(c.Param("course"))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, course)
+	})
+
+	// 修改课程信息，课程代码不能修改；修改权重时必须填写 reason
+	r.PUT("/courses/:course", func(c *gin.Context) {
+		var request struct {
+			Course
+			Reason string `json:"reason"`
+		}
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		modified, err := sm.As(requestActor(c)).ModifyCourse(c.Param("course"), request.Course, request.Reason)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, modified)
+	})
+
+	// 删除课程，仍有成绩的课程不能删除
+	r.DELETE("/courses/:course", func(c *gin.Context) {
+		if err := sm.DeleteCourse(c.Param("course")); err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Course deleted successfully"})
+	})
+
+	// 课程成绩统计，可按班级和学生类型筛选；buckets 为逗号分隔的分段边界，bucket_width 为分段宽度
+	r.GET("/courses/:course/stats", func(c *gin.Context) {
+		opts := CourseStatsOptions{Class: c.Query("class"), Type: c.Query("type")}
+		if buckets := c.Query("buckets"); buckets != "" {
+			for _, edge := range strings.Split(buckets, ",") {
+				value, err := strconv.ParseFloat(strings.TrimSpace(edge), 64)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid buckets"})
+					return
+				}
+				opts.Buckets = append(opts.Buckets, value)
+			}
+		}
+		if width := c.Query("bucket_width"); width != "" {
+			var err error
+			if opts.BucketWidth, err = strconv.ParseFloat(width, 64); err != nil || opts.BucketWidth <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid bucket_width"})
+				return
+			}
+		}
+
+		stats, err := sm.CourseStats(c.Param("course"), opts)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, stats)
+	})
+
+	// 导出学生和成绩，format 可选 csv（默认，可直接导入）、jsonl 或 xlsx，筛选条件与学生列表相同
+	r.GET("/export", func(c *gin.Context) {
+		format := c.DefaultQuery("format", ExportCSV)
+		contentType, err := ExportContentType(format)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		query := StudentQuery{
+			Class:  c.Query("class"),
+			Gender: c.Query("gender"),
+			Type:   c.Query("type"),
+			Name:   c.Query("name"),
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Disposition", `attachment; filename="students.`+format+`"`)
+		c.Status(http.StatusOK)
+		if err := sm.ExportStudents(c.Writer, format, query); err != nil {
+			log.Printf("export students: %v", err)
+			// 还没有写入数据时（例如 XLSX 在全部生成后才写入）返回错误，否则中断连接让客户端发现导出不完整
+			if !c.Writer.Written() {
+				c.Writer.Header().Del("Content-Type")
+				c.Writer.Header().Del("Content-Disposition")
+				respondError(c, err)
+				return
+			}
+			abortConnection(c)
+		}
+	})
+
+	// 后台导入 CSV 或 XLSX 数据，第一行可以是表头，立即返回导入任务
+	// 查询参数 upsert=true 覆盖已存在的学生，dry_run=true 只校验不保存，atomic=true 任何一行失败时整批回滚，
+	// format 指定文件格式（默认按文件名和文件内容识别），sheet 指定 XLSX 中要导入的工作表
 	r.POST("/import", func(c *gin.Context) {
+		opts := ImportOptions{Format: c.Query("format"), Sheet: c.Query("sheet"), Actor: requestActor(c)}
+		if opts.Format != "" && opts.Format != ImportFormatCSV && opts.Format != ImportFormatXLSX {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid format, must be csv or xlsx"})
+			return
+		}
+		for name, flag := range map[string]*bool{"upsert": &opts.Upsert, "dry_run": &opts.DryRun, "atomic": &opts.Atomic} {
+			value, err := strconv.ParseBool(c.DefaultQuery(name, "false"))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name + " flag"})
+				return
+			}
+			*flag = value
+		}
+
 		// 获取上传的文件
-		file, _, err := c.Request.FormFile("file")
+		file, header, err := c.Request.FormFile("file")
 		if err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file"})
 			return
 		}
 		defer file.Close()
-		// 创建一个通道
-		reader := csv.NewReader(file)
-		ch := make(chan StudentInterface)
-		var wg sync.WaitGroup
-		// 启动一个协程，负责读取和解析 CSV 数据
-		go func() {
-			for {
-				record, err := reader.Read()
-				if err == io.EOF {
-					close(ch)
-					break
-				}
-				if err != nil {
-					fmt.Println("Error reading CSV:", err)
-					continue
-				}
-				// 解析 CSV 记录
-				studentType := record[0]
-				studentID, _ := strconv.Atoi(record[1])
-				name := record[2]
-				gender := record[3]
-				class := record[4]
-
-				var student StudentInterface
-				switch studentType {
-				case "undergraduate":
-					student = &Undergraduate{
-						Student{
-							Name:      name,
-							StudentID: studentID,
-							Gender:    gender,
-							Class:     class,
-						},
-					}
-				case "graduate":
-					student = &Graduate{
-						Student{
-							Name:      name,
-							StudentID: studentID,
-							Gender:    gender,
-							Class:     class,
-						},
-					}
-				default:
-					fmt.Println("Unknown student type:", studentType)
-					continue
-				}
+		// 请求结束后上传的文件会被删除，先复制一份给后台任务
+		src, size, err := spoolUpload(file)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		if opts.Format == "" {
+			head := make([]byte, len(zipSignature))
+			n, _ := src.ReadAt(head, 0)
+			opts.Format = DetectImportFormat(header.Filename, head[:n])
+		}
+		job, err := imports.Start(src, size, opts)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.Header("Location", "/import/jobs/"+job.ID)
+		c.JSON(http.StatusAccepted, job)
+	})
 
-				wg.Add(1)
-				// 启动一个新的协程，将学生数据发送到通道
-				go func(s StudentInterface) {
-					defer wg.Done()
-					ch <- s
-				}(student)
-			}
-		}()
-		// 启动一个协程，等待所有解析协程完成，然后关闭通道
-		go func() {
-			wg.Wait()
-			close(ch)
-		}()
-		// 遍历通道，接收学生数据并添加到学生管理器中
-		for student := range ch {
-			sm.AddStudent(student)
-		}
-		// 返回成功响应
-		c.JSON(http.StatusOK, gin.H{"message": "CSV data imported successfully"})
+	// 查询导入任务的进度，任务结束后包含完整的导入报告；只有管理员可以查询其他人创建的任务
+	r.GET("/import/jobs/:id", func(c *gin.Context) {
+		job, err := imports.Get(c.Param("id"), importJobOwner(c))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, job)
+	})
+
+	// 取消导入任务，只有管理员可以取消其他人创建的任务
+	r.POST("/import/jobs/:id/cancel", func(c *gin.Context) {
+		job, err := imports.Cancel(c.Param("id"), importJobOwner(c))
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, job)
 	})
 
 	// 启动服务器
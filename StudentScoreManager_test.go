@@ -1,10 +1,21 @@
 package main
 
-import "testing"
+import (
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
 
 // 测试 AddStudent 方法
 func TestAddStudent(t *testing.T) {
 	sm := NewStudentManager()
+	registerClasses(t, sm, "27", "28")
 
 	// 创建一个本科生实例
 	undergraduate := &Undergraduate{
@@ -30,7 +41,7 @@ func TestAddStudent(t *testing.T) {
 
 	// 创建一个研究生实例
 	graduate := &Graduate{
-		Student{
+		Student: Student{
 			Name:      "hao",
 			StudentID: 2,
 			Gender:    "female",
@@ -55,6 +66,7 @@ func TestAddStudent(t *testing.T) {
 func TestDeleteStudent(t *testing.T) {
 	// 创建一个 StudentManager 实例
 	sm := NewStudentManager()
+	registerClasses(t, sm, "27", "28")
 
 	// 添加一些测试学生
 	undergraduate := &Undergraduate{
@@ -66,7 +78,7 @@ func TestDeleteStudent(t *testing.T) {
 		},
 	}
 	graduate := &Graduate{
-		Student{
+		Student: Student{
 			Name:      "hao",
 			StudentID: 2,
 			Gender:    "female",
@@ -111,6 +123,7 @@ func TestDeleteStudent(t *testing.T) {
 func TestModifyStudent(t *testing.T) {
 	// 创建一个 StudentManager 实例
 	sm := NewStudentManager()
+	registerClasses(t, sm, "27", "27 modified", "28", "28 modified", "29")
 
 	// 添加一些测试学生
 	undergraduate := &Undergraduate{
@@ -122,7 +135,7 @@ func TestModifyStudent(t *testing.T) {
 		},
 	}
 	graduate := &Graduate{
-		Student{
+		Student: Student{
 			Name:      "hao",
 			StudentID: 2,
 			Gender:    "female",
@@ -213,6 +226,8 @@ func TestModifyStudent(t *testing.T) {
 func TestAddScore(t *testing.T) {
 	// 创建一个 StudentManager 实例
 	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "History", "Science")
+	registerClasses(t, sm, "27", "28")
 
 	// 添加一些测试学生
 	undergraduate := &Undergraduate{
@@ -224,7 +239,7 @@ func TestAddScore(t *testing.T) {
 		},
 	}
 	graduate := &Graduate{
-		Student{
+		Student: Student{
 			Name:      "hao",
 			StudentID: 2,
 			Gender:    "female",
@@ -235,7 +250,7 @@ func TestAddScore(t *testing.T) {
 	sm.AddStudent(graduate)
 
 	// 测试为存在的学生添加成绩
-	err := sm.AddScore(1, "Math", 95.0)
+	err := sm.AddScore(1, "Math", "", 95.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -248,7 +263,7 @@ func TestAddScore(t *testing.T) {
 	}
 
 	// 测试为不存在的学生添加成绩
-	err = sm.AddScore(3, "Science", 88.0)
+	err = sm.AddScore(3, "Science", "", 88.0)
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -258,7 +273,7 @@ func TestAddScore(t *testing.T) {
 	}
 
 	// 测试为另一个存在的学生添加成绩
-	err = sm.AddScore(2, "History", 85.0)
+	err = sm.AddScore(2, "History", "", 85.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -275,6 +290,8 @@ func TestAddScore(t *testing.T) {
 func TestDeleteScore(t *testing.T) {
 	// 创建一个 StudentManager 实例
 	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "Science")
+	registerClasses(t, sm, "27", "28")
 
 	// 添加一些测试学生
 	undergraduate := &Undergraduate{
@@ -286,7 +303,7 @@ func TestDeleteScore(t *testing.T) {
 		},
 	}
 	graduate := &Graduate{
-		Student{
+		Student: Student{
 			Name:      "hao",
 			StudentID: 2,
 			Gender:    "female",
@@ -297,17 +314,17 @@ func TestDeleteScore(t *testing.T) {
 	sm.AddStudent(graduate)
 
 	// 为学生添加一些成绩
-	err := sm.AddScore(1, "Math", 95.0)
+	err := sm.AddScore(1, "Math", "", 95.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	err = sm.AddScore(2, "Science", 88.0)
+	err = sm.AddScore(2, "Science", "", 88.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
 	// 测试删除存在的学生成绩
-	err = sm.DeleteScore(1, "Math")
+	err = sm.DeleteScore(1, "Math", "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -320,7 +337,7 @@ func TestDeleteScore(t *testing.T) {
 	}
 
 	// 测试删除不存在的学生成绩
-	err = sm.DeleteScore(1, "Science")
+	err = sm.DeleteScore(1, "Science", "")
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -330,7 +347,7 @@ func TestDeleteScore(t *testing.T) {
 	}
 
 	// 测试删除另一个存在的学生成绩
-	err = sm.DeleteScore(2, "Science")
+	err = sm.DeleteScore(2, "Science", "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -343,7 +360,7 @@ func TestDeleteScore(t *testing.T) {
 	}
 
 	// 测试删除不存在的学生的成绩
-	err = sm.DeleteScore(3, "Math")
+	err = sm.DeleteScore(3, "Math", "")
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -357,6 +374,8 @@ func TestDeleteScore(t *testing.T) {
 func TestModifyScore(t *testing.T) {
 	// 创建一个 StudentManager 实例
 	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "Science")
+	registerClasses(t, sm, "27", "28")
 
 	// 添加一些测试学生
 	undergraduate := &Undergraduate{
@@ -368,7 +387,7 @@ func TestModifyScore(t *testing.T) {
 		},
 	}
 	graduate := &Graduate{
-		Student{
+		Student: Student{
 			Name:      "hao",
 			StudentID: 2,
 			Gender:    "female",
@@ -379,17 +398,17 @@ func TestModifyScore(t *testing.T) {
 	sm.AddStudent(graduate)
 
 	// 为学生添加一些成绩
-	err := sm.AddScore(1, "Math", 95.0)
+	err := sm.AddScore(1, "Math", "", 95.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	err = sm.AddScore(2, "Science", 88.0)
+	err = sm.AddScore(2, "Science", "", 88.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
 	// 测试修改存在的学生成绩
-	err = sm.ModifyScore(1, "Math", 90.0)
+	err = sm.ModifyScore(1, "Math", "", 90.0, "复核")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -402,7 +421,7 @@ func TestModifyScore(t *testing.T) {
 	}
 
 	// 测试修改不存在的学生成绩
-	err = sm.ModifyScore(1, "Science", 85.0)
+	err = sm.ModifyScore(1, "Science", "", 85.0, "复核")
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -412,7 +431,7 @@ func TestModifyScore(t *testing.T) {
 	}
 
 	// 测试修改另一个存在的学生成绩
-	err = sm.ModifyScore(2, "Science", 92.0)
+	err = sm.ModifyScore(2, "Science", "", 92.0, "复核")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -424,8 +443,14 @@ func TestModifyScore(t *testing.T) {
 		t.Errorf("Expected score 92.0 for course Science, got %v", student.Scores)
 	}
 
+	// 测试修改成绩时必须填写原因
+	var validationErr *ValidationError
+	if err := sm.ModifyScore(1, "Math", "", 95.0, "  "); !errors.As(err, &validationErr) || validationErr.Field != "reason" {
+		t.Errorf("Expected validation error on reason, got %v", err)
+	}
+
 	// 测试修改不存在的学生的成绩
-	err = sm.ModifyScore(3, "Math", 80.0)
+	err = sm.ModifyScore(3, "Math", "", 80.0, "复核")
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -439,6 +464,8 @@ func TestModifyScore(t *testing.T) {
 func TestQueryStudent(t *testing.T) {
 	// 创建一个 StudentManager 实例
 	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "Science")
+	registerClasses(t, sm, "27", "27 modified", "28", "28 modified")
 
 	// 添加一些测试学生
 	undergraduate := &Undergraduate{
@@ -451,7 +478,7 @@ func TestQueryStudent(t *testing.T) {
 		},
 	}
 	graduate := &Graduate{
-		Student{
+		Student: Student{
 			Name:      "hao",
 			StudentID: 2,
 			Gender:    "female",
@@ -463,11 +490,11 @@ func TestQueryStudent(t *testing.T) {
 	sm.AddStudent(graduate)
 
 	// 为学生添加一些成绩
-	err := sm.AddScore(1, "Math", 95.0)
+	err := sm.AddScore(1, "Math", "", 95.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	err = sm.AddScore(2, "Science", 88.0)
+	err = sm.AddScore(2, "Science", "", 88.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -526,6 +553,8 @@ func TestQueryStudent(t *testing.T) {
 func TestQueryScore(t *testing.T) {
 	// 创建一个 StudentManager 实例
 	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "Science")
+	registerClasses(t, sm, "27", "27 modified", "28", "28 modified")
 
 	// 添加一些测试学生
 	undergraduate := &Undergraduate{
@@ -538,7 +567,7 @@ func TestQueryScore(t *testing.T) {
 		},
 	}
 	graduate := &Graduate{
-		Student{
+		Student: Student{
 			Name:      "hao",
 			StudentID: 2,
 			Gender:    "female",
@@ -550,11 +579,11 @@ func TestQueryScore(t *testing.T) {
 	sm.AddStudent(graduate)
 
 	// 为学生添加一些成绩
-	err := sm.AddScore(1, "Math", 95.0)
+	err := sm.AddScore(1, "Math", "", 95.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
-	err = sm.AddScore(2, "Science", 88.0)
+	err = sm.AddScore(2, "Science", "", 88.0)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -581,7 +610,7 @@ func TestQueryScore(t *testing.T) {
 	}
 
 	// 测试查询存在的学生成绩
-	score, err := sm.QueryScore(1, "Math")
+	score, err := sm.QueryScore(1, "Math", "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -590,7 +619,7 @@ func TestQueryScore(t *testing.T) {
 	}
 
 	// 测试查询另一个存在的学生成绩
-	score, err = sm.QueryScore(2, "Science")
+	score, err = sm.QueryScore(2, "Science", "")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -599,7 +628,7 @@ func TestQueryScore(t *testing.T) {
 	}
 
 	// 测试查询不存在的学生成绩
-	_, err = sm.QueryScore(1, "Science")
+	_, err = sm.QueryScore(1, "Science", "")
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -609,7 +638,7 @@ func TestQueryScore(t *testing.T) {
 	}
 
 	// 测试查询不存在的学生的成绩
-	_, err = sm.QueryScore(3, "Math")
+	_, err = sm.QueryScore(3, "Math", "")
 	if err == nil {
 		t.Errorf("Expected error, got nil")
 	}
@@ -618,3 +647,181 @@ func TestQueryScore(t *testing.T) {
 		t.Errorf("Expected error message %q, got %q", expectedErr, err.Error())
 	}
 }
+
+// TestAddStudentWithScores 测试添加学生时保留并校验成绩
+func TestAddStudentWithScores(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "History")
+	registerClasses(t, sm, "28")
+
+	undergraduate := &Undergraduate{
+		Student{
+			Name:      "wei",
+			StudentID: 1,
+			Gender:    "male",
+			Class:     "28",
+			Scores:    map[string]float64{"Math": 95.0, "History": 80.0},
+		},
+	}
+	if err := sm.AddStudent(undergraduate); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	score, err := sm.QueryScore(1, "History", "")
+	if err != nil || score != 80.0 {
+		t.Errorf("Expected score 80.0 for course History, got %v %v", score, err)
+	}
+
+	// 测试添加带有不合法成绩的学生
+	for _, scores := range []map[string]float64{
+		{"Math": -1},
+		{"Math": math.NaN()},
+		{"": 90},
+	} {
+		err := sm.AddStudent(&Undergraduate{Student{Name: "hao", StudentID: 2, Scores: scores}})
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("Expected validation error for scores %v, got %v", scores, err)
+		}
+	}
+	if _, err := sm.QueryStudent(2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected student with invalid scores not to be added, got %v", err)
+	}
+}
+
+// TestAddStudentDuplicate 测试重复学号返回冲突错误且不覆盖已有学生
+func TestAddStudentDuplicate(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	registerClasses(t, sm, "27", "28")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	sm.AddScore(1, "Math", "", 95.0)
+
+	err := sm.AddStudent(&Graduate{Student: Student{Name: "hao", StudentID: 1, Gender: "female", Class: "27"}})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected conflict error, got %v", err)
+	}
+	expectedErr := "student with id 1 already exists"
+	if err.Error() != expectedErr {
+		t.Errorf("Expected error message %q, got %q", expectedErr, err.Error())
+	}
+
+	student, _ := sm.QueryStudent(1)
+	if student.Name != "wei" || student.Type != "undergraduate" || student.Scores["Math"] != 95.0 {
+		t.Errorf("Expected existing student to be unchanged, got %v", student)
+	}
+}
+
+// TestUpsertStudent 测试覆盖已有学生时保留已有成绩，不能修改已有成绩
+func TestUpsertStudent(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "History", "Science")
+	registerClasses(t, sm, "28", "29")
+
+	created, err := sm.UpsertStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	if err != nil || !created {
+		t.Fatalf("Expected student to be created, got %v %v", created, err)
+	}
+	sm.AddScore(1, "Math", "", 95.0)
+	sm.AddScore(1, "History", "", 70.0)
+
+	// 修改已有成绩需要填写原因，覆盖学生时拒绝整个请求
+	_, err = sm.UpsertStudent(&Undergraduate{
+		Student{Name: "wei modified", StudentID: 1, Gender: "male", Class: "29", Scores: map[string]float64{"History": 75.0}},
+	})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("Expected overwriting an existing score to conflict, got %v", err)
+	}
+	if student, _ := sm.QueryStudent(1); student.Name != "wei" || student.Scores["History"] != 70.0 {
+		t.Errorf("Expected rejected upsert to change nothing, got %v", student)
+	}
+
+	// 重复提交相同的成绩不算修改
+	created, err = sm.UpsertStudent(&Undergraduate{
+		Student{Name: "wei modified", StudentID: 1, Gender: "male", Class: "29", Scores: map[string]float64{"History": 70.0, "Science": 88.0}},
+	})
+	if err != nil || created {
+		t.Fatalf("Expected student to be replaced, got %v %v", created, err)
+	}
+
+	student, _ := sm.QueryStudent(1)
+	if student.Name != "wei modified" || student.Class != "29" {
+		t.Errorf("Expected student to be replaced, got %v", student)
+	}
+	expected := map[string]float64{"Math": 95.0, "History": 70.0, "Science": 88.0}
+	if len(student.Scores) != len(expected) {
+		t.Errorf("Expected scores %v, got %v", expected, student.Scores)
+	}
+	for course, score := range expected {
+		if student.Scores[course] != score {
+			t.Errorf("Expected score %v for course %s, got %v", score, course, student.Scores[course])
+		}
+	}
+}
+
+// TestAddStudentHandler 测试新增学生接口的状态码
+func TestAddStudentHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math")
+	r := gin.New()
+	r.POST("/undergraduates", func(c *gin.Context) {
+		var undergraduate Undergraduate
+		if err := c.ShouldBindJSON(&undergraduate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		addStudent(c, sm, &undergraduate, "Undergraduate")
+	})
+
+	tests := []struct {
+		url    string
+		body   string
+		status int
+	}{
+		{"/undergraduates", `{"id":1,"name":"wei","scores":{"Math":95}}`, http.StatusCreated},
+		{"/undergraduates", `{"id":1,"name":"hao"}`, http.StatusConflict},
+		{"/undergraduates?upsert=true", `{"id":1,"name":"hao"}`, http.StatusOK},
+		{"/undergraduates?upsert=true", `{"id":2,"name":"li"}`, http.StatusCreated},
+		{"/undergraduates?upsert=maybe", `{"id":3,"name":"li"}`, http.StatusBadRequest},
+		{"/undergraduates", `{"id":4,"name":"li","scores":{"Math":-5}}`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, tt.url, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("POST %s %s: expected status %d, got %d (%s)", tt.url, tt.body, tt.status, w.Code, w.Body.String())
+		}
+	}
+
+	score, err := sm.QueryScore(1, "Math", "")
+	if err != nil || score != 95.0 {
+		t.Errorf("Expected score 95.0 to survive the upsert, got %v %v", score, err)
+	}
+}
+
+// TestAbortConnection 测试已开始写入响应后中断连接，客户端读到不完整的响应
+func TestAbortConnection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.GET("/export", func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.String(http.StatusOK, "type,id,name\n")
+		c.Writer.Flush()
+		abortConnection(c)
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/export")
+	if err != nil {
+		t.Fatalf("Expected response headers, got %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Expected the truncated body to end with an unexpected EOF, got %q %v", body, err)
+	}
+}
@@ -0,0 +1,961 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StudentStore 定义学生数据的存储接口
+// StudentManager 的所有读写操作都经过该接口，实现方只负责持久化，
+// 并发控制由 StudentManager 的互斥锁统一负责。
+// 存储保留学生的具体类型，读取时返回与保存时相同类型的实例
+type StudentStore interface {
+	// Get 按学号读取学生，学生不存在时 exists 为 false
+	Get(studentID int) (student StudentInterface, exists bool, err error)
+	// Save 保存学生信息（包括成绩），已存在则覆盖
+	Save(student StudentInterface) error
+	// Delete 删除学生信息及其全部成绩
+	Delete(studentID int) error
+	// List 按学号升序返回全部学生
+	List() ([]StudentInterface, error)
+	// Close 释放存储占用的资源
+	Close() error
+}
+
+// StudentPager 可以按学号分页读取学生的存储，内置的三种学生存储都实现了该接口
+// 导出等需要遍历全部学生的操作逐页读取，不必一次载入全部学生
+type StudentPager interface {
+	// ListAfter 按学号升序返回学号大于 afterID 的至多 limit 个学生
+	ListAfter(afterID, limit int) ([]StudentInterface, error)
+}
+
+// listStudentsAfter 按学号升序返回学号大于 afterID 的至多 limit 个学生，存储不支持分页时从全部学生中截取
+func listStudentsAfter(store StudentStore, afterID, limit int) ([]StudentInterface, error) {
+	if pager, ok := store.(StudentPager); ok {
+		return pager.ListAfter(afterID, limit)
+	}
+	students, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+	start := sort.Search(len(students), func(i int) bool { return students[i].GetID() > afterID })
+	return students[start:min(start+limit, len(students))], nil
+}
+
+// StudentBatchSaver 可以一次写入多个学生修改的存储，文件存储实现该接口，整批修改只重写一次数据文件
+// 导入等批量操作通过暂存存储提交时使用
+type StudentBatchSaver interface {
+	// SaveAll 保存 changes 中的学生，值为 nil 表示删除该学号的学生，失败时整批不生效
+	SaveAll(changes map[int]StudentInterface) error
+}
+
+// MemoryStore 内存存储，进程退出后数据丢失
+type MemoryStore struct {
+	students map[int]StudentInterface
+	courses  map[string]Course
+	classes  map[string]Class
+	moves    []ClassMove
+	users    map[string]User
+	revoked  map[string]time.Time // 已吊销令牌的 jti 及其过期时间
+	apiKeys  map[string]APIKey
+	audit    []AuditEntry // 按序号升序
+}
+
+// NewMemoryStore 创建内存存储
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		students: make(map[int]StudentInterface),
+		courses:  make(map[string]Course),
+		classes:  make(map[string]Class),
+		users:    make(map[string]User),
+		revoked:  make(map[string]time.Time),
+		apiKeys:  make(map[string]APIKey),
+	}
+}
+
+// Get 按学号读取学生
+func (ms *MemoryStore) Get(studentID int) (StudentInterface, bool, error) {
+	student, exists := ms.students[studentID]
+	if !exists {
+		return nil, false, nil
+	}
+	clone, err := cloneStudent(student)
+	if err != nil {
+		return nil, false, err
+	}
+	return clone, true, nil
+}
+
+// Save 保存学生信息
+func (ms *MemoryStore) Save(student StudentInterface) error {
+	clone, err := cloneStudent(student)
+	if err != nil {
+		return err
+	}
+	ms.students[student.GetID()] = clone
+	return nil
+}
+
+// Delete 删除学生信息
+func (ms *MemoryStore) Delete(studentID int) error {
+	delete(ms.students, studentID)
+	return nil
+}
+
+// List 按学号升序返回全部学生
+func (ms *MemoryStore) List() ([]StudentInterface, error) {
+	students := make([]StudentInterface, 0, len(ms.students))
+	for _, student := range ms.students {
+		clone, err := cloneStudent(student)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, clone)
+	}
+	sort.Slice(students, func(i, j int) bool {
+		return students[i].GetID() < students[j].GetID()
+	})
+	return students, nil
+}
+
+// ListAfter 按学号升序返回学号大于 afterID 的至多 limit 个学生
+func (ms *MemoryStore) ListAfter(afterID, limit int) ([]StudentInterface, error) {
+	ids := make([]int, 0, len(ms.students))
+	for id := range ms.students {
+		if id > afterID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	students := make([]StudentInterface, 0, min(limit, len(ids)))
+	for _, id := range ids[:min(limit, len(ids))] {
+		clone, err := cloneStudent(ms.students[id])
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, clone)
+	}
+	return students, nil
+}
+
+// Close 内存存储无需释放资源
+func (ms *MemoryStore) Close() error {
+	return nil
+}
+
+// SaveCourse 保存课程
+func (ms *MemoryStore) SaveCourse(course Course) error {
+	ms.courses[course.Code] = course
+	return nil
+}
+
+// DeleteCourse 删除课程
+func (ms *MemoryStore) DeleteCourse(code string) error {
+	delete(ms.courses, code)
+	return nil
+}
+
+// ListCourses 按课程代码升序返回全部课程
+func (ms *MemoryStore) ListCourses() ([]Course, error) {
+	courses := make([]Course, 0, len(ms.courses))
+	for _, course := range ms.courses {
+		courses = append(courses, course)
+	}
+	sort.Slice(courses, func(i, j int) bool {
+		return courses[i].Code < courses[j].Code
+	})
+	return courses, nil
+}
+
+// SaveClass 保存班级
+func (ms *MemoryStore) SaveClass(class Class) error {
+	ms.classes[class.ID] = class
+	return nil
+}
+
+// DeleteClass 删除班级
+func (ms *MemoryStore) DeleteClass(id string) error {
+	delete(ms.classes, id)
+	return nil
+}
+
+// ListClasses 按班级编号升序返回全部班级
+func (ms *MemoryStore) ListClasses() ([]Class, error) {
+	classes := make([]Class, 0, len(ms.classes))
+	for _, class := range ms.classes {
+		classes = append(classes, class)
+	}
+	sort.Slice(classes, func(i, j int) bool {
+		return classes[i].ID < classes[j].ID
+	})
+	return classes, nil
+}
+
+// AppendClassMoves 追加调班记录
+func (ms *MemoryStore) AppendClassMoves(moves []ClassMove) error {
+	ms.moves = append(ms.moves, moves...)
+	return nil
+}
+
+// ListClassMoves 按记录的先后顺序返回全部调班记录
+func (ms *MemoryStore) ListClassMoves() ([]ClassMove, error) {
+	return append([]ClassMove{}, ms.moves...), nil
+}
+
+// GetUser 按用户名读取用户
+func (ms *MemoryStore) GetUser(username string) (User, bool, error) {
+	user, exists := ms.users[username]
+	return user, exists, nil
+}
+
+// SaveUser 保存用户
+func (ms *MemoryStore) SaveUser(user User) error {
+	ms.users[user.Username] = user
+	return nil
+}
+
+// DeleteUser 删除用户
+func (ms *MemoryStore) DeleteUser(username string) error {
+	delete(ms.users, username)
+	return nil
+}
+
+// ListUsers 按用户名升序返回全部用户
+func (ms *MemoryStore) ListUsers() ([]User, error) {
+	users := make([]User, 0, len(ms.users))
+	for _, user := range ms.users {
+		users = append(users, user)
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	return users, nil
+}
+
+// RevokeToken 记录已吊销的令牌，同时清理已过期的记录
+func (ms *MemoryStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	now := time.Now()
+	for id, expiry := range ms.revoked {
+		if expiry.Before(now) {
+			delete(ms.revoked, id)
+		}
+	}
+	ms.revoked[tokenID] = expiresAt
+	return nil
+}
+
+// TokenRevoked 判断令牌是否已吊销
+func (ms *MemoryStore) TokenRevoked(tokenID string) (bool, error) {
+	_, revoked := ms.revoked[tokenID]
+	return revoked, nil
+}
+
+// GetAPIKey 按编号读取 API 密钥
+func (ms *MemoryStore) GetAPIKey(id string) (APIKey, bool, error) {
+	key, exists := ms.apiKeys[id]
+	key.Scopes = append([]string{}, key.Scopes...)
+	return key, exists, nil
+}
+
+// SaveAPIKey 保存 API 密钥
+func (ms *MemoryStore) SaveAPIKey(key APIKey) error {
+	key.Scopes = append([]string{}, key.Scopes...)
+	ms.apiKeys[key.ID] = key
+	return nil
+}
+
+// DeleteAPIKey 删除 API 密钥
+func (ms *MemoryStore) DeleteAPIKey(id string) error {
+	delete(ms.apiKeys, id)
+	return nil
+}
+
+// ListAPIKeys 按创建时间升序返回全部 API 密钥
+func (ms *MemoryStore) ListAPIKeys() ([]APIKey, error) {
+	keys := make([]APIKey, 0, len(ms.apiKeys))
+	for _, key := range ms.apiKeys {
+		key.Scopes = append([]string{}, key.Scopes...)
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ID < keys[j].ID
+	})
+	return keys, nil
+}
+
+// AppendAudit 追加审计记录，序号从 1 开始递增
+func (ms *MemoryStore) AppendAudit(entry AuditEntry) error {
+	entry.Seq = int64(len(ms.audit)) + 1
+	ms.audit = append(ms.audit, entry)
+	return nil
+}
+
+// QueryAudit 按序号降序返回满足条件的审计记录及其总数，Limit 为 0 时返回全部
+func (ms *MemoryStore) QueryAudit(query AuditQuery) ([]AuditEntry, int, error) {
+	matched := []AuditEntry{}
+	for i := len(ms.audit) - 1; i >= 0; i-- {
+		if query.matches(ms.audit[i]) {
+			matched = append(matched, ms.audit[i])
+		}
+	}
+	total := len(matched)
+	if query.Offset >= total {
+		return []AuditEntry{}, total, nil
+	}
+	end := total
+	if query.Limit > 0 {
+		end = min(query.Offset+query.Limit, total)
+	}
+	return matched[query.Offset:end], total, nil
+}
+
+// stagingStore 在底层存储之上暂存修改，commit 之前读到的是暂存后的数据，底层存储保持不变
+// 用于导入的试运行和全部成功才写入的模式。暂存期间不持有 StudentManager 的锁，
+// 每次读取底层存储时才短暂加锁，并记下读到的学生，提交时据此检查是否被其他请求修改过
+type stagingStore struct {
+	base    StudentStore
+	lock    sync.Locker              // 读取底层存储时加的锁，为空表示调用方一直持有锁
+	changes map[int]StudentInterface // 值为 nil 表示已删除
+	read    map[int]string           // 第一次读到的底层学生，不存在时为空字符串
+}
+
+// newStagingStore 创建暂存存储，lock 为保护底层存储的锁，调用方一直持有锁时为空
+func newStagingStore(base StudentStore, lock sync.Locker) *stagingStore {
+	return &stagingStore{
+		base:    base,
+		lock:    lock,
+		changes: make(map[int]StudentInterface),
+		read:    make(map[int]string),
+	}
+}
+
+// snapshotKey 返回学生的 JSON，用于比较学生是否被修改过
+func snapshotKey(student StudentInterface, exists bool) (string, error) {
+	if !exists {
+		return "", nil
+	}
+	data, err := json.Marshal(student)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Get 按学号读取学生，优先读取暂存的修改
+func (ss *stagingStore) Get(studentID int) (StudentInterface, bool, error) {
+	student, changed := ss.changes[studentID]
+	if !changed {
+		if ss.lock != nil {
+			ss.lock.Lock()
+			defer ss.lock.Unlock()
+		}
+		student, exists, err := ss.base.Get(studentID)
+		if err != nil {
+			return nil, false, err
+		}
+		if _, seen := ss.read[studentID]; !seen {
+			if ss.read[studentID], err = snapshotKey(student, exists); err != nil {
+				return nil, false, err
+			}
+		}
+		return student, exists, nil
+	}
+	if student == nil {
+		return nil, false, nil
+	}
+	clone, err := cloneStudent(student)
+	if err != nil {
+		return nil, false, err
+	}
+	return clone, true, nil
+}
+
+// Save 暂存学生信息
+func (ss *stagingStore) Save(student StudentInterface) error {
+	clone, err := cloneStudent(student)
+	if err != nil {
+		return err
+	}
+	ss.changes[student.GetID()] = clone
+	return nil
+}
+
+// Delete 暂存删除操作
+func (ss *stagingStore) Delete(studentID int) error {
+	ss.changes[studentID] = nil
+	return nil
+}
+
+// List 按学号升序返回合并暂存修改后的全部学生
+func (ss *stagingStore) List() ([]StudentInterface, error) {
+	if ss.lock != nil {
+		ss.lock.Lock()
+		defer ss.lock.Unlock()
+	}
+	base, err := ss.base.List()
+	if err != nil {
+		return nil, err
+	}
+	students := make([]StudentInterface, 0, len(base)+len(ss.changes))
+	for _, student := range base {
+		if _, changed := ss.changes[student.GetID()]; !changed {
+			students = append(students, student)
+		}
+	}
+	for _, student := range ss.changes {
+		if student == nil {
+			continue
+		}
+		clone, err := cloneStudent(student)
+		if err != nil {
+			return nil, err
+		}
+		students = append(students, clone)
+	}
+	sort.Slice(students, func(i, j int) bool {
+		return students[i].GetID() < students[j].GetID()
+	})
+	return students, nil
+}
+
+// Close 暂存存储不关闭底层存储
+func (ss *stagingStore) Close() error {
+	return nil
+}
+
+// commit 把暂存的修改写入底层存储，底层存储支持批量写入时一次写入，否则按学号顺序逐个写入，中途失败时恢复已写入的学生
+// 暂存期间读过的学生被其他请求修改时整批不写入，返回 ErrConflict。调用方需持有锁
+func (ss *stagingStore) commit() error {
+	for _, id := range sortedStudentIDs(ss.changes) {
+		read, seen := ss.read[id]
+		if !seen {
+			continue
+		}
+		student, exists, err := ss.base.Get(id)
+		if err != nil {
+			return err
+		}
+		current, err := snapshotKey(student, exists)
+		if err != nil {
+			return err
+		}
+		if current != read {
+			return fmt.Errorf("a newer version of student with id %d %w, it was modified during the import", id, ErrConflict)
+		}
+	}
+
+	var err error
+	if saver, ok := ss.base.(StudentBatchSaver); ok {
+		err = saver.SaveAll(ss.changes)
+	} else {
+		err = saveEach(ss.base, ss.changes)
+	}
+	if err != nil {
+		return err
+	}
+	ss.changes = make(map[int]StudentInterface)
+	return nil
+}
+
+// sortedStudentIDs 按升序返回 changes 中的学号
+func sortedStudentIDs(changes map[int]StudentInterface) []int {
+	ids := make([]int, 0, len(changes))
+	for id := range changes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// saveEach 按学号顺序逐个写入 changes 中的学生，值为 nil 表示删除，中途失败时恢复已写入的学生
+func saveEach(store StudentStore, changes map[int]StudentInterface) error {
+	type previous struct {
+		student StudentInterface
+		exists  bool
+	}
+	applied := make(map[int]previous, len(changes))
+	rollback := func(err error) error {
+		errs := []error{err}
+		for id, prev := range applied {
+			if prev.exists {
+				errs = append(errs, store.Save(prev.student))
+			} else {
+				errs = append(errs, store.Delete(id))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	for _, id := range sortedStudentIDs(changes) {
+		student, exists, err := store.Get(id)
+		if err != nil {
+			return rollback(err)
+		}
+		applied[id] = previous{student: student, exists: exists}
+		if changes[id] == nil {
+			err = store.Delete(id)
+		} else {
+			err = store.Save(changes[id])
+		}
+		if err != nil {
+			return rollback(err)
+		}
+	}
+	return nil
+}
+
+// FileStore 基于 JSON 文件的存储
+// 数据常驻内存，每次修改后将全部数据写入临时文件再重命名覆盖，保证文件始终完整；SaveAll 整批修改只写一次。
+// 审计日志单独保存在数据文件旁的 JSON Lines 文件中，每条记录追加一行，不随数据文件重写
+type FileStore struct {
+	*MemoryStore
+	path      string
+	auditLog  *os.File
+	auditSize int64 // 审计日志文件中完整记录的字节数，追加失败时截断到这里
+}
+
+// auditLogPath 返回数据文件对应的审计日志文件路径，例如 students.json 对应 students.audit.jsonl
+func auditLogPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".audit.jsonl"
+}
+
+// fileData 数据文件的内容
+// 早期版本的数据文件只有学生数组，读取时仍然兼容；审计日志曾经保存在数据文件的 audit_log 中
+type fileData struct {
+	Students []json.RawMessage    `json:"students"`
+	Courses  []Course             `json:"courses"`
+	Classes  []Class              `json:"classes"`
+	Moves    []ClassMove          `json:"class_moves"`
+	Users    []User               `json:"users"`
+	Revoked  map[string]time.Time `json:"revoked_tokens"`
+	APIKeys  []APIKey             `json:"api_keys"`
+	Audit    []AuditEntry         `json:"audit_log"`
+}
+
+// NewFileStore 打开或创建 JSON 文件存储，文件已存在时加载其中的学生数据和审计日志
+func NewFileStore(path string) (*FileStore, error) {
+	fs := &FileStore{
+		MemoryStore: NewMemoryStore(),
+		path:        path,
+	}
+	legacyAudit, err := fs.load()
+	if err != nil {
+		return nil, err
+	}
+	if err := fs.openAuditLog(); err != nil {
+		return nil, err
+	}
+	// 把旧版数据文件中的审计日志移到审计日志文件，已经移过的记录不再重复追加
+	if len(legacyAudit) > len(fs.audit) {
+		for _, entry := range legacyAudit[len(fs.audit):] {
+			if err := fs.AppendAudit(entry); err != nil {
+				fs.Close()
+				return nil, err
+			}
+		}
+	}
+	if len(legacyAudit) > 0 {
+		if err := fs.flush(); err != nil {
+			fs.Close()
+			return nil, err
+		}
+	}
+	return fs, nil
+}
+
+// load 读取数据文件，返回旧版数据文件中保存的审计日志
+func (fs *FileStore) load() ([]AuditEntry, error) {
+	path := fs.path
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read data file %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var content fileData
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(data, &content.Students)
+	} else {
+		err = json.Unmarshal(data, &content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse data file %s: %w", path, err)
+	}
+	for _, record := range content.Students {
+		student, err := decodeStudent(record)
+		if err != nil {
+			return nil, fmt.Errorf("parse data file %s: %w", path, err)
+		}
+		fs.students[student.GetID()] = student
+	}
+	for _, course := range content.Courses {
+		fs.courses[course.Code] = course
+	}
+	for _, class := range content.Classes {
+		fs.classes[class.ID] = class
+	}
+	fs.moves = content.Moves
+	for _, user := range content.Users {
+		// 引入角色之前创建的用户都是管理员
+		if user.Role == "" {
+			user.Role = RoleAdmin
+		}
+		fs.users[user.Username] = user
+	}
+	for id, expiresAt := range content.Revoked {
+		fs.revoked[id] = expiresAt
+	}
+	for _, key := range content.APIKeys {
+		fs.apiKeys[key.ID] = key
+	}
+	return content.Audit, nil
+}
+
+// openAuditLog 读取审计日志文件中的记录，并以追加方式打开文件
+// 写到一半的最后一行（例如追加时进程退出）会被截掉
+func (fs *FileStore) openAuditLog() error {
+	path := auditLogPath(fs.path)
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit log %s: %w", path, err)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("read audit log %s: %w", path, err)
+	}
+	var size int64
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(data[:end], &entry); err != nil {
+			file.Close()
+			return fmt.Errorf("parse audit log %s: %w", path, err)
+		}
+		fs.audit = append(fs.audit, entry)
+		size += int64(end) + 1
+		data = data[end+1:]
+	}
+	if len(data) > 0 {
+		if err := file.Truncate(size); err != nil {
+			file.Close()
+			return fmt.Errorf("truncate audit log %s: %w", path, err)
+		}
+	}
+	fs.auditLog = file
+	fs.auditSize = size
+	return nil
+}
+
+// Save 保存学生信息并写回文件
+func (fs *FileStore) Save(student StudentInterface) error {
+	studentID := student.GetID()
+	previous, existed := fs.students[studentID]
+	if err := fs.MemoryStore.Save(student); err != nil {
+		return err
+	}
+	if err := fs.flush(); err != nil {
+		// 写文件失败时恢复内存数据，保持与文件一致
+		if existed {
+			fs.students[studentID] = previous
+		} else {
+			delete(fs.students, studentID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete 删除学生信息并写回文件
+func (fs *FileStore) Delete(studentID int) error {
+	previous, existed := fs.students[studentID]
+	if !existed {
+		return nil
+	}
+	fs.MemoryStore.Delete(studentID)
+	if err := fs.flush(); err != nil {
+		fs.students[studentID] = previous
+		return err
+	}
+	return nil
+}
+
+// SaveAll 一次保存多个学生的修改，只写回一次文件，写文件失败时恢复内存数据
+func (fs *FileStore) SaveAll(changes map[int]StudentInterface) error {
+	type previous struct {
+		student StudentInterface
+		existed bool
+	}
+	applied := make(map[int]previous, len(changes))
+	restore := func() {
+		for id, prev := range applied {
+			if prev.existed {
+				fs.students[id] = prev.student
+			} else {
+				delete(fs.students, id)
+			}
+		}
+	}
+	for id, student := range changes {
+		prev, existed := fs.students[id]
+		applied[id] = previous{student: prev, existed: existed}
+		if student == nil {
+			fs.MemoryStore.Delete(id)
+			continue
+		}
+		if err := fs.MemoryStore.Save(student); err != nil {
+			restore()
+			return err
+		}
+	}
+	if err := fs.flush(); err != nil {
+		restore()
+		return err
+	}
+	return nil
+}
+
+// SaveCourse 保存课程并写回文件
+func (fs *FileStore) SaveCourse(course Course) error {
+	previous, existed := fs.courses[course.Code]
+	fs.MemoryStore.SaveCourse(course)
+	if err := fs.flush(); err != nil {
+		if existed {
+			fs.courses[course.Code] = previous
+		} else {
+			delete(fs.courses, course.Code)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteCourse 删除课程并写回文件
+func (fs *FileStore) DeleteCourse(code string) error {
+	previous, existed := fs.courses[code]
+	if !existed {
+		return nil
+	}
+	fs.MemoryStore.DeleteCourse(code)
+	if err := fs.flush(); err != nil {
+		fs.courses[code] = previous
+		return err
+	}
+	return nil
+}
+
+// SaveClass 保存班级并写回文件
+func (fs *FileStore) SaveClass(class Class) error {
+	previous, existed := fs.classes[class.ID]
+	fs.MemoryStore.SaveClass(class)
+	if err := fs.flush(); err != nil {
+		if existed {
+			fs.classes[class.ID] = previous
+		} else {
+			delete(fs.classes, class.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteClass 删除班级并写回文件
+func (fs *FileStore) DeleteClass(id string) error {
+	previous, existed := fs.classes[id]
+	if !existed {
+		return nil
+	}
+	fs.MemoryStore.DeleteClass(id)
+	if err := fs.flush(); err != nil {
+		fs.classes[id] = previous
+		return err
+	}
+	return nil
+}
+
+// AppendClassMoves 追加调班记录并写回文件
+func (fs *FileStore) AppendClassMoves(moves []ClassMove) error {
+	n := len(fs.moves)
+	fs.MemoryStore.AppendClassMoves(moves)
+	if err := fs.flush(); err != nil {
+		fs.moves = fs.moves[:n]
+		return err
+	}
+	return nil
+}
+
+// SaveUser 保存用户并写回文件
+func (fs *FileStore) SaveUser(user User) error {
+	previous, existed := fs.users[user.Username]
+	fs.MemoryStore.SaveUser(user)
+	if err := fs.flush(); err != nil {
+		if existed {
+			fs.users[user.Username] = previous
+		} else {
+			delete(fs.users, user.Username)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteUser 删除用户并写回文件
+func (fs *FileStore) DeleteUser(username string) error {
+	previous, existed := fs.users[username]
+	if !existed {
+		return nil
+	}
+	fs.MemoryStore.DeleteUser(username)
+	if err := fs.flush(); err != nil {
+		fs.users[username] = previous
+		return err
+	}
+	return nil
+}
+
+// RevokeToken 记录已吊销的令牌并写回文件
+func (fs *FileStore) RevokeToken(tokenID string, expiresAt time.Time) error {
+	fs.MemoryStore.RevokeToken(tokenID, expiresAt)
+	if err := fs.flush(); err != nil {
+		// 清理掉的过期记录不需要恢复
+		delete(fs.revoked, tokenID)
+		return err
+	}
+	return nil
+}
+
+// SaveAPIKey 保存 API 密钥并写回文件
+func (fs *FileStore) SaveAPIKey(key APIKey) error {
+	previous, existed := fs.apiKeys[key.ID]
+	fs.MemoryStore.SaveAPIKey(key)
+	if err := fs.flush(); err != nil {
+		if existed {
+			fs.apiKeys[key.ID] = previous
+		} else {
+			delete(fs.apiKeys, key.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteAPIKey 删除 API 密钥并写回文件
+func (fs *FileStore) DeleteAPIKey(id string) error {
+	previous, existed := fs.apiKeys[id]
+	if !existed {
+		return nil
+	}
+	fs.MemoryStore.DeleteAPIKey(id)
+	if err := fs.flush(); err != nil {
+		fs.apiKeys[id] = previous
+		return err
+	}
+	return nil
+}
+
+// AppendAudit 在审计日志文件末尾追加一行记录，写入磁盘后才返回
+func (fs *FileStore) AppendAudit(entry AuditEntry) error {
+	n := len(fs.audit)
+	fs.MemoryStore.AppendAudit(entry)
+	line, err := json.Marshal(fs.audit[n])
+	if err != nil {
+		fs.audit = fs.audit[:n]
+		return fmt.Errorf("encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+	if _, err := fs.auditLog.Write(line); err != nil {
+		fs.audit = fs.audit[:n]
+		// 去掉写了一半的记录，保证每一行都是完整的记录
+		fs.auditLog.Truncate(fs.auditSize)
+		return fmt.Errorf("append audit log: %w", err)
+	}
+	if err := fs.auditLog.Sync(); err != nil {
+		fs.audit = fs.audit[:n]
+		fs.auditLog.Truncate(fs.auditSize)
+		return fmt.Errorf("sync audit log: %w", err)
+	}
+	fs.auditSize += int64(len(line))
+	return nil
+}
+
+// Close 关闭审计日志文件
+func (fs *FileStore) Close() error {
+	return fs.auditLog.Close()
+}
+
+// flush 将全部数据写入临时文件后原子替换数据文件
+func (fs *FileStore) flush() error {
+	students, err := fs.MemoryStore.List()
+	if err != nil {
+		return err
+	}
+	courses, err := fs.MemoryStore.ListCourses()
+	if err != nil {
+		return err
+	}
+	classes, err := fs.MemoryStore.ListClasses()
+	if err != nil {
+		return err
+	}
+	users, err := fs.MemoryStore.ListUsers()
+	if err != nil {
+		return err
+	}
+	apiKeys, err := fs.MemoryStore.ListAPIKeys()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(struct {
+		Students []StudentInterface   `json:"students"`
+		Courses  []Course             `json:"courses"`
+		Classes  []Class              `json:"classes"`
+		Moves    []ClassMove          `json:"class_moves"`
+		Users    []User               `json:"users"`
+		Revoked  map[string]time.Time `json:"revoked_tokens"`
+		APIKeys  []APIKey             `json:"api_keys"`
+	}{students, courses, classes, fs.moves, users, fs.revoked, apiKeys}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode data file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fs.path), filepath.Base(fs.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), fs.path); err != nil {
+		return fmt.Errorf("replace data file %s: %w", fs.path, err)
+	}
+	return nil
+}
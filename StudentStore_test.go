@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMemoryStoreIsolation 测试内存存储返回的是副本
+func TestMemoryStoreIsolation(t *testing.T) {
+	store := NewMemoryStore()
+	student := &Graduate{
+		Student: Student{
+			Name:      "wei",
+			StudentID: 1,
+			Gender:    "male",
+			Class:     "28",
+			Scores:    map[string]float64{"Math": 95.0},
+		},
+	}
+	if err := store.Save(student); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 修改调用方持有的数据不应影响存储
+	student.Scores["Math"] = 10.0
+	stored, exists, err := store.Get(1)
+	if err != nil || !exists {
+		t.Fatalf("Expected student 1 to exist, got %v %v", exists, err)
+	}
+	if stored.GetScores()["Math"] != 95.0 {
+		t.Errorf("Expected stored score 95.0, got %v", stored.GetScores()["Math"])
+	}
+	if _, ok := stored.(*Graduate); !ok {
+		t.Errorf("Expected stored student to keep type *Graduate, got %T", stored)
+	}
+
+	// 修改读取到的数据也不应影响存储
+	stored.GetBase().Name = "changed"
+	again, _, _ := store.Get(1)
+	if again.GetName() != "wei" {
+		t.Errorf("Expected stored name wei, got %v", again.GetName())
+	}
+
+	_, exists, err = store.Get(2)
+	if err != nil || exists {
+		t.Errorf("Expected student 2 to be missing, got %v %v", exists, err)
+	}
+}
+
+// TestFileStorePersistence 测试文件存储在重新打开后保留数据
+func TestFileStorePersistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.json")
+
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	registerCourses(t, sm, "Math")
+	registerClasses(t, sm, "27", "28")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+	sm.AddStudent(&Graduate{Student: Student{Name: "hao", StudentID: 2, Gender: "female", Class: "27"}})
+	if err := sm.AddScore(1, "Math", "", 95.0); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.DeleteStudent(2); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.Close(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 模拟服务重启
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm = NewStudentManagerWithStore(store)
+
+	score, err := sm.QueryScore(1, "Math", "")
+	if err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if score != 95.0 {
+		t.Errorf("Expected score 95.0 for course Math, got %v", score)
+	}
+	_, err = sm.QueryStudent(2)
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected student 2 to stay deleted, got %v", err)
+	}
+
+	// 写入过程中不应遗留临时文件
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 2 || entries[0].Name() != "students.audit.jsonl" || entries[1].Name() != "students.json" {
+		t.Errorf("Expected only the data file and the audit log, got %v", entries)
+	}
+}
+
+// TestFileStoreAuditLog 测试审计日志追加到单独的文件，不写入数据文件，并迁移旧版数据文件中的审计日志
+func TestFileStoreAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "students.json")
+	legacy := `{"students":[{"type":"undergraduate","name":"wei","id":1}],"audit_log":[` +
+		`{"seq":1,"time":"2025-01-01T00:00:00Z","actor":"wei","action":"add_student","student_id":1}]}`
+	if err := os.WriteFile(path, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	sm := NewStudentManagerWithStore(store)
+	sm.ModifyStudent(1, map[string]interface{}{"name": "hao"})
+	sm.Close()
+
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "audit_log") {
+		t.Errorf("Expected the audit log to be moved out of the data file, got %s", data)
+	}
+	logPath := filepath.Join(dir, "students.audit.jsonl")
+	lines, _ := os.ReadFile(logPath)
+	if records := strings.Split(strings.TrimSpace(string(lines)), "\n"); len(records) != 2 || !strings.Contains(records[1], `"seq":2`) {
+		t.Fatalf("Expected one line for each audit entry, got %s", lines)
+	}
+
+	// 追加到一半的最后一行在重新打开时被丢弃
+	file, _ := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0)
+	file.WriteString(`{"seq":3,"act`)
+	file.Close()
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error on reopen, got %v", err)
+	}
+	sm = NewStudentManagerWithStore(store)
+	defer sm.Close()
+	if err := sm.ModifyStudent(1, map[string]interface{}{"name": "li"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	entries, total, err := sm.QueryAudit(AuditQuery{})
+	if err != nil || total != 3 || entries[0].Seq != 3 || entries[2].Action != AuditAddStudent {
+		t.Errorf("Expected 3 entries after reopen, got %d %+v %v", total, entries, err)
+	}
+}
+
+// TestFileStoreCorruptFile 测试数据文件损坏时返回错误
+func TestFileStoreCorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileStore(path); err == nil {
+		t.Errorf("Expected error for corrupt data file, got nil")
+	}
+}
+
+// TestStagingStore 测试暂存存储在提交前不修改底层存储
+func TestStagingStore(t *testing.T) {
+	base := NewMemoryStore()
+	base.Save(&Undergraduate{Student{Name: "wei", StudentID: 1}})
+	base.Save(&Undergraduate{Student{Name: "hao", StudentID: 2}})
+
+	staging := newStagingStore(base, nil)
+	staging.Save(&Undergraduate{Student{Name: "li", StudentID: 3}})
+	staging.Save(&Undergraduate{Student{Name: "changed", StudentID: 1}})
+	staging.Delete(2)
+
+	students, err := staging.List()
+	if err != nil || len(students) != 2 || students[0].GetName() != "changed" || students[1].GetID() != 3 {
+		t.Errorf("Expected staged view [changed, li], got %v %v", students, err)
+	}
+	if _, exists, _ := staging.Get(2); exists {
+		t.Errorf("Expected student 2 to be deleted in staged view")
+	}
+	if student, _, _ := base.Get(1); student.GetName() != "wei" {
+		t.Errorf("Expected base store unchanged before commit, got %v", student.GetName())
+	}
+
+	if err := staging.commit(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	students, _ = base.List()
+	if len(students) != 2 || students[0].GetName() != "changed" || students[1].GetID() != 3 {
+		t.Errorf("Expected committed changes in base store, got %v", students)
+	}
+}
+
+// TestListAfter 测试内存存储和 SQLite 存储按学号分页读取学生
+func TestListAfter(t *testing.T) {
+	sqlite, err := NewSQLiteStore(filepath.Join(t.TempDir(), "students.db"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer sqlite.Close()
+	stores := map[string]StudentStore{"memory": NewMemoryStore(), "sqlite": sqlite}
+	for kind, store := range stores {
+		for _, id := range []int{5, 1, 3, 4, 2} {
+			store.Save(&Undergraduate{Student{Name: "wei", StudentID: id, Scores: map[string]float64{"Math": float64(90 + id)}}})
+		}
+		page, err := store.(StudentPager).ListAfter(1, 2)
+		if err != nil || len(page) != 2 || page[0].GetID() != 2 || page[1].GetID() != 3 || page[1].GetScores()["Math"] != 93 {
+			t.Errorf("%s: expected students 2 and 3 with scores, got %v %v", kind, page, err)
+		}
+		if page, err := store.(StudentPager).ListAfter(5, 2); err != nil || len(page) != 0 {
+			t.Errorf("%s: expected an empty last page, got %v %v", kind, page, err)
+		}
+	}
+}
+
+// TestFileStoreSaveAll 测试文件存储一次写入多个学生的修改，写文件失败时整批不生效
+func TestFileStoreSaveAll(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "students.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	store.Save(&Undergraduate{Student{Name: "wei", StudentID: 1}})
+	changes := map[int]StudentInterface{
+		1: nil,
+		2: &Undergraduate{Student{Name: "hao", StudentID: 2}},
+		3: &Graduate{Student: Student{Name: "li", StudentID: 3}, Advisor: "zhang"},
+	}
+	if err := store.SaveAll(changes); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	store.Close()
+
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error on reopen, got %v", err)
+	}
+	defer store.Close()
+	students, _ := store.List()
+	if len(students) != 2 || students[0].GetID() != 2 || students[1].(*Graduate).Advisor != "zhang" {
+		t.Errorf("Expected students 2 and 3 after reopen, got %v", students)
+	}
+
+	// 数据文件所在目录不存在时写文件失败，内存中的数据保持不变
+	store.path = filepath.Join(t.TempDir(), "missing", "students.json")
+	if err := store.SaveAll(map[int]StudentInterface{2: nil, 4: &Undergraduate{Student{Name: "chen", StudentID: 4}}}); err == nil {
+		t.Fatalf("Expected error when the data file cannot be written")
+	}
+	if students, _ := store.List(); len(students) != 2 || students[0].GetID() != 2 {
+		t.Errorf("Expected the failed batch to be undone, got %v", students)
+	}
+}
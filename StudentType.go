@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// defaultStudentType 旧数据没有记录学生类型时使用的类型
+const defaultStudentType = "undergraduate"
+
+// studentTypes 已注册的学生类型，键为类型名，值为创建该类型空实例的工厂函数
+var (
+	studentTypes   = make(map[string]func() StudentInterface)
+	studentTypesMu sync.RWMutex
+)
+
+func init() {
+	RegisterStudentType("undergraduate", func() StudentInterface { return &Undergraduate{} })
+	RegisterStudentType("graduate", func() StudentInterface { return &Graduate{} })
+}
+
+// RegisterStudentType 注册学生类型
+// 新增的学生类型（如博士生、交换生）只需实现 StudentInterface 并在此注册，
+// 即可通过 AddStudent、存储和 CSV 导入使用，无需修改 StudentManager
+func RegisterStudentType(kind string, factory func() StudentInterface) {
+	studentTypesMu.Lock()
+	defer studentTypesMu.Unlock()
+	if kind == "" || factory == nil {
+		panic("RegisterStudentType: empty kind or nil factory")
+	}
+	if _, exists := studentTypes[kind]; exists {
+		panic(fmt.Sprintf("RegisterStudentType: student type %q already registered", kind))
+	}
+	studentTypes[kind] = factory
+}
+
+// StudentTypes 按名称排序返回已注册的学生类型
+func StudentTypes() []string {
+	studentTypesMu.RLock()
+	defer studentTypesMu.RUnlock()
+	kinds := make([]string, 0, len(studentTypes))
+	for kind := range studentTypes {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// NewStudentOfType 创建指定类型的空学生实例
+func NewStudentOfType(kind string) (StudentInterface, error) {
+	studentTypesMu.RLock()
+	factory, exists := studentTypes[kind]
+	studentTypesMu.RUnlock()
+	if !exists {
+		return nil, &ValidationError{Field: "type", Message: fmt.Sprintf("unknown student type %q", kind)}
+	}
+	return factory(), nil
+}
+
+// decodeStudent 根据 JSON 中的 type 字段还原具体的学生类型
+func decodeStudent(data []byte) (StudentInterface, error) {
+	var header struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, err
+	}
+	if header.Type == "" {
+		header.Type = defaultStudentType
+	}
+	return decodeStudentOfType(header.Type, data)
+}
+
+// decodeStudentOfType 将 JSON 解码为指定类型的学生
+func decodeStudentOfType(kind string, data []byte) (StudentInterface, error) {
+	student, err := NewStudentOfType(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, student); err != nil {
+		return nil, err
+	}
+	base := student.GetBase()
+	base.Type = student.GetType()
+	base.adoptScores()
+	return student, nil
+}
+
+// cloneStudent 深拷贝学生信息，避免调用方与存储共享成绩映射等引用数据
+func cloneStudent(student StudentInterface) (StudentInterface, error) {
+	data, err := json.Marshal(student)
+	if err != nil {
+		return nil, fmt.Errorf("encode student %d: %w", student.GetID(), err)
+	}
+	clone, err := decodeStudentOfType(student.GetType(), data)
+	if err != nil {
+		return nil, fmt.Errorf("decode student %d: %w", student.GetID(), err)
+	}
+	return clone, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// exchangeStudent 测试用的交换生类型，带有本科生和研究生没有的字段
+type exchangeStudent struct {
+	Student
+	HomeUniversity string `json:"home_university"`
+}
+
+func (e *exchangeStudent) GetID() int                          { return e.StudentID }
+func (e *exchangeStudent) GetName() string                     { return e.Name }
+func (e *exchangeStudent) GetGender() string                   { return e.Gender }
+func (e *exchangeStudent) GetClass() string                    { return e.Class }
+func (e *exchangeStudent) GetScores() map[string]float64       { return e.Scores }
+func (e *exchangeStudent) SetScores(scores map[string]float64) { e.Scores = scores }
+func (e *exchangeStudent) GetType() string                     { return "exchange" }
+
+// unregisteredStudent 没有注册的学生类型
+type unregisteredStudent struct {
+	exchangeStudent
+}
+
+func (u *unregisteredStudent) GetType() string { return "unregistered" }
+
+func init() {
+	RegisterStudentType("exchange", func() StudentInterface { return &exchangeStudent{} })
+}
+
+// TestAddStudentKeepsType 测试 AddStudent 保留学生的具体类型
+func TestAddStudentKeepsType(t *testing.T) {
+	dir := t.TempDir()
+	fileStore, err := NewFileStore(filepath.Join(dir, "students.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqliteStore, err := NewSQLiteStore(filepath.Join(dir, "students.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := map[string]StudentStore{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+		"sqlite": sqliteStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			sm := NewStudentManagerWithStore(store)
+			registerClasses(t, sm, "27", "28")
+			defer sm.Close()
+			registerCourses(t, sm, "Math")
+
+			sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Gender: "male", Class: "28"}})
+			sm.AddStudent(&Graduate{Student: Student{Name: "hao", StudentID: 2, Gender: "female", Class: "27"}})
+			err := sm.AddStudent(&exchangeStudent{
+				Student:        Student{Name: "anna", StudentID: 3, Gender: "female", Class: "28"},
+				HomeUniversity: "TU Berlin",
+			})
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			sm.AddScore(3, "Math", "", 90.0)
+
+			student, err := sm.QueryStudent(2)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if student.Type != "graduate" {
+				t.Errorf("Expected type graduate, got %q", student.Type)
+			}
+
+			detail, err := sm.QueryStudentDetail(3)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			exchange, ok := detail.(*exchangeStudent)
+			if !ok {
+				t.Fatalf("Expected *exchangeStudent, got %T", detail)
+			}
+			if exchange.HomeUniversity != "TU Berlin" || exchange.Type != "exchange" || exchange.Scores["Math"] != 90.0 {
+				t.Errorf("Expected exchange student with home university and score, got %+v", exchange)
+			}
+
+			undergraduates, err := sm.QueryStudentsByType("undergraduate")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if len(undergraduates) != 1 || undergraduates[0].GetID() != 1 {
+				t.Errorf("Expected only student 1 to be an undergraduate, got %v", undergraduates)
+			}
+		})
+	}
+}
+
+// TestAddStudentUnregisteredType 测试添加未注册类型的学生返回错误
+func TestAddStudentUnregisteredType(t *testing.T) {
+	sm := NewStudentManager()
+	err := sm.AddStudent(&unregisteredStudent{exchangeStudent{Student: Student{Name: "x", StudentID: 1}}})
+	if err == nil {
+		t.Errorf("Expected error for unregistered student type, got nil")
+	}
+	if _, err := sm.QueryStudentsByType("unregistered"); err == nil {
+		t.Errorf("Expected error for unregistered student type, got nil")
+	}
+}
+
+// TestDecodeStudentDefaultType 测试没有类型字段的旧数据按本科生还原
+func TestDecodeStudentDefaultType(t *testing.T) {
+	student, err := decodeStudent([]byte(`{"name":"wei","id":1,"gender":"male","class":"28"}`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, ok := student.(*Undergraduate); !ok || student.GetBase().Type != "undergraduate" {
+		t.Errorf("Expected undergraduate, got %T %q", student, student.GetBase().Type)
+	}
+}
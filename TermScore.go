@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// termPattern 学期的格式：起始学年-结束学年-学期序号，例如 2025-2026-1
+var termPattern = regexp.MustCompile(`^(\d{4})-(\d{4})-([1-3])$`)
+
+// ValidateTerm 校验学期格式，空字符串表示未指定学期
+func ValidateTerm(term string) error {
+	if term == "" {
+		return nil
+	}
+	match := termPattern.FindStringSubmatch(term)
+	if match == nil {
+		return &ValidationError{Field: "term", Rule: "format", Message: fmt.Sprintf("%q must look like 2025-2026-1", term)}
+	}
+	start, _ := strconv.Atoi(match[1])
+	end, _ := strconv.Atoi(match[2])
+	if end != start+1 {
+		return &ValidationError{Field: "term", Rule: "format", Message: fmt.Sprintf("%q must span two consecutive years", term)}
+	}
+	return nil
+}
+
+// TermScore 学生一门课程在一个学期的成绩，课程和学期唯一确定一条记录
+// 学期为空表示未指定学期，例如引入学期之前录入的成绩；同一课程在之后的学期再有成绩即为重修
+type TermScore struct {
+	Course string   `json:"course"`
+	Term   string   `json:"term,omitempty"`
+	Score  float64  `json:"score"`
+	Makeup *float64 `json:"makeup,omitempty"` // 补考成绩
+	// Components 各组成部分的成绩，不为空时 Score 为按课程权重计算的总评成绩
+	Components map[string]float64 `json:"components,omitempty"`
+}
+
+// Effective 返回该学期的有效成绩，参加了补考时以补考成绩为准
+func (ts TermScore) Effective() float64 {
+	if ts.Makeup != nil {
+		return *ts.Makeup
+	}
+	return ts.Score
+}
+
+// Equal 判断两条学期成绩的成绩、补考成绩和组成部分成绩是否相同
+func (ts TermScore) Equal(other TermScore) bool {
+	if ts.Course != other.Course || ts.Term != other.Term || ts.Score != other.Score {
+		return false
+	}
+	if (ts.Makeup == nil) != (other.Makeup == nil) || ts.Makeup != nil && *ts.Makeup != *other.Makeup {
+		return false
+	}
+	if len(ts.Components) != len(other.Components) {
+		return false
+	}
+	for component, score := range ts.Components {
+		if value, ok := other.Components[component]; !ok || value != score {
+			return false
+		}
+	}
+	return true
+}
+
+// findTermScore 返回课程在学期的成绩记录的下标，不存在时返回 -1
+func (s *Student) findTermScore(courseName, term string) int {
+	for i, record := range s.TermScores {
+		if record.Course == courseName && record.Term == term {
+			return i
+		}
+	}
+	return -1
+}
+
+// termScores 返回全部学期成绩，Scores 中没有学期记录的课程补为未指定学期的记录
+func (s *Student) termScores() []TermScore {
+	records := append([]TermScore(nil), s.TermScores...)
+	recorded := make(map[string]bool, len(records))
+	for _, record := range records {
+		recorded[record.Course] = true
+	}
+	for courseName, score := range s.Scores {
+		if !recorded[courseName] {
+			records = append(records, TermScore{Course: courseName, Score: score})
+		}
+	}
+	return records
+}
+
+// adoptScores 把 Scores 中没有学期记录的成绩转为未指定学期的记录，并重新计算 Scores
+// 兼容引入学期之前的数据以及只提交 scores 的请求
+func (s *Student) adoptScores() {
+	s.TermScores = s.termScores()
+	s.rebuildScores()
+}
+
+// rebuildScores 按课程和学期排序学期成绩，并重新计算 Scores：每门课程取最近一个学期的有效成绩
+// 排名、统计、绩点和导出都使用 Scores 中的成绩
+func (s *Student) rebuildScores() {
+	sort.SliceStable(s.TermScores, func(i, j int) bool {
+		if s.TermScores[i].Course != s.TermScores[j].Course {
+			return s.TermScores[i].Course < s.TermScores[j].Course
+		}
+		return s.TermScores[i].Term < s.TermScores[j].Term
+	})
+	if len(s.TermScores) == 0 && s.Scores == nil {
+		return
+	}
+	scores := make(map[string]float64, len(s.TermScores))
+	for _, record := range s.TermScores {
+		scores[record.Course] = record.Effective()
+	}
+	s.Scores = scores
+}
+
+// TranscriptEntry 成绩单中一门课程在一个学期的成绩
+type TranscriptEntry struct {
+	Course    string   `json:"course"`
+	Name      string   `json:"name,omitempty"` // 已注册课程的名称
+	Credits   float64  `json:"credits"`
+	Score     float64  `json:"score"`
+	Makeup    *float64 `json:"makeup,omitempty"`
+	Effective float64  `json:"effective"`
+	Grade     string   `json:"grade,omitempty"`
+	Passed    bool     `json:"passed"`
+	Retake    bool     `json:"retake"` // 之前的学期已有该课程的成绩
+}
+
+// TranscriptTerm 成绩单中的一个学期
+type TranscriptTerm struct {
+	Term    string            `json:"term"` // 为空表示未指定学期
+	Courses []TranscriptEntry `json:"courses"`
+}
+
+// Transcript 学生按学期排列的成绩单，包括重修和补考
+type Transcript struct {
+	StudentID int              `json:"id"`
+	Name      string           `json:"name"`
+	Terms     []TranscriptTerm `json:"terms"`
+}
+
+// Transcript 按学期升序返回学生的成绩单，未指定学期的成绩排在最前
+func (sm *StudentManager) Transcript(studentID int) (*Transcript, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	record, exists, err := sm.store.Get(studentID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, studentNotFound(studentID)
+	}
+	catalog, err := sm.catalog()
+	if err != nil {
+		return nil, err
+	}
+
+	student := record.GetBase()
+	records := append([]TermScore(nil), student.TermScores...)
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Term != records[j].Term {
+			return records[i].Term < records[j].Term
+		}
+		return records[i].Course < records[j].Course
+	})
+
+	transcript := &Transcript{StudentID: studentID, Name: student.Name, Terms: []TranscriptTerm{}}
+	attempted := make(map[string]bool)
+	for _, termScore := range records {
+		scale, err := sm.grading.ScaleFor(termScore.Course)
+		if err != nil {
+			return nil, err
+		}
+		entry := TranscriptEntry{
+			Course:    termScore.Course,
+			Credits:   sm.grading.CreditsFor(termScore.Course),
+			Score:     termScore.Score,
+			Makeup:    termScore.Makeup,
+			Effective: termScore.Effective(),
+			Grade:     scale.GradeOf(termScore.Effective()),
+			Passed:    scale.Passed(termScore.Effective()),
+			Retake:    attempted[termScore.Course],
+		}
+		if course, ok := catalog.find(termScore.Course); ok && course.Code == termScore.Course {
+			entry.Name = course.Name
+			entry.Credits = course.Credits
+		}
+		attempted[termScore.Course] = true
+
+		if n := len(transcript.Terms); n == 0 || transcript.Terms[n-1].Term != termScore.Term {
+			transcript.Terms = append(transcript.Terms, TranscriptTerm{Term: termScore.Term})
+		}
+		term := &transcript.Terms[len(transcript.Terms)-1]
+		term.Courses = append(term.Courses, entry)
+	}
+	return transcript, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestValidateTerm 测试学期格式校验
+func TestValidateTerm(t *testing.T) {
+	for _, term := range []string{"", "2025-2026-1", "2025-2026-3"} {
+		if err := ValidateTerm(term); err != nil {
+			t.Errorf("%q: expected no error, got %v", term, err)
+		}
+	}
+	for _, term := range []string{"2025", "2025-2026-4", "2025-2027-1", "history"} {
+		var validationErr *ValidationError
+		if err := ValidateTerm(term); !errors.As(err, &validationErr) || validationErr.Field != "term" {
+			t.Errorf("%q: expected validation error, got %v", term, err)
+		}
+	}
+}
+
+// TestTermScores 测试同一课程在不同学期的成绩分别保存，当前成绩取最近一个学期
+func TestTermScores(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "History")
+	registerClasses(t, sm, "28")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Class: "28", Scores: map[string]float64{"History": 70}}})
+	if err := sm.AddScore(1, "Math", "2024-2025-1", 45); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := sm.AddScore(1, "Math", "2025-2026-1", 82); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if score, err := sm.QueryScore(1, "Math", "2024-2025-1"); err != nil || score != 45 {
+		t.Errorf("Expected first attempt 45 to be kept, got %v %v", score, err)
+	}
+	student, _ := sm.QueryStudent(1)
+	if student.Scores["Math"] != 82 || student.Scores["History"] != 70 || len(student.TermScores) != 3 {
+		t.Errorf("Expected current Math score 82 and 3 term scores, got %+v", student)
+	}
+	if _, err := sm.QueryScore(1, "Math", ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected no untermed Math score, got %v", err)
+	}
+
+	if err := sm.DeleteScore(1, "Math", "2025-2026-1"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	student, _ = sm.QueryStudent(1)
+	if student.Scores["Math"] != 45 {
+		t.Errorf("Expected current Math score to fall back to 45, got %v", student.Scores["Math"])
+	}
+	if err := sm.DeleteScore(1, "Math", "2025-2026-1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected deleted term score to be not found, got %v", err)
+	}
+	var validationErr *ValidationError
+	if err := sm.AddScore(1, "Math", "2025", 90); !errors.As(err, &validationErr) || validationErr.Field != "term" {
+		t.Errorf("Expected invalid term to be rejected, got %v", err)
+	}
+}
+
+// TestMakeupAndTranscript 测试补考成绩和按学期排列的成绩单
+func TestMakeupAndTranscript(t *testing.T) {
+	sm := NewStudentManager()
+	registerCourses(t, sm, "Math", "English")
+	registerClasses(t, sm, "28")
+	sm.AddStudent(&Undergraduate{Student{Name: "wei", StudentID: 1, Class: "28"}})
+	sm.AddScore(1, "Math", "2024-2025-1", 45)
+	sm.AddScore(1, "English", "2024-2025-1", 88)
+	sm.AddScore(1, "Math", "2024-2025-2", 75)
+
+	var validationErr *ValidationError
+	if err := sm.RecordMakeup(1, "English", "2024-2025-1", 90, ""); !errors.As(err, &validationErr) || validationErr.Rule != "failed" {
+		t.Errorf("Expected makeup of a passed score to be rejected, got %v", err)
+	}
+	if err := sm.RecordMakeup(1, "Math", "2023-2024-1", 60, ""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected makeup without a score to be not found, got %v", err)
+	}
+	if err := sm.RecordMakeup(1, "Math", "2024-2025-1", 61, ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if score, err := sm.QueryScore(1, "Math", "2024-2025-1"); err != nil || score != 61 {
+		t.Errorf("Expected makeup score 61 to be effective, got %v %v", score, err)
+	}
+
+	transcript, err := sm.Transcript(1)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(transcript.Terms) != 2 || transcript.Terms[0].Term != "2024-2025-1" || len(transcript.Terms[0].Courses) != 2 {
+		t.Fatalf("Expected 2 terms with 2 courses in the first, got %+v", transcript.Terms)
+	}
+	first := transcript.Terms[0].Courses[1]
+	if first.Course != "Math" || first.Score != 45 || first.Makeup == nil || first.Effective != 61 || !first.Passed || first.Retake {
+		t.Errorf("Unexpected first Math attempt %+v", first)
+	}
+	retake := transcript.Terms[1].Courses[0]
+	if retake.Course != "Math" || retake.Effective != 75 || !retake.Retake {
+		t.Errorf("Expected second Math attempt to be a retake, got %+v", retake)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// minPasswordLength 密码的最小长度
+const minPasswordLength = 8
+
+// ErrUnauthorized 表示用户名或密码错误、令牌无效或已吊销，可通过 errors.Is 判断
+var ErrUnauthorized = errors.New("unauthorized")
+
+// User 本地用户，密码只保存 bcrypt 哈希
+type User struct {
+	Username     string    `json:"username"`
+	PasswordHash string    `json:"password_hash,omitempty"` // 接口返回用户信息时清空
+	Role         string    `json:"role"`
+	StudentID    int       `json:"student_id,omitempty"` // 学生用户对应的学号
+	CreatedAt    time.Time `json:"created_at"`
+	// PasswordChangedAt 最近一次设置密码的时间，之前签发的令牌全部失效
+	PasswordChangedAt time.Time `json:"password_changed_at"`
+}
+
+// UserStore 定义用户和已吊销令牌的存储接口，内置的三种学生存储都实现了该接口
+type UserStore interface {
+	// GetUser 按用户名读取用户，用户不存在时 exists 为 false
+	GetUser(username string) (user User, exists bool, err error)
+	// SaveUser 保存用户，已存在则覆盖
+	SaveUser(user User) error
+	// DeleteUser 删除用户
+	DeleteUser(username string) error
+	// ListUsers 按用户名升序返回全部用户
+	ListUsers() ([]User, error)
+	// RevokeToken 记录已吊销的令牌，令牌过期后记录可以清理
+	RevokeToken(tokenID string, expiresAt time.Time) error
+	// TokenRevoked 判断令牌是否已吊销
+	TokenRevoked(tokenID string) (bool, error)
+}
+
+// placeholderHash 返回用户不存在时用于比较的哈希，首次使用时生成
+var placeholderHash = sync.OnceValue(func() string {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("placeholder password"), bcrypt.DefaultCost)
+	return string(hash)
+})
+
+// userNotFound 返回用户不存在的错误
+func userNotFound(username string) error {
+	return fmt.Errorf("user %s %w", username, ErrNotFound)
+}
+
+// validateUsername 校验用户名
+func validateUsername(username string) error {
+	if username == "" {
+		return &ValidationError{Field: "username", Rule: "required", Message: "must not be empty"}
+	}
+	if strings.ContainsAny(username, "/?# \t") {
+		return &ValidationError{Field: "username", Rule: "format", Message: "must not contain spaces, /, ? or #"}
+	}
+	return nil
+}
+
+// hashPassword 校验密码长度并计算 bcrypt 哈希
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", &ValidationError{Field: "password", Rule: "min", Message: fmt.Sprintf("must be at least %d characters", minPasswordLength)}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		// 超过 72 字节的密码 bcrypt 无法处理
+		return "", &ValidationError{Field: "password", Rule: "max", Message: err.Error()}
+	}
+	return string(hash), nil
+}
+
+// AddUser 创建本地用户，user 中的用户名、角色和学号有效，用户名已存在时返回 ErrConflict
+func (sm *StudentManager) AddUser(user User, password string) (*User, error) {
+	user.Username = strings.TrimSpace(user.Username)
+	if err := validateUsername(user.Username); err != nil {
+		return nil, err
+	}
+	if err := validateRole(user.Role, user.StudentID); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	_, exists, err := sm.users.GetUser(user.Username)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("user %s %w", user.Username, ErrConflict)
+	}
+	now := time.Now().UTC()
+	user.PasswordHash = hash
+	user.CreatedAt = now
+	user.PasswordChangedAt = now
+	if err := sm.users.SaveUser(user); err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+	return &user, nil
+}
+
+// SetPassword 修改用户的密码，之前签发给该用户的令牌全部失效
+func (sm *StudentManager) SetPassword(username, password string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	user, exists, err := sm.users.GetUser(username)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return userNotFound(username)
+	}
+	user.PasswordHash = hash
+	user.PasswordChangedAt = time.Now().UTC()
+	return sm.users.SaveUser(user)
+}
+
+// ModifyUser 修改用户的角色和对应的学号，修改立即生效，不需要重新登录
+func (sm *StudentManager) ModifyUser(username string, changes User) (*User, error) {
+	if err := validateRole(changes.Role, changes.StudentID); err != nil {
+		return nil, err
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	user, exists, err := sm.users.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, userNotFound(username)
+	}
+	if user.Role == RoleAdmin && changes.Role != RoleAdmin {
+		if err := sm.checkOtherAdmin(username); err != nil {
+			return nil, err
+		}
+	}
+	user.Role = changes.Role
+	user.StudentID = changes.StudentID
+	if err := sm.users.SaveUser(user); err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+	return &user, nil
+}
+
+// DeleteUser 删除用户，已签发的令牌在验证时因用户不存在而失效，不能删除最后一个管理员
+func (sm *StudentManager) DeleteUser(username string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	user, exists, err := sm.users.GetUser(username)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return userNotFound(username)
+	}
+	if user.Role == RoleAdmin {
+		if err := sm.checkOtherAdmin(username); err != nil {
+			return err
+		}
+	}
+	return sm.users.DeleteUser(username)
+}
+
+// checkOtherAdmin 检查除 username 外还有其他管理员，避免系统中没有管理员，调用方需持有锁
+func (sm *StudentManager) checkOtherAdmin(username string) error {
+	users, err := sm.users.ListUsers()
+	if err != nil {
+		return err
+	}
+	for _, user := range users {
+		if user.Role == RoleAdmin && user.Username != username {
+			return nil
+		}
+	}
+	return &ValidationError{Field: "role", Rule: "last_admin", Message: fmt.Sprintf("%s is the last admin", username)}
+}
+
+// ListUsers 按用户名升序返回全部用户，不包含密码哈希
+func (sm *StudentManager) ListUsers() ([]User, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	users, err := sm.users.ListUsers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range users {
+		users[i].PasswordHash = ""
+	}
+	return users, nil
+}
+
+// Authenticate 校验用户名和密码，不匹配时返回 ErrUnauthorized，不区分是用户不存在还是密码错误
+func (sm *StudentManager) Authenticate(username, password string) (*User, error) {
+	sm.mu.Lock()
+	user, exists, err := sm.users.GetUser(username)
+	sm.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	// 用户不存在时同样比较一次哈希，避免通过响应时间判断用户名是否存在
+	hash := user.PasswordHash
+	if !exists {
+		hash = placeholderHash()
+	}
+	// bcrypt 比较较慢，不在持有锁时进行
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)); err != nil || !exists {
+		return nil, fmt.Errorf("invalid username or password: %w", ErrUnauthorized)
+	}
+	user.PasswordHash = ""
+	return &user, nil
+}
+
+// QueryUser 查询用户，不包含密码哈希
+func (sm *StudentManager) QueryUser(username string) (*User, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	user, exists, err := sm.users.GetUser(username)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, userNotFound(username)
+	}
+	user.PasswordHash = ""
+	return &user, nil
+}
+
+// RevokeToken 吊销令牌
+func (sm *StudentManager) RevokeToken(tokenID string, expiresAt time.Time) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.users.RevokeToken(tokenID, expiresAt)
+}
+
+// RevokeTokenOnce 吊销令牌，令牌已被吊销时返回 ErrConflict
+// 检查和吊销在同一次加锁中完成，同一个令牌只有一个调用方能成功吊销，用于刷新令牌只能使用一次
+func (sm *StudentManager) RevokeTokenOnce(tokenID string, expiresAt time.Time) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	revoked, err := sm.users.TokenRevoked(tokenID)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return fmt.Errorf("token %s %w in the revocation list", tokenID, ErrConflict)
+	}
+	return sm.users.RevokeToken(tokenID, expiresAt)
+}
+
+// TokenRevoked 判断令牌是否已吊销
+func (sm *StudentManager) TokenRevoked(tokenID string) (bool, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.users.TokenRevoked(tokenID)
+}